@@ -0,0 +1,122 @@
+// Package api defines golem's public API, and provides a client for it.
+//
+// The API is served on golem's profile socket, which is located at
+// $XDG_RUNTIME_DIR/golem-PROFILE. It is accessed either with msgpack-rpc
+// calls, made to the "GolemAPI" service after the handshake
+//
+//	>>> msgpack-rpc-client:TOKEN\0
+//	<<< ok\0
+//
+// or as a stream of events, after the handshake
+//
+//	>>> msgpack-events:TOKEN\0
+//	<<< ok\0
+//	>>> [event types to subscribe to, or an empty array for all]
+//
+// after which golem writes each event as a msgpack encoded Event until the
+// connection is closed.
+//
+// Only processes of the same user may connect, and only with a valid token.
+// Golem generates a random token at startup, and writes it to a file only the
+// user may read, next to its socket. (see TokenFile) If golem's remote-token
+// setting is set, it is accepted as well. Golem replies "denied\0" and closes
+// the connection if it refuses it.
+//
+// Tabs are identified by their ID, which remains the same for the lifetime of
+// the tab, even if it is moved between windows. The ID 0 refers to the
+// current tab of the first window.
+//
+// The API is versioned; backwards incompatible changes increment Version.
+package api
+
+// Version is the version of the API. Clients should check it matches the
+// version golem reports before making further calls.
+const Version = 1
+
+// The types of events which may be subscribed to.
+const (
+	// EventLoadFinished is sent once a tab finished loading a page.
+	EventLoadFinished = "load-finished"
+	// EventTabOpened is sent once a new tab is created.
+	EventTabOpened = "tab-opened"
+	// EventTitleChanged is sent if the title of a tab changes.
+	EventTitleChanged = "title-changed"
+)
+
+// EventTypes are all types of events which may be subscribed to.
+var EventTypes = []string{
+	EventLoadFinished,
+	EventTabOpened,
+	EventTitleChanged,
+}
+
+// Nothing is the argument or reply of API calls which don't need one.
+type Nothing struct{}
+
+// A Window is one of golem's windows.
+type Window struct {
+	// Index is the index of the window, in the order the windows were opened.
+	Index int
+	// Current is the index of the currently displayed tab.
+	Current int
+	Tabs    []Tab
+}
+
+// A Tab is a single tab of a window.
+type Tab struct {
+	ID    uint64
+	URI   string
+	Title string
+}
+
+// An Entry is a single history entry or bookmark.
+type Entry struct {
+	URI   string
+	Title string
+}
+
+// An Event is a notification of something happening in a tab.
+type Event struct {
+	Type  string
+	TabID uint64
+	URI   string
+	Title string
+}
+
+// A URIRequest is a request to load a URI in a tab.
+type URIRequest struct {
+	TabID uint64
+	URI   string
+}
+
+// An OpenRequest is a request to open a URI in a new tab.
+//
+// The URI is interpreted as for the open command, and may be a search. The tab
+// is opened in the first window and focused, unless Window or Background are
+// set. If Window is set, a new window is opened instead.
+type OpenRequest struct {
+	URI        string
+	Window     bool
+	Background bool
+}
+
+// A CommandRequest is a request to run a command in a tab.
+//
+// The command is run as if it was typed into the command line of the tab's
+// window while the tab is displayed, without the leading colon.
+type CommandRequest struct {
+	TabID   uint64
+	Command string
+}
+
+// A SettingRequest is a request to query or set a setting, as seen from a
+// tab.
+//
+// Keys are of the same form as those used by the set command, e.g.
+// "golem:hints-chars" or "webkit:tab:enable-javascript". Values are
+// formatted and parsed as for the set command.
+type SettingRequest struct {
+	TabID uint64
+	Key   string
+	Value string
+}
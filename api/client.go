@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+
+	"github.com/ugorji/go/codec"
+)
+
+// handshake introduces a connection to golem with the given header and
+// token, and returns a reader for the remainder of the connection.
+func handshake(conn net.Conn, header, token string) (*bufio.Reader, error) {
+	if token != "" {
+		header += ":" + token
+	}
+	_, err := conn.Write([]byte(header + "\u0000"))
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes(0)
+	if err != nil {
+		return nil, err
+	} else if string(line) == "denied\u0000" {
+		return nil, ErrDenied
+	} else if string(line) != "ok\u0000" {
+		return nil, errors.New("Handshake failed.")
+	}
+	return reader, nil
+}
+
+// ErrDenied is returned if golem refuses a connection, e.g. because the
+// token presented is invalid.
+var ErrDenied = errors.New("Connection denied by golem.")
+
+// TokenFile is the path of the file golem writes its token to, given the path
+// of its socket.
+func TokenFile(path string) string {
+	return path + ".token"
+}
+
+// ReadToken reads the token of the golem listening at the given socket path
+// from its token file.
+func ReadToken(path string) (string, error) {
+	token, err := ioutil.ReadFile(TokenFile(path))
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// A Client makes API calls to a running golem instance.
+type Client struct {
+	conn net.Conn
+	rpc  *rpc.Client
+}
+
+// Dial connects to golem's socket at the given path.
+//
+// The token is either golem's own (see ReadToken), or its remote-token
+// setting.
+func Dial(path, token string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(conn, token)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient creates a client communicating over a connection to golem's
+// socket, and checks that golem serves the same API version.
+//
+// The token is either golem's own (see ReadToken), or its remote-token
+// setting.
+func NewClient(conn net.Conn, token string) (*Client, error) {
+	if _, err := handshake(conn, "msgpack-rpc-client", token); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn,
+		rpc.NewClientWithCodec(
+			codec.MsgpackSpecRpc.ClientCodec(conn, new(codec.MsgpackHandle))),
+	}
+	v, err := c.Version()
+	if err != nil {
+		return nil, err
+	} else if v != Version {
+		return nil, fmt.Errorf(
+			"API version mismatch: golem serves %d, expected %d", v, Version)
+	}
+	return c, nil
+}
+
+// Close closes the connection to golem.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Call calls an API method by name, e.g. "Windows".
+func (c *Client) Call(method string, args interface{}, reply interface{}) error {
+	return c.rpc.Call("GolemAPI."+method, args, reply)
+}
+
+// Version retrieves the version of the API served.
+func (c *Client) Version() (int, error) {
+	var v int
+	err := c.Call("Version", &Nothing{}, &v)
+	return v, err
+}
+
+// Windows retrieves all windows and their tabs.
+func (c *Client) Windows() ([]Window, error) {
+	var ws []Window
+	err := c.Call("Windows", &Nothing{}, &ws)
+	return ws, err
+}
+
+// URI retrieves the URI of a tab.
+func (c *Client) URI(tab uint64) (string, error) {
+	var uri string
+	err := c.Call("URI", tab, &uri)
+	return uri, err
+}
+
+// SetURI loads a URI in a tab.
+func (c *Client) SetURI(tab uint64, uri string) error {
+	return c.Call("SetURI", &URIRequest{tab, uri}, &Nothing{})
+}
+
+// Open opens a URI in a new tab, and returns the tab's ID.
+func (c *Client) Open(req OpenRequest) (uint64, error) {
+	var id uint64
+	err := c.Call("Open", &req, &id)
+	return id, err
+}
+
+// CloseTab closes a tab.
+func (c *Client) CloseTab(tab uint64) error {
+	return c.Call("CloseTab", tab, &Nothing{})
+}
+
+// Reload reloads a tab.
+func (c *Client) Reload(tab uint64) error {
+	return c.Call("Reload", tab, &Nothing{})
+}
+
+// Quit closes all of golem's windows, which quits golem.
+//
+// Golem may close the connection while quitting, before replying; this is
+// not treated as an error.
+func (c *Client) Quit() error {
+	err := c.Call("Quit", &Nothing{}, &Nothing{})
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == rpc.ErrShutdown {
+		return nil
+	}
+	return err
+}
+
+// RunCommand runs a command in a tab.
+//
+// If the command fails, its errors are returned.
+func (c *Client) RunCommand(tab uint64, command string) error {
+	return c.Call("RunCommand", &CommandRequest{tab, command}, &Nothing{})
+}
+
+// Setting retrieves the value of a setting as seen from a tab.
+//
+// If key has no level, as in webkit:KEY, this is the value in effect for the
+// tab, with site settings applied. Otherwise, as in webkit:global:KEY, it is
+// the value set at the given level.
+func (c *Client) Setting(tab uint64, key string) (string, error) {
+	var value string
+	err := c.Call("Setting", &SettingRequest{tab, key, ""}, &value)
+	return value, err
+}
+
+// SetSetting sets a setting as seen from a tab.
+func (c *Client) SetSetting(tab uint64, key, value string) error {
+	return c.Call("SetSetting", &SettingRequest{tab, key, value}, &Nothing{})
+}
+
+// History retrieves the browsing history, oldest entries first.
+func (c *Client) History() ([]Entry, error) {
+	var es []Entry
+	err := c.Call("History", &Nothing{}, &es)
+	return es, err
+}
+
+// Bookmarks retrieves the bookmarks.
+func (c *Client) Bookmarks() ([]Entry, error) {
+	var es []Entry
+	err := c.Call("Bookmarks", &Nothing{}, &es)
+	return es, err
+}
+
+// A Subscription is a stream of events from golem.
+type Subscription struct {
+	// Events recieves the events subscribed to. It is closed once the
+	// subscription ends, and must be read until then.
+	Events <-chan Event
+	conn   net.Conn
+	err    error
+}
+
+// DialEvents connects to golem's socket at the given path and subscribes to
+// the given types of events, or to all events if none are given.
+//
+// The token is either golem's own (see ReadToken), or its remote-token
+// setting.
+func DialEvents(
+	path, token string,
+	types ...string) (*Subscription, error) {
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSubscription(conn, token, types...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSubscription subscribes to the given types of events over a connection
+// to golem's socket, or to all events if none are given.
+func NewSubscription(
+	conn net.Conn,
+	token string,
+	types ...string) (*Subscription, error) {
+
+	reader, err := handshake(conn, "msgpack-events", token)
+	if err != nil {
+		return nil, err
+	}
+	h := new(codec.MsgpackHandle)
+	if types == nil {
+		types = []string{}
+	}
+	if err := codec.NewEncoder(conn, h).Encode(types); err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	s := &Subscription{events, conn, nil}
+	go func() {
+		dec := codec.NewDecoder(reader, h)
+		for {
+			var e Event
+			if err := dec.Decode(&e); err != nil {
+				s.err = err
+				close(events)
+				return
+			}
+			events <- e
+		}
+	}()
+	return s, nil
+}
+
+// Err retrieves the error which ended the subscription, once Events has been
+// closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
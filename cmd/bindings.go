@@ -25,6 +25,64 @@ type Builtin struct {
 	Desc string
 }
 
+// A Mode is a mode which key bindings can be defined for.
+type Mode uint
+
+const (
+	// ModeNormal is normal mode, in which bindings may be key sequences.
+	ModeNormal Mode = iota
+	// ModeInsert is insert mode. Keys which aren't bound are passed through.
+	ModeInsert
+	// ModeCommandLine is command line mode. Keys which aren't bound are
+	// inserted into the command line.
+	ModeCommandLine
+	// ModeHints is hints mode. Keys which aren't bound are used to select
+	// hints.
+	ModeHints
+	// ModePassThrough is pass-through mode. All keys which aren't bound are
+	// passed through, including escape.
+	ModePassThrough
+)
+
+// ParseMode parses the name of a mode, as given to the bind command.
+//
+// Valid names are normal, insert, command-line, hints and pass-through, or n,
+// i, c, h and p as shorthand.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "normal", "n":
+		return ModeNormal, nil
+	case "insert", "i":
+		return ModeInsert, nil
+	case "command-line", "commandline", "c":
+		return ModeCommandLine, nil
+	case "hints", "h":
+		return ModeHints, nil
+	case "pass-through", "passthrough", "p":
+		return ModePassThrough, nil
+	default:
+		return 0, fmt.Errorf("Unknown mode: %v", name)
+	}
+}
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeNormal:
+		return "normal"
+	case ModeInsert:
+		return "insert"
+	case ModeCommandLine:
+		return "command-line"
+	case ModeHints:
+		return "hints"
+	case ModePassThrough:
+		return "pass-through"
+	default:
+		return fmt.Sprintf("mode %d", uint(m))
+	}
+}
+
 // A RawBinding map one string (representing the keysequence to be pressed) to
 // another (representing what the binding should do).
 type RawBinding struct {
@@ -181,6 +239,18 @@ func (t *BindingTree) IterLeaves() <-chan *Binding {
 	return ret
 }
 
+// lookup retrieves the subtree a key press leads to.
+//
+// Keys are matched by their keyval first, and by their keycode if no binding
+// uses the keyval.
+func (t *BindingTree) lookup(key RealKey) (*BindingTree, bool) {
+	if subtree, ok := t.Subtrees[key.Normalize()]; ok {
+		return subtree, true
+	}
+	subtree, ok := t.Subtrees[key.KeycodeKey()]
+	return subtree, ok
+}
+
 // Append adds a new binding to a BindingTree.
 //
 // Fails if the binding conflicts with an existing one.
@@ -0,0 +1,206 @@
+package cmd
+
+import "strings"
+
+// A History stores the command lines previously accepted in each command
+// line substate.
+type History interface {
+	// Entries retrieves the history of a substate, oldest entry first.
+	Entries(st Substate) []string
+	// Add adds a newly accepted command line to the history of a substate.
+	Add(st Substate, line string)
+}
+
+// historyNav tracks the navigation through the history of a command line.
+type historyNav struct {
+	// prefix is the text entries must start with to be recalled.
+	prefix string
+	// index is the index of the recalled history entry.
+	index int
+	// saved is the command line before navigation started.
+	saved []Key
+}
+
+// recall returns a new state with a history entry recalled.
+func (s *CommandLineMode) recall(
+	keys []Key,
+	nav *historyNav) *CommandLineMode {
+
+	ret := s.edit(
+		keys,
+		len(keys),
+		min(len(keys), s.CursorHome),
+		0,
+		editOther)
+	ret.hist = nav
+	return ret
+}
+
+// HistoryPrevious recalls the previous history entry starting with the text
+// before the cursor.
+func (s *CommandLineMode) HistoryPrevious() *CommandLineMode {
+	if s.History == nil {
+		return s
+	}
+	entries := s.History.Entries(s.Substate)
+	nav := s.hist
+	if nav == nil {
+		nav = &historyNav{
+			KeysStringSelective(s.CurrentKeys[:s.CursorPos], false),
+			len(entries),
+			s.CurrentKeys,
+		}
+	}
+	current := KeysStringSelective(s.CurrentKeys, false)
+	for i := min(nav.index, len(entries)) - 1; i >= 0; i-- {
+		if entries[i] != current && strings.HasPrefix(entries[i], nav.prefix) {
+			return s.recall(
+				ParseKeys(entries[i]),
+				&historyNav{nav.prefix, i, nav.saved})
+		}
+	}
+	return s
+}
+
+// HistoryNext recalls the next history entry starting with the text before
+// the cursor, or the original command line if there is none.
+func (s *CommandLineMode) HistoryNext() *CommandLineMode {
+	if s.History == nil || s.hist == nil {
+		return s
+	}
+	entries := s.History.Entries(s.Substate)
+	current := KeysStringSelective(s.CurrentKeys, false)
+	for i := s.hist.index + 1; i < len(entries); i++ {
+		if entries[i] != current &&
+			strings.HasPrefix(entries[i], s.hist.prefix) {
+
+			return s.recall(
+				ParseKeys(entries[i]),
+				&historyNav{s.hist.prefix, i, s.hist.saved})
+		}
+	}
+	return s.recall(s.hist.saved, nil)
+}
+
+// HistorySearchMode is a mode which incrementally searches backwards through
+// the history of a command line.
+type HistorySearchMode struct {
+	*StateIndependant
+	Substate
+	// CommandLine is the command line the search was started from.
+	CommandLine *CommandLineMode
+	Query       []Key
+	// Match is the index of the matching history entry, or -1 if nothing
+	// matched yet.
+	Match     int
+	MatchText string
+	// Failing is true if no entry matches the current query.
+	Failing bool
+}
+
+// NewHistorySearchMode starts a history search from a command line.
+func NewHistorySearchMode(s *CommandLineMode) *HistorySearchMode {
+	return &HistorySearchMode{
+		s.StateIndependant,
+		s.Substate,
+		s,
+		make([]Key, 0),
+		-1,
+		"",
+		false,
+	}
+}
+
+// search searches for query backwards through the history, starting at the
+// entry with index from.
+func (s *HistorySearchMode) search(query []Key, from int) *HistorySearchMode {
+	var entries []string
+	if s.History != nil {
+		entries = s.History.Entries(s.CommandLine.Substate)
+	}
+	if from < 0 || from >= len(entries) {
+		from = len(entries) - 1
+	}
+	queryStr := KeysStringSelective(query, false)
+	if queryStr != "" {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(entries[i], queryStr) {
+				return &HistorySearchMode{
+					s.StateIndependant,
+					s.Substate,
+					s.CommandLine,
+					query,
+					i,
+					entries[i],
+					false,
+				}
+			}
+		}
+	}
+	return &HistorySearchMode{
+		s.StateIndependant,
+		s.Substate,
+		s.CommandLine,
+		query,
+		s.Match,
+		s.MatchText,
+		queryStr != "",
+	}
+}
+
+// Next searches for the next older entry matching the query.
+func (s *HistorySearchMode) Next() *HistorySearchMode {
+	if s.Match <= 0 {
+		return s
+	}
+	return s.search(s.Query, s.Match-1)
+}
+
+// Accept ends the search, returning to the command line with the matching
+// entry.
+func (s *HistorySearchMode) Accept() *CommandLineMode {
+	if s.Match == -1 {
+		return s.CommandLine
+	}
+	return s.CommandLine.Edit(ParseKeys(s.MatchText))
+}
+
+// ProcessKeyPress processes a single key press in history search mode.
+//
+// Printable keys are added to the query, and BackSpace removes the last one.
+// Escape cancels the search and returns to the original command line. Any
+// other key accepts the match and is passed on to the command line.
+//
+// Command line mode bindings take precedence over all of the above.
+func (s *HistorySearchMode) ProcessKeyPress(key RealKey) (State, bool) {
+	if s.ExecuteModeBinding(s, ModeCommandLine, key) {
+		return s, true
+	}
+	key = key.Normalize()
+	switch key.Keyval {
+	case KeyEscape:
+		return s.CommandLine, true
+	case KeyBackSpace:
+		if len(s.Query) == 0 {
+			return s.CommandLine, true
+		}
+		return s.search(s.Query[:len(s.Query)-1], -1), true
+	}
+	if r := KeyRune(key); r == 0 || r == '\n' || r == '\r' || r == '\t' {
+		return s.Accept().ProcessKeyPress(key)
+	}
+	query := make([]Key, len(s.Query)+1)
+	copy(query, s.Query)
+	query[len(s.Query)] = key
+	return s.search(query, s.Match), true
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *HistorySearchMode) GetStateIndependant() *StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *HistorySearchMode) GetSubstate() Substate {
+	return s.Substate
+}
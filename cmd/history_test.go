@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testHistory is a history kept in memory.
+type testHistory map[Substate][]string
+
+// Entries retrieves the history of a substate, oldest entry first.
+func (h testHistory) Entries(st Substate) []string {
+	return h[st]
+}
+
+// Add adds a command line to the history of a substate.
+func (h testHistory) Add(st Substate, line string) {
+	h[st] = append(h[st], line)
+}
+
+// historyBindings are the command line bindings used with a history: C-w
+// kills a word, and C-r starts a history search.
+var historyBindings = map[string]func(*CommandLineMode) State{
+	"<C-w>": edit((*CommandLineMode).KillWord),
+	"<C-r>": func(s *CommandLineMode) State {
+		return NewHistorySearchMode(s)
+	},
+}
+
+// newHistoryTestSession starts an empty command line with a history of
+// the given command lines.
+func newHistoryTestSession(lines ...string) *testSession {
+	return newTestSession(testHistory{SubstateDefault: lines}, historyBindings)
+}
+
+// search retrieves the current history search.
+func (ts *testSession) search(t *testing.T) *HistorySearchMode {
+	s, ok := ts.state.(*HistorySearchMode)
+	if !ok {
+		t.Fatalf("In %T instead of history search mode.", ts.state)
+	}
+	return s
+}
+
+// checkHistory checks the command lines resulting from key sequences, each
+// pressed on a new command line with a history of the given lines.
+func checkHistory(t *testing.T, lines []string, cases [][2]string) {
+	for _, c := range cases {
+		ts := newHistoryTestSession(lines...)
+		ts.press(t, c[0])
+		if line := ts.line(t); line != c[1] {
+			t.Errorf("Keys %s led to %q, expected %q.", c[0], line, c[1])
+		}
+	}
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	checkHistory(t, []string{"open a", "tabopen b", "open c"}, [][2]string{
+		{"<Up>", "open c|"},
+		{"<Up><Up>", "tabopen b|"},
+		{"<Up><Up><Up><Up>", "open a|"},
+		{"<Up><Up><Down>", "open c|"},
+		{"<Up><Up><Down><Down>", "|"},
+		{"<Down>", "|"},
+		// Only entries starting with the text before the cursor are
+		// recalled, and the text is restored afterwards.
+		{"open<Up>", "open c|"},
+		{"open<Up><Up>", "open a|"},
+		{"open<Up><Up><Down><Down>", "open|"},
+		{"tab<Up><Up>", "tabopen b|"},
+		{"x<Up>", "x|"},
+	})
+}
+
+func TestHistorySkipsDuplicates(t *testing.T) {
+	checkHistory(t, []string{"a", "b", "b"}, [][2]string{
+		{"<Up>", "b|"},
+		{"<Up><Up>", "a|"},
+	})
+}
+
+func TestHistoryRecordsAcceptedLines(t *testing.T) {
+	h := testHistory{SubstateDefault: []string{"open a"}}
+	ts := newTestSession(h, historyBindings)
+	ts.press(t, "open b<Return>")
+	expected := []string{"open a", "open b"}
+	if !reflect.DeepEqual(h[SubstateDefault], expected) {
+		t.Errorf("History is %q, expected %q.", h[SubstateDefault], expected)
+	}
+	// Other substates have histories of their own.
+	if len(h.Entries(SubstateDefault+1)) != 0 {
+		t.Error("History of another substate was changed.")
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	lines := []string{"open golang.org", "tabopen example.com", "open go.dev"}
+	ts := newHistoryTestSession(lines...)
+	ts.press(t, "<C-r>go")
+	if s := ts.search(t); s.MatchText != "open go.dev" || s.Failing {
+		t.Errorf("Search matched %q, failing: %v.", s.MatchText, s.Failing)
+	}
+	ts.state = ts.search(t).Next()
+	if s := ts.search(t); s.MatchText != "open golang.org" {
+		t.Errorf("Searching further matched %q.", s.MatchText)
+	}
+	// Return accepts the match, and the command line.
+	ts.press(t, "<Return>")
+	if !reflect.DeepEqual(ts.accepted, []string{"open golang.org"}) {
+		t.Errorf("Accepted %q after a search.", ts.accepted)
+	}
+}
+
+func TestHistorySearchAcceptsOnOtherKeys(t *testing.T) {
+	lines := []string{"open golang.org", "tabopen example.com"}
+	for _, c := range [][2]string{
+		// Keys without text accept the match, and are then processed.
+		{"<C-r>exam<Left>", "tabopen example.co|m"},
+		// Bindings accept the match before they are executed.
+		{"<C-r>exam<C-w>", "tabopen |"},
+		// Escape cancels the search.
+		{"open<C-r>exam<Escape>", "open|"},
+		// Deleting the whole query cancels the search.
+		{"<C-r>e<BackSpace><BackSpace>", "|"},
+	} {
+		ts := newHistoryTestSession(lines...)
+		ts.press(t, c[0])
+		if line := ts.line(t); line != c[1] {
+			t.Errorf("Keys %s led to %q, expected %q.", c[0], line, c[1])
+		}
+	}
+}
+
+func TestHistorySearchFailing(t *testing.T) {
+	ts := newHistoryTestSession("open golang.org")
+	ts.press(t, "<C-r>gox")
+	s := ts.search(t)
+	if !s.Failing || s.MatchText != "open golang.org" {
+		t.Errorf("Search for a missing entry matched %q, failing: %v.",
+			s.MatchText, s.Failing)
+	}
+	// Removing the offending key finds the match again.
+	ts.press(t, "<BackSpace>")
+	if s := ts.search(t); s.Failing {
+		t.Error("Search still failing after fixing the query.")
+	}
+}
@@ -8,12 +8,39 @@ static guint
 gdk_event_key_is_modifier(GdkEventKey *key) {
 	return key->is_modifier;
 }
+
+static guint
+gdk_event_key_modifiers(GdkEventKey *key) {
+	GdkKeymap *keymap = gdk_keymap_get_default();
+	GdkModifierType consumed = 0;
+	GdkModifierType state;
+
+	gdk_keymap_translate_keyboard_state(
+		keymap,
+		key->hardware_keycode,
+		key->state,
+		key->group,
+		NULL,
+		NULL,
+		NULL,
+		&consumed);
+	// Keys not typing a character may consume control and alt (e.g. the
+	// function keys), these shouldn't be dropped.
+	if (!g_unichar_isprint(gdk_keyval_to_unicode(key->keyval))) {
+		consumed &= GDK_SHIFT_MASK | GDK_LOCK_MASK |
+			GDK_MOD3_MASK | GDK_MOD5_MASK;
+	}
+	state = key->state & ~consumed;
+	gdk_keymap_add_virtual_modifiers(keymap, &state);
+	return state;
+}
 */
 import "C"
 import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 	"unsafe"
 
@@ -70,9 +97,67 @@ const (
 
 	// The modifiers which are considered for comparison operations, all other
 	// modifiers are ignored.
-	modifierNormalMask = ControlMask | Mod1Mask
+	modifierNormalMask = ControlMask | Mod1Mask | ShiftMask | SuperMask |
+		HyperMask
 )
 
+// modifierPrefixes are the prefixes of key strings, and the modifiers they
+// represent.
+//
+// They are ordered as they are written for a key.
+var modifierPrefixes = []struct {
+	prefix string
+	mod    Modifiers
+}{
+	{"C-", ControlMask},
+	{"A-", Mod1Mask},
+	{"S-", ShiftMask},
+	{"Super-", SuperMask},
+	{"Hyper-", HyperMask},
+}
+
+// keyNameAliases maps lower case alternative names of keys to the names GDK
+// uses for them.
+//
+// GDK names are case sensitive, and are always tried first.
+var keyNameAliases = map[string]string{
+	"space":     "space",
+	"lt":        "less",
+	"gt":        "greater",
+	"esc":       "Escape",
+	"escape":    "Escape",
+	"cr":        "Return",
+	"enter":     "Return",
+	"return":    "Return",
+	"tab":       "Tab",
+	"bs":        "BackSpace",
+	"backspace": "BackSpace",
+	"del":       "Delete",
+	"delete":    "Delete",
+	"ins":       "Insert",
+	"insert":    "Insert",
+	"home":      "Home",
+	"end":       "End",
+	"pageup":    "Page_Up",
+	"pgup":      "Page_Up",
+	"pagedown":  "Page_Down",
+	"pgdn":      "Page_Down",
+	"up":        "Up",
+	"down":      "Down",
+	"left":      "Left",
+	"right":     "Right",
+}
+
+// keycodePrefix is the prefix of key strings naming a hardware keycode
+// rather than a key.
+const keycodePrefix = "keycode:"
+
+func init() {
+	for i := 1; i <= 24; i++ {
+		keyNameAliases[fmt.Sprintf("f%d", i)] = fmt.Sprintf("F%d", i)
+	}
+}
+
 // The constants in this block map directly to GDK keyvals, and are used to
 // compare with Key.Keyval to check which key was pressed.
 const (
@@ -119,6 +204,47 @@ func isNonPrintRune(r rune, selective bool) bool {
 	return false
 }
 
+// isPrintKeyval checks if a keyval types a printable character.
+//
+// The shift modifier is part of the keyval of such keys, and is therefore
+// ignored for them.
+func isPrintKeyval(keyval uint) bool {
+	r := rune(C.gdk_keyval_to_unicode(C.guint(keyval)))
+	return r != 0 && unicode.IsPrint(r)
+}
+
+// modifiersString produces the string prefix of a key for the given
+// modifiers, e.g. "C-A-".
+func modifiersString(mods Modifiers) string {
+	str := ""
+	for _, p := range modifierPrefixes {
+		if mods&p.mod != 0 {
+			str += p.prefix
+		}
+	}
+	return str
+}
+
+// keyvalFromName retrieves the keyval of a named key, or KeyVoid if no key
+// has the name.
+//
+// GDK's names are tried first, followed by keyNameAliases.
+func keyvalFromName(name string) uint {
+	cStr := (*C.gchar)(C.CString(name))
+	defer C.free(unsafe.Pointer(cStr))
+	keyval := uint(C.gdk_keyval_from_name(cStr))
+	if keyval != KeyVoid {
+		return keyval
+	}
+	alias, ok := keyNameAliases[strings.ToLower(name)]
+	if !ok {
+		return KeyVoid
+	}
+	cAlias := (*C.gchar)(C.CString(alias))
+	defer C.free(unsafe.Pointer(cAlias))
+	return uint(C.gdk_keyval_from_name(cAlias))
+}
+
 // keyParseError is an error parsing a key.
 //
 // Its string value is the string which failed to parse.
@@ -157,6 +283,10 @@ type RealKey struct {
 	Keyval     uint
 	Modifiers  Modifiers
 	IsModifier bool
+	// Keycode is the hardware keycode of the key, or 0 if it isn't known.
+	//
+	// It is ignored when comparing keys; see KeycodeKey.
+	Keycode uint
 }
 
 // IsNum checks if a real key is a number key.
@@ -204,18 +334,17 @@ func (k RealKey) Equals(k2 Key, mods Modifiers) bool {
 //
 // The long form will be used in all other cases.
 //
-// If a Key has the control modifier pressed, 'C-' is prepended. Likewise, if
-// alt is pressed, 'A-' is prepended. 'C-A-' is prepended if both are pressed.
+// If a Key has the control modifier pressed, 'C-' is prepended. Likewise,
+// 'A-', 'S-', 'Super-' and 'Hyper-' are prepended for alt, shift, super and
+// hyper, in this order. Shift is omitted for keys typing a character, as the
+// character already reflects it.
 func (k RealKey) StringSelective(selective bool) string {
-	// Produces string like "a", "C-a", "C-A-a", "Escape", "C-Escape"
-	str := ""
-
-	if (k.Modifiers & ControlMask) != 0 {
-		str += "C-"
-	}
-	if (k.Modifiers & Mod1Mask) != 0 {
-		str += "A-"
+	// Produces string like "a", "C-a", "C-A-a", "Escape", "C-S-Escape"
+	mods := k.Modifiers
+	if isPrintKeyval(k.Keyval) {
+		mods &^= ShiftMask
 	}
+	str := modifiersString(mods)
 
 	r := rune(C.gdk_keyval_to_unicode(C.guint(k.Keyval)))
 	if r != 0 && !isNonPrintRune(r, selective) {
@@ -233,19 +362,103 @@ func (k RealKey) String() string {
 	return k.StringSelective(true)
 }
 
-// Normalize keeps only Control and Alt modfiers of the key.
+// Normalize keeps only the Control, Alt, Shift, Super and Hyper modifiers of
+// the key, and drops its keycode.
+//
+// Shift is only kept for keys which don't type a character.
 func (k RealKey) Normalize() RealKey {
-	return RealKey{k.Keyval, k.Modifiers & modifierNormalMask, k.IsModifier}
+	mods := k.Modifiers & modifierNormalMask
+	if isPrintKeyval(k.Keyval) {
+		mods &^= ShiftMask
+	}
+	return RealKey{k.Keyval, mods, k.IsModifier, 0}
+}
+
+// KeycodeKey retrieves the layout independent key of the hardware key
+// pressed, with normalized modifiers.
+func (k RealKey) KeycodeKey() KeycodeKey {
+	return KeycodeKey{k.Keycode, k.Normalize().Modifiers}
+}
+
+// Describe describes how a key press is parsed, both by its keyval and by
+// its keycode.
+func (k RealKey) Describe() string {
+	return fmt.Sprintf(
+		"%s (keyval 0x%x, keycode %d: %s)",
+		KeysString([]Key{k.Normalize()}),
+		k.Keyval,
+		k.Keycode,
+		KeysString([]Key{k.KeycodeKey()}))
+}
+
+// KeyRune retrieves the unicode rune a key types, or 0 if it types none.
+//
+// Keys with modifiers and virtual keys type no rune.
+func KeyRune(k Key) rune {
+	rk, ok := k.(RealKey)
+	if !ok || rk.Normalize().Modifiers != 0 {
+		return 0
+	}
+	return rune(C.gdk_keyval_to_unicode(C.guint(rk.Keyval)))
 }
 
 // NewKeyFromEventKey converts a gdk key event into a Key.
+//
+// Modifiers consumed by the keyboard layout in producing the keyval (e.g.
+// shift for upper case letters, or AltGr) are removed, and virtual modifiers
+// such as super and hyper are added.
 func NewKeyFromEventKey(ek gdk.EventKey) RealKey {
 	cek := (*C.GdkEventKey)(unsafe.Pointer(ek.Native()))
 	return RealKey{
 		uint(cek.keyval),
-		Modifiers(cek.state),
+		Modifiers(C.gdk_event_key_modifiers(cek)),
 		C.gdk_event_key_is_modifier(cek) != 0,
+		uint(cek.hardware_keycode),
+	}
+}
+
+// A KeycodeKey is a key identified by its hardware keycode rather than its
+// keyval, and as such independent of the keyboard layout.
+//
+// Modifiers consumed by the layout (such as shift for letters) cannot be
+// part of a KeycodeKey.
+type KeycodeKey struct {
+	Keycode   uint
+	Modifiers Modifiers
+}
+
+// KeyType gets the type of the key.
+//
+// Will always return KeyTypeNormal.
+func (k KeycodeKey) KeyType() KeyType {
+	return KeyTypeNormal
+}
+
+// Equals compares to another key for equality, respecting a particular
+// modifier mask.
+func (k KeycodeKey) Equals(k2 Key, mods Modifiers) bool {
+	k2k, ok := k2.(KeycodeKey)
+	if !ok {
+		return false
 	}
+	return k.Keycode == k2k.Keycode &&
+		(k.Modifiers&mods) == (k2k.Modifiers&mods)
+}
+
+// String retrieves the string value of this key, e.g. "C-keycode:38".
+func (k KeycodeKey) String() string {
+	return fmt.Sprintf(
+		"%s%s%d",
+		modifiersString(k.Modifiers),
+		keycodePrefix,
+		k.Keycode)
+}
+
+// StringSelective retrieves the string value of this key.
+//
+// Equivalent to String for KeycodeKeys.
+func (k KeycodeKey) StringSelective(selective bool) string {
+	return k.String()
 }
 
 // A VirtualKey is the abstract notion of a named key.
@@ -279,7 +492,7 @@ func (k VirtualKey) StringSelective(selective bool) string {
 // NewKeyFromRune creates a new key object from a rune.
 func NewKeyFromRune(r rune) Key {
 	keyval := uint(C.gdk_unicode_to_keyval(C.guint32(r)))
-	return RealKey{keyval, 0, false}
+	return RealKey{keyval, 0, false, 0}
 }
 
 // NewKeyFromString creates a new key object from a string.
@@ -288,46 +501,67 @@ func NewKeyFromRune(r rune) Key {
 // flagged as not being modifiers. This functionality is at the time of
 // writing not required.
 //
-// If the string Starts with C-, A-, C-A- or A-C-, it will be interpreted
-// as the modifiers control, alt, both or both being pressed respectively.
+// The string may start with any combination of the prefixes C-, A-, S-,
+// Super- and Hyper-, which will be interpreted as the modifiers control, alt,
+// shift, super and hyper being pressed respectively.
 //
 // Beyond such a prefix, a key is either parsed as whichever key is associated
 // with the single unicode rune remaining, (e.g. a or ! or £), or whichever
-// key has the name of the string remaining (e.g. Escape, Enter, space)
+// key has the name of the string remaining (e.g. Escape, Return, space).
+// GDK's key names are case sensitive; failing these, common alternatives
+// such as Space, PageUp, Esc or CR are accepted regardless of case.
 //
-// Note the importance of capitalization.
+// Shift applied to a key typing a character produces the upper case
+// character instead (S-a is A), and S-Tab is the ISO_Left_Tab GDK reports.
+// Other shifted characters depend on the layout, and should be written
+// directly.
+//
+// The string keycode:N, again optionally prefixed by modifiers, is parsed
+// as the key with the hardware keycode N, regardless of layout.
 //
 // If a real key cannot be derived in this way, a virtual one is used instead.
 func NewKeyFromString(strOrig string) Key {
 	str := strOrig
 	var mod Modifiers
 loop:
-	for len(str) >= 2 {
-		switch str[0:2] {
-		case "C-":
-			mod |= ControlMask
-		case "A-":
-			mod |= Mod1Mask
-		default:
-			// We've probably got a key name.
-			break loop
+	for {
+		for _, p := range modifierPrefixes {
+			if len(str) > len(p.prefix) && strings.HasPrefix(str, p.prefix) {
+				mod |= p.mod
+				str = str[len(p.prefix):]
+				continue loop
+			}
+		}
+		// We've probably got a key name.
+		break
+	}
+	if strings.HasPrefix(str, keycodePrefix) {
+		code, err := strconv.ParseUint(str[len(keycodePrefix):], 10, 0)
+		if err == nil && code != 0 {
+			return KeycodeKey{uint(code), mod}
 		}
-		str = str[2:len(str)]
 	}
 	var keyval uint
 	if utf8.RuneCountInString(str) == 1 {
 		r, _ := utf8.DecodeRuneInString(str)
 		keyval = uint(C.gdk_unicode_to_keyval(C.guint32(r)))
 	} else {
-		cStr := (*C.gchar)(C.CString(str))
-		defer C.free(unsafe.Pointer(cStr))
-		keyval = uint(C.gdk_keyval_from_name(cStr))
+		keyval = keyvalFromName(str)
 	}
 	if keyval == KeyVoid {
 		// It's not a real key. So lets make it virtual.
 		return VirtualKey(strOrig)
 	}
-	return RealKey{keyval, mod, false}
+	if mod&ShiftMask != 0 {
+		if keyval == KeyTab {
+			keyval = KeyLeftTab
+			mod &^= ShiftMask
+		} else if isPrintKeyval(keyval) {
+			keyval = uint(C.gdk_keyval_to_upper(C.guint(keyval)))
+			mod &^= ShiftMask
+		}
+	}
+	return RealKey{keyval, mod, false, 0}
 }
 
 // KeysStringSelective produces a string representation of a slice of keys,
@@ -0,0 +1,39 @@
+package cmd
+
+// KeyTestMode is a mode which displays how each key pressed is parsed, to aid
+// in writing bindings.
+//
+// No bindings are executed in key test mode. Pressing escape twice in a row
+// returns to normal mode.
+type KeyTestMode struct {
+	*StateIndependant
+	Substate
+	// Key is the last key pressed, or nil if none was pressed yet.
+	Key *RealKey
+}
+
+// NewKeyTestMode creates a new KeyTestMode.
+func NewKeyTestMode(s State, st Substate) *KeyTestMode {
+	return &KeyTestMode{s.GetStateIndependant(), st, nil}
+}
+
+// ProcessKeyPress records the key pressed, and swallows it.
+func (s *KeyTestMode) ProcessKeyPress(key RealKey) (State, bool) {
+	if key.Keyval == KeyEscape &&
+		s.Key != nil &&
+		s.Key.Normalize() == key.Normalize() {
+
+		return NewNormalMode(s), true
+	}
+	return &KeyTestMode{s.StateIndependant, s.Substate, &key}, true
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *KeyTestMode) GetStateIndependant() *StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *KeyTestMode) GetSubstate() Substate {
+	return s.Substate
+}
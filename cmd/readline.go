@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"sync"
+	"unicode"
+)
+
+// killRingSize is the maximum number of entries kept in a kill ring.
+const killRingSize = 30
+
+// commandLineEdit is the kind of edit which produced a command line state.
+//
+// It is used to merge consecutive kills, to allow yank-pop directly after a
+// yank, and to undo consecutive insertions together.
+type commandLineEdit uint
+
+const (
+	editNone commandLineEdit = iota
+	editInsert
+	editKillForward
+	editKillBackward
+	editYank
+	editOther
+)
+
+// A KillRing stores text killed in command line mode, so that it can be
+// yanked back later.
+type KillRing struct {
+	entries [][]Key
+	mutex   *sync.Mutex
+}
+
+// NewKillRing creates a new, empty kill ring.
+func NewKillRing() *KillRing {
+	return &KillRing{make([][]Key, 0), new(sync.Mutex)}
+}
+
+// push adds a new entry to the kill ring.
+func (r *KillRing) push(keys []Key) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, keys)
+	if len(r.entries) > killRingSize {
+		r.entries = r.entries[len(r.entries)-killRingSize:]
+	}
+}
+
+// extend adds keys to the most recent entry of the kill ring, either in front
+// of it or after it.
+func (r *KillRing) extend(keys []Key, front bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.entries) == 0 {
+		r.entries = append(r.entries, keys)
+		return
+	}
+	top := r.entries[len(r.entries)-1]
+	newTop := make([]Key, 0, len(top)+len(keys))
+	if front {
+		newTop = append(append(newTop, keys...), top...)
+	} else {
+		newTop = append(append(newTop, top...), keys...)
+	}
+	r.entries[len(r.entries)-1] = newTop
+}
+
+// get retrieves the i-th most recent entry of the kill ring, wrapping around
+// at its end.
+//
+// ok is false if the kill ring is empty.
+func (r *KillRing) get(i int) (keys []Key, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.entries) == 0 {
+		return nil, false
+	}
+	return r.entries[len(r.entries)-1-i%len(r.entries)], true
+}
+
+// isWordKey checks if a key is part of a word for the purposes of word
+// motions.
+func isWordKey(k Key) bool {
+	r := KeyRune(k)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isSpaceKey checks if a key is whitespace.
+func isSpaceKey(k Key) bool {
+	return unicode.IsSpace(KeyRune(k))
+}
+
+// BackwardWord moves the cursor to the start of the current or previous word.
+func (s *CommandLineMode) BackwardWord() *CommandLineMode {
+	pos := s.CursorPos
+	for pos > 0 && !isWordKey(s.CurrentKeys[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordKey(s.CurrentKeys[pos-1]) {
+		pos--
+	}
+	return s.move(pos, min(pos, s.CursorHome), s.CursorEnd)
+}
+
+// ForwardWord moves the cursor to the end of the current or next word.
+func (s *CommandLineMode) ForwardWord() *CommandLineMode {
+	pos := s.CursorPos
+	for pos < len(s.CurrentKeys) && !isWordKey(s.CurrentKeys[pos]) {
+		pos++
+	}
+	for pos < len(s.CurrentKeys) && isWordKey(s.CurrentKeys[pos]) {
+		pos++
+	}
+	return s.move(
+		pos,
+		s.CursorHome,
+		min(len(s.CurrentKeys)-pos, s.CursorEnd))
+}
+
+// BeginningOfLine moves the cursor to the start of the editable part of the
+// command line, or to the very start if it is already there.
+func (s *CommandLineMode) BeginningOfLine() *CommandLineMode {
+	if s.CursorPos == s.CursorHome {
+		return s.move(0, 0, s.CursorEnd)
+	}
+	return s.move(s.CursorHome, s.CursorHome, s.CursorEnd)
+}
+
+// EndOfLine moves the cursor to the end of the editable part of the command
+// line, or to the very end if it is already there.
+func (s *CommandLineMode) EndOfLine() *CommandLineMode {
+	end := len(s.CurrentKeys) - s.CursorEnd
+	if s.CursorPos == end {
+		return s.move(len(s.CurrentKeys), s.CursorHome, 0)
+	}
+	return s.move(end, s.CursorHome, s.CursorEnd)
+}
+
+// kill removes the keys between from and to, and stores them in the kill
+// ring.
+//
+// Consecutive kills are merged into a single kill ring entry.
+func (s *CommandLineMode) kill(from, to int) *CommandLineMode {
+	if from == to {
+		return s
+	}
+	backward := to == s.CursorPos
+	killed := make([]Key, to-from)
+	copy(killed, s.CurrentKeys[from:to])
+	switch {
+	case s.lastEdit == editKillBackward || s.lastEdit == editKillForward:
+		s.KillRing.extend(killed, backward)
+	default:
+		s.KillRing.push(killed)
+	}
+	newKeys := make([]Key, 0, len(s.CurrentKeys)-len(killed))
+	newKeys = append(newKeys, s.CurrentKeys[:from]...)
+	newKeys = append(newKeys, s.CurrentKeys[to:]...)
+	e := editKillForward
+	if backward {
+		e = editKillBackward
+	}
+	return s.edit(
+		newKeys,
+		from,
+		min(from, s.CursorHome),
+		min(len(newKeys)-from, s.CursorEnd),
+		e)
+}
+
+// KillWord kills the whitespace delimited word before the cursor.
+func (s *CommandLineMode) KillWord() *CommandLineMode {
+	pos := s.CursorPos
+	for pos > 0 && isSpaceKey(s.CurrentKeys[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !isSpaceKey(s.CurrentKeys[pos-1]) {
+		pos--
+	}
+	return s.kill(pos, s.CursorPos)
+}
+
+// KillToStart kills everything before the cursor.
+func (s *CommandLineMode) KillToStart() *CommandLineMode {
+	return s.kill(0, s.CursorPos)
+}
+
+// KillToEnd kills everything after the cursor.
+func (s *CommandLineMode) KillToEnd() *CommandLineMode {
+	return s.kill(s.CursorPos, len(s.CurrentKeys))
+}
+
+// yank inserts the i-th most recent kill ring entry at the cursor.
+func (s *CommandLineMode) yank(i int) *CommandLineMode {
+	keys, ok := s.KillRing.get(i)
+	if !ok {
+		return s
+	}
+	ret := s.insert(keys, editYank)
+	ret.yankIndex = i
+	return ret
+}
+
+// Yank inserts the most recently killed text at the cursor.
+func (s *CommandLineMode) Yank() *CommandLineMode {
+	return s.yank(0)
+}
+
+// YankPop replaces the text just yanked with the next older kill ring entry.
+//
+// It only has an effect directly after a yank or another yank-pop.
+func (s *CommandLineMode) YankPop() *CommandLineMode {
+	if s.lastEdit != editYank || s.undo == nil {
+		return s
+	}
+	// Yank from the state before the last yank, which also makes undo
+	// revert the yank altogether.
+	return s.undo.yank(s.yankIndex + 1)
+}
+
+// Transpose swaps the key before the cursor with the key under it, and moves
+// the cursor forward. At the end of the line, the two keys before the cursor
+// are swapped instead.
+func (s *CommandLineMode) Transpose() *CommandLineMode {
+	pos := s.CursorPos
+	if pos == len(s.CurrentKeys) {
+		pos--
+	}
+	if pos < 1 {
+		return s
+	}
+	newKeys := make([]Key, len(s.CurrentKeys))
+	copy(newKeys, s.CurrentKeys)
+	newKeys[pos-1], newKeys[pos] = newKeys[pos], newKeys[pos-1]
+	return s.edit(
+		newKeys,
+		pos+1,
+		s.CursorHome,
+		min(len(newKeys)-pos-1, s.CursorEnd),
+		editOther)
+}
+
+// Undo reverts the last edit to the command line.
+func (s *CommandLineMode) Undo() *CommandLineMode {
+	if s.undo == nil {
+		return s
+	}
+	return s.undo
+}
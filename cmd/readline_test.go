@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+// A testSession feeds key presses to states as a window does, and records
+// the command lines accepted.
+type testSession struct {
+	state    State
+	accepted []string
+}
+
+// edit makes a command line binding out of an edit to the command line.
+func edit(
+	f func(*CommandLineMode) *CommandLineMode) func(*CommandLineMode) State {
+
+	return func(s *CommandLineMode) State {
+		return f(s)
+	}
+}
+
+// readlineBindings are the command line bindings of the readline edits.
+var readlineBindings = map[string]func(*CommandLineMode) State{
+	"<C-w>": edit((*CommandLineMode).KillWord),
+	"<C-u>": edit((*CommandLineMode).KillToStart),
+	"<C-k>": edit((*CommandLineMode).KillToEnd),
+	"<C-y>": edit((*CommandLineMode).Yank),
+	"<A-y>": edit((*CommandLineMode).YankPop),
+	"<C-t>": edit((*CommandLineMode).Transpose),
+	"<C-z>": edit((*CommandLineMode).Undo),
+	"<A-b>": edit((*CommandLineMode).BackwardWord),
+	"<A-f>": edit((*CommandLineMode).ForwardWord),
+}
+
+// newTestSession starts an empty command line with the given history and
+// command line bindings.
+//
+// In history search mode, bindings first accept the search, as they do in
+// golem.
+func newTestSession(
+	history History,
+	bindings map[string]func(*CommandLineMode) State) *testSession {
+
+	ts := &testSession{}
+	bs := make([]*Binding, 0, len(bindings))
+	for keys, f := range bindings {
+		f := f
+		bs = append(bs, &Binding{
+			ParseKeys(keys),
+			func(_ []Key, _ *int, _ Substate) {
+				switch s := ts.state.(type) {
+				case *CommandLineMode:
+					ts.state = f(s)
+				case *HistorySearchMode:
+					ts.state = f(s.Accept())
+				}
+			},
+			keys,
+			""})
+	}
+	tree, _ := NewBindingTree(bs)
+	normal := NewState(
+		nil,
+		map[Mode]*BindingTree{ModeCommandLine: tree},
+		history,
+		func() time.Duration { return time.Second },
+		func(s State) { ts.state = s },
+		func() State { return ts.state },
+		nil)
+	ts.state = NewCommandLineMode(normal, SubstateDefault, ts.acceptor())
+	return ts
+}
+
+// acceptor retrieves a finalizer which records accepted command lines.
+func (ts *testSession) acceptor() func(string) {
+	return func(line string) {
+		ts.accepted = append(ts.accepted, line)
+	}
+}
+
+// press presses keys, given as for ParseKeys.
+func (ts *testSession) press(t *testing.T, keys string) {
+	for _, k := range ParseKeys(keys) {
+		key, ok := k.(RealKey)
+		if !ok {
+			t.Fatalf("Key %v is not a real key.", k)
+		}
+		old := ts.state
+		s, _ := ts.state.ProcessKeyPress(key)
+		// State changes made by bindings take precedence.
+		if ts.state == old {
+			ts.state = s
+		}
+	}
+}
+
+// line retrieves the command line, with the cursor position marked by '|'.
+func (ts *testSession) line(t *testing.T) string {
+	s, ok := ts.state.(*CommandLineMode)
+	if !ok {
+		t.Fatalf("In %T instead of command line mode.", ts.state)
+	}
+	return KeysStringSelective(s.CurrentKeys[:s.CursorPos], false) + "|" +
+		KeysStringSelective(s.CurrentKeys[s.CursorPos:], false)
+}
+
+// checkReadline checks the command lines resulting from key sequences, each
+// pressed on a new command line.
+func checkReadline(t *testing.T, cases [][2]string) {
+	for _, c := range cases {
+		ts := newTestSession(nil, readlineBindings)
+		ts.press(t, c[0])
+		if line := ts.line(t); line != c[1] {
+			t.Errorf("Keys %s led to %q, expected %q.", c[0], line, c[1])
+		}
+	}
+}
+
+func TestCommandLineEditing(t *testing.T) {
+	checkReadline(t, [][2]string{
+		{"open", "open|"},
+		{"helo<Left>l", "hell|o"},
+		{"hello<Home>", "|hello"},
+		{"hello<Home><End>", "hello|"},
+		{"hello<Left><Left><BackSpace>", "he|lo"},
+		{"hello<Home><Delete>", "|ello"},
+		{"hello<Home><Right>", "h|ello"},
+	})
+}
+
+func TestCommandLineWordMotions(t *testing.T) {
+	checkReadline(t, [][2]string{
+		{"foo-bar baz<A-b>", "foo-bar |baz"},
+		{"foo-bar baz<A-b><A-b>", "foo-|bar baz"},
+		{"foo-bar baz<A-b><A-b><A-f>", "foo-bar| baz"},
+		{"foo bar<Home><A-f><A-f>", "foo bar|"},
+	})
+}
+
+func TestCommandLineKills(t *testing.T) {
+	checkReadline(t, [][2]string{
+		{"open foo bar<C-w>", "open foo |"},
+		{"abcdef<Left><Left><C-k>", "abcd|"},
+		{"abcdef<Left><Left><C-u>", "|ef"},
+		// Consecutive kills are yanked together.
+		{"open foo bar<C-w><C-w>", "open |"},
+		{"open foo bar<C-w><C-w><C-y>", "open foo bar|"},
+		{"abcdef<Left><Left><C-k><C-u><C-y>", "abcdef|"},
+		// Kills separated by other edits are not.
+		{"one two<C-w><BackSpace><C-w><C-y>", "one|"},
+		{"one two<C-w><BackSpace><C-w><C-y><A-y>", "two|"},
+		{"one two<C-w><BackSpace><C-w><C-y><A-y><A-y>", "one|"},
+		{"one two<C-w><BackSpace><C-w><C-y><A-y><C-z>", "|"},
+		// Yank-pop only follows a yank.
+		{"one two<C-w><BackSpace><C-w><C-y>x<A-y>", "onex|"},
+	})
+}
+
+func TestCommandLineTransposeAndUndo(t *testing.T) {
+	checkReadline(t, [][2]string{
+		{"ab<C-t>", "ba|"},
+		{"abc<Left><C-t>", "acb|"},
+		{"a<C-t>", "a|"},
+		{"abc<C-w><C-z>", "abc|"},
+		// Consecutive insertions are undone together.
+		{"abc<C-z>", "|"},
+		{"abc<Left>d<C-z>", "ab|c"},
+	})
+}
+
+func TestCommandLineKillRingIsShared(t *testing.T) {
+	ts := newTestSession(nil, readlineBindings)
+	ts.press(t, "killed<C-w><Return>")
+	// A new command line in the same session yanks from the same ring.
+	ts.state = NewCommandLineMode(ts.state, SubstateDefault, ts.acceptor())
+	ts.press(t, "<C-y>")
+	if line := ts.line(t); line != "killed|" {
+		t.Errorf("Yank in a new command line led to %q.", line)
+	}
+}
+
+func TestCommandLineAcceptAndCancel(t *testing.T) {
+	ts := newTestSession(nil, readlineBindings)
+	ts.press(t, "open a<Return>")
+	if _, ok := ts.state.(*NormalMode); !ok {
+		t.Errorf("Return led to %T, expected normal mode.", ts.state)
+	}
+	ts.state = NewCommandLineMode(ts.state, SubstateDefault, ts.acceptor())
+	ts.press(t, "open b<Escape>")
+	if _, ok := ts.state.(*NormalMode); !ok {
+		t.Errorf("Escape led to %T, expected normal mode.", ts.state)
+	}
+	if len(ts.accepted) != 1 || ts.accepted[0] != "open a" {
+		t.Errorf("Accepted %q, expected [\"open a\"].", ts.accepted)
+	}
+}
@@ -41,10 +41,6 @@ var primarySelectionPasteKey = NewKeyFromString("C-V")
 // run.
 var PrintBindings = false
 
-// timeout is the time waited in normal mode before an ambiguous binding is
-// executed.
-const timeout = time.Millisecond * 500
-
 // The State of some window/program is... well, it's state (in regards to
 // keypresses)
 type State interface {
@@ -89,12 +85,25 @@ const SubstateDefault Substate = 0
 // NewState creates a new state, in its original setting.
 func NewState(
 	bindings map[Substate]*BindingTree,
+	modeBindings map[Mode]*BindingTree,
+	history History,
+	timeout func() time.Duration,
 	setState func(State),
 	getState func() State,
 	completer CompleterFunction) State {
 
 	return &NormalMode{
-		&StateIndependant{bindings, setState, getState, completer},
+		&StateIndependant{
+			bindings,
+			modeBindings,
+			NewKillRing(),
+			history,
+			timeout,
+			setState,
+			getState,
+			completer,
+			false,
+		},
 		SubstateDefault,
 		make([]Key, 0),
 		bindings[SubstateDefault],
@@ -108,10 +117,85 @@ func NewState(
 // A StateIndependant encompasses all data indepentant of the state, avoiding
 // copying it around every time the state is changed.
 type StateIndependant struct {
-	Bindings  map[Substate]*BindingTree
+	Bindings map[Substate]*BindingTree
+	// ModeBindings are the bindings of modes other than normal mode. These
+	// consist of single keys only.
+	ModeBindings map[Mode]*BindingTree
+	KillRing     *KillRing
+	History      History
+	// Timeout retrieves the time waited in normal mode before an ambiguous
+	// binding is executed.
+	Timeout   func() time.Duration
 	SetState  func(s State)
 	GetState  func() State
 	Completer CompleterFunction
+	// synchronous is set while normal mode bindings are executed
+	// synchronously.
+	synchronous bool
+}
+
+// Synchronously runs f, executing any normal mode bindings triggered during
+// it synchronously rather than in their own goroutines.
+//
+// This allows keys to be fed into the state machine one after another, with
+// each binding taking effect before the next key is processed.
+func (si *StateIndependant) Synchronously(f func()) {
+	old := si.synchronous
+	si.synchronous = true
+	defer func() {
+		si.synchronous = old
+	}()
+	f()
+}
+
+// execute executes a binding. Unless bindings are being executed
+// synchronously, it is executed in a new goroutine.
+func (si *StateIndependant) execute(
+	binding func([]Key, *int, Substate),
+	keys []Key,
+	nump *int,
+	st Substate) {
+
+	if si.synchronous {
+		binding(keys, nump, st)
+	} else {
+		go binding(keys, nump, st)
+	}
+}
+
+// ExecuteModeBinding executes the binding for a key in a mode other than
+// normal mode, if one exists.
+//
+// s is the state processing the key, and is made the current state before
+// the binding is executed, so the binding can act on it. The binding is
+// executed synchronously, so that any state changes it makes take effect
+// before the next key press is processed. It returns whether a binding was
+// executed.
+func (si *StateIndependant) ExecuteModeBinding(
+	s State,
+	m Mode,
+	key RealKey) bool {
+
+	t := si.ModeBindings[m]
+	if t == nil {
+		return false
+	}
+	subtree, ok := t.lookup(key)
+	if !ok || subtree.Binding == nil {
+		return false
+	}
+	if PrintBindings {
+		log.Printf("Executing %v mode binding for %v...", m, key)
+	}
+	si.SetState(s)
+	subtree.Binding.To([]Key{key.Normalize()}, nil, s.GetSubstate())
+	return true
+}
+
+// hasModeBindings checks if any bindings exist for a mode.
+func (si *StateIndependant) hasModeBindings(m Mode) bool {
+	t := si.ModeBindings[m]
+	return t != nil && (t.Binding != nil || len(t.Subtrees) != 0)
 }
 
 // NormalMode is a mode which mostly deals with key sequence bindings.
@@ -212,7 +296,7 @@ func executeAfterTimeout(
 			return
 		}
 		// Continue
-	case <-time.After(timeout):
+	case <-time.After(s.GetStateIndependant().Timeout()):
 		// Continue
 	}
 	go binding(keys, nump, s.GetSubstate())
@@ -235,7 +319,7 @@ func (s *NormalMode) ProcessKeyPress(key RealKey) (State, bool) {
 			return s, false
 		}
 	}
-	subtree, ok := s.CurrentTree.Subtrees[key.Normalize()]
+	subtree, ok := s.CurrentTree.lookup(key)
 	num := s.num
 	inNum := s.inNum
 	hadNum := s.hadNum
@@ -260,7 +344,8 @@ func (s *NormalMode) ProcessKeyPress(key RealKey) (State, bool) {
 			} else {
 				nump = nil
 			}
-			go subtree.Binding.To(
+			s.execute(
+				subtree.Binding.To,
 				s.CurrentKeys,
 				nump,
 				s.Substate)
@@ -331,7 +416,8 @@ func (s *NormalMode) ProcessKeyPress(key RealKey) (State, bool) {
 				log.Printf("Executing binding for %v...",
 					KeysString(ImmutableAppend(s.CurrentKeys, key)))
 			}
-			go subtree.Binding.To(
+			s.execute(
+				subtree.Binding.To,
 				ImmutableAppend(s.CurrentKeys, key),
 				nump,
 				s.Substate)
@@ -370,7 +456,7 @@ func (s *NormalMode) GetSubstate() Substate {
 }
 
 // InsertMode is a mode which ignores any keypresses, with the exception of the
-// escape key,
+// escape key and any insert mode bindings.
 type InsertMode struct {
 	*StateIndependant
 	Substate
@@ -384,7 +470,13 @@ func NewInsertMode(s State, st Substate) *InsertMode {
 
 // ProcessKeyPress passes through any keys except escape, which it immediately
 // swallows and switches to normal mode.
+//
+// Keys with insert mode bindings are swallowed, and the binding executed
+// instead.
 func (s *InsertMode) ProcessKeyPress(key RealKey) (State, bool) {
+	if s.ExecuteModeBinding(s, ModeInsert, key) {
+		return s, true
+	}
 	if key.Keyval == KeyEscape {
 		return NewNormalMode(s), true
 	}
@@ -401,6 +493,50 @@ func (s *InsertMode) GetSubstate() Substate {
 	return s.Substate
 }
 
+// PassThroughMode is a mode which passes all keys through to the web page,
+// including escape.
+//
+// It is left only through pass-through mode bindings, allowing pages with
+// their own keyboard shortcuts to be used undisturbed. If there are no
+// pass-through mode bindings, shift-escape returns to normal mode, so that it
+// can't be entered without a way out.
+type PassThroughMode struct {
+	*StateIndependant
+	Substate
+}
+
+// NewPassThroughMode creates a new PassThroughMode.
+func NewPassThroughMode(s State, st Substate) *PassThroughMode {
+	return &PassThroughMode{s.GetStateIndependant(), st}
+}
+
+// ProcessKeyPress passes through any keys without pass-through mode bindings.
+//
+// Keys with pass-through mode bindings are swallowed, and the binding
+// executed instead.
+func (s *PassThroughMode) ProcessKeyPress(key RealKey) (State, bool) {
+	if s.ExecuteModeBinding(s, ModePassThrough, key) {
+		return s, true
+	}
+	if !s.hasModeBindings(ModePassThrough) &&
+		key.Keyval == KeyEscape &&
+		key.Normalize().Modifiers == ShiftMask {
+
+		return NewNormalMode(s), true
+	}
+	return s, false
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *PassThroughMode) GetStateIndependant() *StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *PassThroughMode) GetSubstate() Substate {
+	return s.Substate
+}
+
 // CommandLineMode a mode which allows the user to enter a single line of text.
 //
 // The invoker of CommandLineMode supplies a Finalizer function, which is used
@@ -413,6 +549,16 @@ type CommandLineMode struct {
 	CursorHome  int
 	CursorEnd   int
 	Finalizer   func(string)
+	// undo is the state before the last edit, or nil if nothing was edited.
+	undo *CommandLineMode
+	// lastEdit is the kind of edit which produced this state.
+	lastEdit commandLineEdit
+	// yankIndex is the kill ring index of the last yank, if lastEdit is
+	// editYank.
+	yankIndex int
+	// hist is the current navigation through the history, or nil if the
+	// command line wasn't recalled from it.
+	hist *historyNav
 }
 
 // NewCommandLineMode initializes a command line mode, starting from some
@@ -433,6 +579,10 @@ func NewCommandLineMode(
 		0,
 		0,
 		f,
+		nil,
+		editNone,
+		0,
+		nil,
 	}
 }
 
@@ -459,27 +609,96 @@ func NewPartialCommandLineMode(
 		len(keysBC),
 		len(keysBC),
 		len(keysAC),
-		f}
+		f,
+		nil,
+		editNone,
+		0,
+		nil,
+	}
+}
+
+// move returns a copy of the state with the cursor moved to pos.
+//
+// Moving the cursor is not recorded as an edit.
+func (s *CommandLineMode) move(pos, home, end int) *CommandLineMode {
+	return &CommandLineMode{
+		s.StateIndependant,
+		s.Substate,
+		s.CurrentKeys,
+		pos,
+		home,
+		end,
+		s.Finalizer,
+		s.undo,
+		editNone,
+		0,
+		nil,
+	}
+}
+
+// edit returns a new state with the given keys and cursor position.
+//
+// The current state is recorded so the edit can be undone. Consecutive
+// insertions are undone together.
+func (s *CommandLineMode) edit(
+	keys []Key,
+	pos, home, end int,
+	e commandLineEdit) *CommandLineMode {
+
+	undo := s
+	if e == editInsert && s.lastEdit == editInsert {
+		undo = s.undo
+	}
+	return &CommandLineMode{
+		s.StateIndependant,
+		s.Substate,
+		keys,
+		pos,
+		home,
+		end,
+		s.Finalizer,
+		undo,
+		e,
+		0,
+		nil,
+	}
+}
+
+// Edit returns a new state with the given keys, and the cursor at the end of
+// them.
+//
+// The current state is recorded so the edit can be undone.
+func (s *CommandLineMode) Edit(keys []Key) *CommandLineMode {
+	return s.edit(
+		keys,
+		len(keys),
+		min(len(keys), s.CursorHome),
+		0,
+		editOther)
 }
 
 // Paste pastes a string into the command line.
 func (s *CommandLineMode) Paste(str string) State {
-	insertKeys := ParseKeys(str)
+	return s.insert(ParseKeys(str), editOther)
+}
+
+// insert inserts keys at the cursor position.
+func (s *CommandLineMode) insert(
+	insertKeys []Key,
+	e commandLineEdit) *CommandLineMode {
+
 	// Grow keys
 	newKeys := make([]Key, len(s.CurrentKeys)+len(insertKeys))
 	// Copy data over
 	copy(newKeys[:s.CursorPos], s.CurrentKeys[:s.CursorPos])
 	copy(newKeys[s.CursorPos:s.CursorPos+len(insertKeys)], insertKeys)
 	copy(newKeys[s.CursorPos+len(insertKeys):], s.CurrentKeys[s.CursorPos:])
-	// Return
-	return &CommandLineMode{
-		s.StateIndependant,
-		s.Substate,
+	return s.edit(
 		newKeys,
-		s.CursorPos + len(insertKeys),
+		s.CursorPos+len(insertKeys),
 		s.CursorHome,
 		s.CursorEnd,
-		s.Finalizer}
+		e)
 }
 
 // ProcessKeyPress processes the press of a single Key in CommandLineMode.
@@ -490,11 +709,19 @@ func (s *CommandLineMode) Paste(str string) State {
 // BackSpace deletes the last read key, or if none are left, returns to
 // NormalMode.
 //
-// Enter accepts the CommandLine and runs the finalizer, returning to
-// NormalMode afterwards.
+// Enter accepts the CommandLine, adds it to the history and runs the
+// finalizer, returning to NormalMode afterwards.
+//
+// Up and Down recall history entries starting with the text before the
+// cursor.
 //
 // Escape returns to NormalMode.
+//
+// Command line mode bindings take precedence over all of the above.
 func (s *CommandLineMode) ProcessKeyPress(key RealKey) (State, bool) {
+	if s.ExecuteModeBinding(s, ModeCommandLine, key) {
+		return s, true
+	}
 	key = key.Normalize()
 	if key == pasteKey || key == primarySelectionPasteKey {
 		var clip *gtk.Clipboard
@@ -526,14 +753,7 @@ func (s *CommandLineMode) ProcessKeyPress(key RealKey) (State, bool) {
 		if s.CursorPos == 0 {
 			return s, false
 		}
-		return &CommandLineMode{
-			s.StateIndependant,
-			s.Substate,
-			s.CurrentKeys,
-			s.CursorHome,
-			s.CursorHome,
-			s.CursorEnd,
-			s.Finalizer}, true
+		return s.BeginningOfLine(), true
 	// Move cursor to end
 	case KeyKPEnd:
 		fallthrough
@@ -541,51 +761,44 @@ func (s *CommandLineMode) ProcessKeyPress(key RealKey) (State, bool) {
 		if s.CursorPos == len(s.CurrentKeys) {
 			return s, false
 		}
-		return &CommandLineMode{
-			s.StateIndependant,
-			s.Substate,
-			s.CurrentKeys,
-			len(s.CurrentKeys) - s.CursorEnd,
-			s.CursorHome,
-			s.CursorEnd,
-			s.Finalizer}, true
+		return s.EndOfLine(), true
 	// Execute command line
 	case KeyKPEnter:
 		fallthrough
 	case KeyReturn:
-		s.Finalizer(KeysStringSelective(s.CurrentKeys, false))
+		line := KeysStringSelective(s.CurrentKeys, false)
+		if s.History != nil {
+			s.History.Add(s.Substate, line)
+		}
+		s.Finalizer(line)
 		fallthrough
 	// Cancel command line
 	case KeyEscape:
 		return NewNormalMode(s), true
+	// Recall history
+	case KeyKPUp:
+		fallthrough
+	case KeyUp:
+		return s.HistoryPrevious(), true
+	case KeyKPDown:
+		fallthrough
+	case KeyDown:
+		return s.HistoryNext(), true
 	// Move cursor left
 	case KeyKPLeft:
 		fallthrough
 	case KeyLeft:
 		pos := max(s.CursorPos-1, 0)
-		return &CommandLineMode{
-			s.StateIndependant,
-			s.Substate,
-			s.CurrentKeys,
-			pos,
-			min(pos, s.CursorHome),
-			s.CursorEnd,
-			s.Finalizer,
-		}, true
+		return s.move(pos, min(pos, s.CursorHome), s.CursorEnd), true
 	// Move cursor right
 	case KeyKPRight:
 		fallthrough
 	case KeyRight:
 		pos := min(s.CursorPos+1, len(s.CurrentKeys))
-		return &CommandLineMode{
-			s.StateIndependant,
-			s.Substate,
-			s.CurrentKeys,
+		return s.move(
 			pos,
 			s.CursorHome,
-			min(len(s.CurrentKeys)-pos, s.CursorEnd),
-			s.Finalizer,
-		}, true
+			min(len(s.CurrentKeys)-pos, s.CursorEnd)), true
 	// Delete last key.
 	case KeyDelete:
 		fallthrough
@@ -601,15 +814,12 @@ func (s *CommandLineMode) ProcessKeyPress(key RealKey) (State, bool) {
 			copy(
 				newKeys[s.CursorPos:],
 				s.CurrentKeys[s.CursorPos+1:])
-			return &CommandLineMode{
-				s.StateIndependant,
-				s.Substate,
+			return s.edit(
 				newKeys,
 				s.CursorPos,
 				s.CursorHome,
 				min(len(newKeys)-s.CursorPos, s.CursorEnd),
-				s.Finalizer,
-			}, true
+				editOther), true
 		} else if len(s.CurrentKeys) == 0 {
 			return NewNormalMode(s), true
 		}
@@ -627,40 +837,19 @@ func (s *CommandLineMode) ProcessKeyPress(key RealKey) (State, bool) {
 			copy(
 				newKeys[s.CursorPos-1:],
 				s.CurrentKeys[s.CursorPos:])
-			return &CommandLineMode{
-				s.StateIndependant,
-				s.Substate,
+			return s.edit(
 				newKeys,
-				s.CursorPos - 1,
+				s.CursorPos-1,
 				min(s.CursorPos-1, s.CursorHome),
 				s.CursorEnd,
-				s.Finalizer,
-			}, true
+				editOther), true
 		} else if len(s.CurrentKeys) == 0 {
 			return NewNormalMode(s), true
 		}
 		return s, false
 	// Add new key
 	default:
-		newKeys := make([]Key, len(s.CurrentKeys)+1)
-		// Copy keys before cursor
-		copy(
-			newKeys[:s.CursorPos],
-			s.CurrentKeys[:s.CursorPos])
-		// Copy keys after cursor
-		copy(
-			newKeys[s.CursorPos+1:],
-			s.CurrentKeys[s.CursorPos:])
-		newKeys[s.CursorPos] = key
-		return &CommandLineMode{
-			s.StateIndependant,
-			s.Substate,
-			newKeys,
-			s.CursorPos + 1,
-			s.CursorHome,
-			s.CursorEnd,
-			s.Finalizer,
-		}, true
+		return s.insert([]Key{key}, editInsert), true
 	}
 }
 
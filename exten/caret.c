@@ -0,0 +1,101 @@
+#include <webkit2/webkit-web-extension.h>
+#include <glib.h>
+#include "caret.h"
+
+// Returns the selection of the main document, or NULL if there is none.
+static WebKitDOMDOMSelection *
+dom_get_selection(Exten *exten)
+{
+    if(exten->document == NULL) {
+        return NULL;
+    }
+    WebKitDOMDOMWindow *win = webkit_dom_document_get_default_view(
+            exten->document);
+    if(win == NULL) {
+        return NULL;
+    }
+    return webkit_dom_dom_window_get_selection(win);
+}
+
+gboolean
+start_caret(Exten *exten)
+{
+    WebKitDOMDOMSelection *sel = dom_get_selection(exten);
+    if(sel == NULL) {
+        return FALSE;
+    }
+    if(webkit_dom_dom_selection_get_range_count(sel) > 0) {
+        return TRUE;
+    }
+    WebKitDOMRange *range = webkit_dom_document_caret_range_from_point(
+            exten->document, 0, 0);
+    if(range != NULL) {
+        webkit_dom_dom_selection_add_range(sel, range);
+        webkit_dom_dom_selection_collapse_to_start(sel, NULL);
+        return TRUE;
+    }
+    // Nothing at the top of the viewport; fall back to the start of the
+    // document.
+    WebKitDOMHTMLElement *body = webkit_dom_document_get_body(exten->document);
+    if(body == NULL) {
+        return FALSE;
+    }
+    webkit_dom_dom_selection_collapse(sel, WEBKIT_DOM_NODE(body), 0);
+    return TRUE;
+}
+
+gboolean
+modify_selection(const gchar *alter,
+                 const gchar *direction,
+                 const gchar *granularity,
+                 Exten       *exten)
+{
+    WebKitDOMDOMSelection *sel = dom_get_selection(exten);
+    if(sel == NULL || webkit_dom_dom_selection_get_range_count(sel) == 0) {
+        return FALSE;
+    }
+    webkit_dom_dom_selection_modify(sel, alter, direction, granularity);
+    return TRUE;
+}
+
+gchar *
+get_selection(Exten *exten)
+{
+    WebKitDOMDOMSelection *sel = dom_get_selection(exten);
+    if(sel == NULL || webkit_dom_dom_selection_get_range_count(sel) == 0) {
+        return g_strdup("");
+    }
+    WebKitDOMRange *range = webkit_dom_dom_selection_get_range_at(
+            sel, 0, NULL);
+    if(range == NULL) {
+        return g_strdup("");
+    }
+    gchar *ret = webkit_dom_range_to_string(range, NULL);
+    if(ret == NULL) {
+        return g_strdup("");
+    }
+    return ret;
+}
+
+void
+collapse_selection(Exten *exten)
+{
+    WebKitDOMDOMSelection *sel = dom_get_selection(exten);
+    if(sel == NULL || webkit_dom_dom_selection_get_range_count(sel) == 0) {
+        return;
+    }
+    webkit_dom_dom_selection_collapse(
+            sel,
+            webkit_dom_dom_selection_get_focus_node(sel),
+            webkit_dom_dom_selection_get_focus_offset(sel));
+}
+
+void
+end_caret(Exten *exten)
+{
+    WebKitDOMDOMSelection *sel = dom_get_selection(exten);
+    if(sel == NULL) {
+        return;
+    }
+    webkit_dom_dom_selection_remove_all_ranges(sel);
+}
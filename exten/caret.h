@@ -0,0 +1,35 @@
+#ifndef GOLEM_CARET_H
+#define GOLEM_CARET_H
+
+#include <glib.h>
+#include "libgolem.h"
+
+// start_caret places the caret at the top left of the viewport, unless a
+// selection exists already.
+gboolean
+start_caret(Exten *exten);
+
+// modify_selection moves the caret or extends the selection, as
+// window.getSelection().modify() does.
+gboolean
+modify_selection(const gchar *alter,
+                 const gchar *direction,
+                 const gchar *granularity,
+                 Exten       *exten);
+
+// get_selection retrieves the text of the current selection.
+//
+// The string is transferred to the caller and must be freed.
+gchar *
+get_selection(Exten *exten);
+
+// collapse_selection collapses the selection to its focus, leaving only the
+// caret.
+void
+collapse_selection(Exten *exten);
+
+// end_caret removes the caret and any selection.
+void
+end_caret(Exten *exten);
+
+#endif /* GOLEM_CARET_H */
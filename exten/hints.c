@@ -241,35 +241,252 @@ hint_call_by_click(WebKitDOMNode *n, Exten *exten)
     return FALSE;
 }
 
-// TODO: For now, this is limited to check if it is visible within its own
-// document.
+// hint_call_string calls the hint with the given string, printing any error.
 static gboolean
-is_visible(GHashTable *h, WebKitDOMNode *n) {
-    if(!WEBKIT_DOM_IS_ELEMENT(n)) {
+hint_call_string(const gchar *str, Exten *exten)
+{
+    GError *err = NULL;
+    gboolean ret = hint_call(str, exten, &err);
+    if(err != NULL) {
+        printf("Failed to call hint: %s\n", err->message);
+        g_error_free(err);
+    }
+    return ret;
+}
+
+// Calls the hint with the text of the passed node.
+gboolean
+hint_call_by_text(WebKitDOMNode *n, Exten *exten)
+{
+    gchar *text = webkit_dom_node_get_text_content(n);
+    if(text == NULL) {
         return FALSE;
     }
-    WebKitDOMElement *e = WEBKIT_DOM_ELEMENT(n);
-    Point *p = dom_get_absolute_position(h, e);
+    gchar *stripped = g_strstrip(text);
+    gboolean ret = hint_call_string(stripped, exten);
+    g_free(text);
+    return ret;
+}
+
+// Calls the hint with the source uri of the passed image.
+gboolean
+hint_call_by_src(WebKitDOMNode *n, Exten *exten)
+{
+    if(!WEBKIT_DOM_IS_HTML_IMAGE_ELEMENT(n)) {
+        return TRUE;
+    }
+    // The src property is already resolved against the document's uri.
+    gchar *src = webkit_dom_html_image_element_get_src(
+            WEBKIT_DOM_HTML_IMAGE_ELEMENT(n));
+    if(src == NULL) {
+        return TRUE;
+    }
+    gboolean ret = hint_call_string(src, exten);
+    g_free(src);
+    return ret;
+}
+
+// Focuses the passed node.
+gboolean
+hint_call_by_focus(WebKitDOMNode *n, Exten *exten)
+{
+    if(WEBKIT_DOM_IS_ELEMENT(n)) {
+        webkit_dom_element_focus(WEBKIT_DOM_ELEMENT(n));
+    }
+    return FALSE;
+}
 
-    glong vp_width, vp_height, vp_x_offset, vp_y_offset;
-    WebKitDOMDOMWindow *vp = webkit_dom_document_get_default_view(
-            webkit_dom_node_get_owner_document(n));
-    g_object_get(vp,
+// Moves the mouse over the passed node, by dispatching mouseover and
+// mouseenter events to it.
+gboolean
+hint_call_by_hover(WebKitDOMNode *n, Exten *exten)
+{
+    const char* const types[] = {"mouseover", "mouseenter", NULL};
+    WebKitDOMDocument *doc = webkit_dom_node_get_owner_document(n);
+    WebKitDOMDOMWindow *view = webkit_dom_document_get_default_view(doc);
+    guint i;
+    for(i = 0; types[i] != NULL; i++) {
+        GError *err = NULL;
+        WebKitDOMEvent *ev = webkit_dom_document_create_event(
+                doc, "MouseEvents", &err);
+        if(err != NULL) {
+            printf("Failed to create mouse event: %s\n", err->message);
+            g_error_free(err);
+            return FALSE;
+        }
+        webkit_dom_mouse_event_init_mouse_event(
+                WEBKIT_DOM_MOUSE_EVENT(ev),
+                types[i],
+                // mouseenter doesn't bubble.
+                i == 0,
+                TRUE,
+                view,
+                0, 0, 0, 0, 0,
+                FALSE, FALSE, FALSE, FALSE,
+                0,
+                NULL);
+        webkit_dom_event_target_dispatch_event(
+                WEBKIT_DOM_EVENT_TARGET(n), ev, &err);
+        g_object_unref(ev);
+        if(err != NULL) {
+            printf("Failed to dispatch mouse event: %s\n", err->message);
+            g_error_free(err);
+            return FALSE;
+        }
+    }
+    return FALSE;
+}
+
+// Makes the passed node the target of scrolling.
+gboolean
+hint_call_by_scroll_target(WebKitDOMNode *n, Exten *exten)
+{
+    if(WEBKIT_DOM_IS_ELEMENT(n)) {
+        exten->scroll_target = WEBKIT_DOM_ELEMENT(n);
+    }
+    return FALSE;
+}
+
+typedef struct _Rect {
+    gdouble x;
+    gdouble y;
+    gdouble width;
+    gdouble height;
+} Rect;
+
+// clip_rect clips a rectangle to the given bounds. Returns FALSE if nothing of
+// it remains.
+static gboolean
+clip_rect(Rect *r, gdouble width, gdouble height)
+{
+    gdouble right = MIN(r->x + r->width, width);
+    gdouble bottom = MIN(r->y + r->height, height);
+    r->x = MAX(r->x, 0);
+    r->y = MAX(r->y, 0);
+    r->width = right - r->x;
+    r->height = bottom - r->y;
+    return r->width > 0 && r->height > 0;
+}
+
+// client_rect gets the bounding rectangle of an element relative to the
+// viewport of its document, clipped by that viewport. Returns FALSE if the
+// element isn't within the viewport.
+static gboolean
+client_rect(WebKitDOMElement *e, Rect *r)
+{
+    WebKitDOMDOMWindow *win = webkit_dom_document_get_default_view(
+            webkit_dom_node_get_owner_document(WEBKIT_DOM_NODE(e)));
+    if(win == NULL) {
+        return FALSE;
+    }
+    WebKitDOMClientRect *cr = webkit_dom_element_get_bounding_client_rect(e);
+    if(cr == NULL) {
+        return FALSE;
+    }
+    r->x = webkit_dom_client_rect_get_left(cr);
+    r->y = webkit_dom_client_rect_get_top(cr);
+    r->width = webkit_dom_client_rect_get_width(cr);
+    r->height = webkit_dom_client_rect_get_height(cr);
+    g_object_unref(cr);
+    glong vp_width, vp_height;
+    g_object_get(win,
             "inner-width", &vp_width,
             "inner-height", &vp_height,
-            "page-x-offset", &vp_x_offset,
-            "page-y-offset", &vp_y_offset,
             NULL);
-    return
-        p->x >= vp_x_offset &&
-        p->x <= vp_x_offset + vp_width &&
-        p->y >= vp_y_offset &&
-        p->y <= vp_y_offset + vp_height;
+    return clip_rect(r, vp_width, vp_height);
+}
+
+// viewport_rect gets the bounding rectangle of an element relative to the
+// top level viewport, clipped by the viewports of all frames it is in.
+// Returns FALSE if no part of the element is visible.
+static gboolean
+viewport_rect(WebKitDOMElement *e, Rect *r)
+{
+    if(!client_rect(e, r)) {
+        return FALSE;
+    }
+    WebKitDOMDocument *doc =
+        webkit_dom_node_get_owner_document(WEBKIT_DOM_NODE(e));
+    while(TRUE) {
+        WebKitDOMElement *frame = webkit_dom_dom_window_get_frame_element(
+                webkit_dom_document_get_default_view(doc));
+        if(frame == NULL) {
+            return TRUE;
+        }
+        Rect fr;
+        if(!client_rect(frame, &fr)) {
+            return FALSE;
+        }
+        // The frame's content starts within its border.
+        r->x += fr.x + webkit_dom_element_get_client_left(frame);
+        r->y += fr.y + webkit_dom_element_get_client_top(frame);
+        r->width = MIN(r->x + r->width, fr.x + fr.width) - r->x;
+        r->height = MIN(r->y + r->height, fr.y + fr.height) - r->y;
+        r->x = MAX(r->x, fr.x);
+        r->y = MAX(r->y, fr.y);
+        if(r->width <= 0 || r->height <= 0) {
+            return FALSE;
+        }
+        doc = webkit_dom_node_get_owner_document(WEBKIT_DOM_NODE(frame));
+    }
+}
+
+// in_shadow_tree checks if a node is within a shadow tree, rather than its
+// document.
+static gboolean
+in_shadow_tree(WebKitDOMNode *n)
+{
+    WebKitDOMNode *parent;
+    while((parent = webkit_dom_node_get_parent_node(n)) != NULL) {
+        n = parent;
+    }
+    return !WEBKIT_DOM_IS_DOCUMENT(n);
+}
+
+// is_obscured checks if the centre of the visible part of an element is
+// covered by another element.
+//
+// Elements within shadow trees are hit tested as their shadow host, which
+// isn't accessible through the DOM bindings. They are never considered
+// obscured.
+static gboolean
+is_obscured(WebKitDOMElement *e)
+{
+    if(in_shadow_tree(WEBKIT_DOM_NODE(e))) {
+        return FALSE;
+    }
+    Rect r;
+    if(!client_rect(e, &r)) {
+        return TRUE;
+    }
+    WebKitDOMElement *hit = webkit_dom_document_element_from_point(
+            webkit_dom_node_get_owner_document(WEBKIT_DOM_NODE(e)),
+            (glong)(r.x + r.width / 2),
+            (glong)(r.y + r.height / 2));
+    if(hit == NULL || hit == e) {
+        return FALSE;
+    }
+    return !webkit_dom_node_contains(WEBKIT_DOM_NODE(e), WEBKIT_DOM_NODE(hit));
+}
+
+// is_visible checks if any part of an element is within the top level
+// viewport, and it isn't covered by other elements.
+static gboolean
+is_visible(WebKitDOMNode *n) {
+    if(!WEBKIT_DOM_IS_ELEMENT(n)) {
+        return FALSE;
+    }
+    Rect r;
+    return viewport_rect(WEBKIT_DOM_ELEMENT(n), &r) &&
+        !is_obscured(WEBKIT_DOM_ELEMENT(n));
 }
 
 static void
 scan_documents(WebKitDOMDocument *doc, GList **l, Exten *exten)
 {
+    if(doc == NULL) {
+        return;
+    }
     *l = g_list_prepend(*l, doc);
     WebKitDOMNodeList *iframes = webkit_dom_document_get_elements_by_tag_name(doc, "IFRAME");
     gulong len = webkit_dom_node_list_get_length(iframes);
@@ -283,9 +500,72 @@ scan_documents(WebKitDOMDocument *doc, GList **l, Exten *exten)
     }
 }
 
-// Selects text input elements of forms.
-GList *
-select_form_text_variables(GHashTable *h, Exten *exten)
+// SHADOW_ELEMENTS_JS collects all elements within open shadow roots,
+// including those in frames within them, which the DOM bindings can't
+// reach.
+#define SHADOW_ELEMENTS_JS \
+    "(function() {\n" \
+    "    var ret = [];\n" \
+    "    function scan(root, inShadow) {\n" \
+    "        var elems = root.querySelectorAll('*');\n" \
+    "        for(var i = 0; i < elems.length; i++) {\n" \
+    "            var e = elems[i];\n" \
+    "            if(inShadow) {\n" \
+    "                ret.push(e);\n" \
+    "            }\n" \
+    "            if(e.shadowRoot) {\n" \
+    "                scan(e.shadowRoot, true);\n" \
+    "            }\n" \
+    "            if(e.tagName === 'IFRAME') {\n" \
+    "                var doc = null;\n" \
+    "                try {\n" \
+    "                    doc = e.contentDocument;\n" \
+    "                } catch(err) {}\n" \
+    "                if(doc) {\n" \
+    "                    scan(doc, inShadow);\n" \
+    "                }\n" \
+    "            }\n" \
+    "        }\n" \
+    "    }\n" \
+    "    scan(document, false);\n" \
+    "    return ret;\n" \
+    "})()"
+
+// scan_shadow_elements retrieves the elements in open shadow roots of the
+// page.
+static GList *
+scan_shadow_elements(Exten *exten)
+{
+    WebKitFrame *frame = webkit_web_page_get_main_frame(exten->web_page);
+    JSCContext *ctx = webkit_frame_get_js_context(frame);
+    JSCValue *elems = jsc_context_evaluate(ctx, SHADOW_ELEMENTS_JS, -1);
+    GList *ret = NULL;
+    if(jsc_value_is_array(elems)) {
+        JSCValue *len_value = jsc_value_object_get_property(elems, "length");
+        gint32 len = jsc_value_to_int32(len_value);
+        g_object_unref(len_value);
+        gint32 i;
+        for(i = 0; i < len; i++) {
+            JSCValue *v = jsc_value_object_get_property_at_index(elems, i);
+            WebKitDOMNode *n = webkit_dom_node_for_js_value(v);
+            if(n != NULL) {
+                ret = g_list_prepend(ret, n);
+            }
+            g_object_unref(v);
+        }
+    }
+    g_object_unref(elems);
+    g_object_unref(ctx);
+    return g_list_reverse(ret);
+}
+
+// NodePredicate checks if a node should be hinted.
+typedef gboolean (*NodePredicate)(WebKitDOMNode*);
+
+// select_elements selects all visible elements satisfying a predicate, from
+// all frames and open shadow roots of the page.
+static GList *
+select_elements(NodePredicate p, Exten *exten)
 {
     if(exten->document == NULL) {
         return NULL;
@@ -295,48 +575,103 @@ select_form_text_variables(GHashTable *h, Exten *exten)
     scan_documents(exten->document, &docs, exten);
     GList *l;
     for(l = docs; l != NULL; l = l->next) {
-        WebKitDOMNodeList *nl = webkit_dom_document_get_elements_by_tag_name(
-                l->data, "INPUT");
+        WebKitDOMNodeList *nl =
+            webkit_dom_document_get_elements_by_tag_name(l->data, "*");
         gulong len = webkit_dom_node_list_get_length(nl);
         gulong i;
         for(i = 0; i < len; i++) {
             WebKitDOMNode *item = webkit_dom_node_list_item(nl, i);
-            if(!is_visible(h, item) || !WEBKIT_DOM_IS_HTML_INPUT_ELEMENT(item)) {
-                continue;
-            }
-            WebKitDOMHTMLInputElement *input = WEBKIT_DOM_HTML_INPUT_ELEMENT(item);
-            // Filter non-text input types.
-            gchar *input_type = webkit_dom_html_input_element_get_input_type(input);
-            if(input_type != NULL &&
-                    *input_type != '\0' &&
-                    g_strcmp0(input_type, "text") != 0 &&
-                    g_strcmp0(input_type, "search") != 0) {
-                g_free(input_type);
-                continue;
-            }
-            g_free(input_type);
-            WebKitDOMHTMLFormElement *form = webkit_dom_html_input_element_get_form(input);
-            if(form == NULL) {
-                continue;
-            }
-            // Filter non-get forms.
-            gchar *method = webkit_dom_html_form_element_get_method(form);
-            if(method != NULL && *method != '\0' && g_strcmp0(method, "get") != 0) {
-                g_free(method);
+            if(!p(item) || !is_visible(item)) {
                 continue;
             }
-            g_free(method);
             g_object_ref(item);
             ret = g_list_prepend(ret, item);
         }
     }
     g_list_free(docs);
+    GList *shadow = scan_shadow_elements(exten);
+    for(l = shadow; l != NULL; l = l->next) {
+        if(!p(l->data) || !is_visible(l->data)) {
+            continue;
+        }
+        g_object_ref(l->data);
+        ret = g_list_prepend(ret, l->data);
+    }
+    g_list_free(shadow);
+    return ret;
+}
+
+// has_href checks if an anchor or area element links somewhere.
+static gboolean
+has_href(WebKitDOMNode *n)
+{
+    gchar *href = NULL;
+    if(WEBKIT_DOM_IS_HTML_ANCHOR_ELEMENT(n)) {
+        href = webkit_dom_html_anchor_element_get_href(
+                WEBKIT_DOM_HTML_ANCHOR_ELEMENT(n));
+    } else if(WEBKIT_DOM_IS_HTML_AREA_ELEMENT(n)) {
+        href = webkit_dom_html_area_element_get_href(
+                WEBKIT_DOM_HTML_AREA_ELEMENT(n));
+    }
+    gboolean ret = href != NULL && *href != '\0';
+    g_free(href);
     return ret;
 }
 
+// is_form_text_variable checks if a node is a text input element of a get
+// form.
+static gboolean
+is_form_text_variable(WebKitDOMNode *n)
+{
+    if(!WEBKIT_DOM_IS_HTML_INPUT_ELEMENT(n)) {
+        return FALSE;
+    }
+    WebKitDOMHTMLInputElement *input = WEBKIT_DOM_HTML_INPUT_ELEMENT(n);
+    // Filter non-text input types.
+    gchar *input_type = webkit_dom_html_input_element_get_input_type(input);
+    if(input_type != NULL &&
+            *input_type != '\0' &&
+            g_strcmp0(input_type, "text") != 0 &&
+            g_strcmp0(input_type, "search") != 0) {
+        g_free(input_type);
+        return FALSE;
+    }
+    g_free(input_type);
+    WebKitDOMHTMLFormElement *form = webkit_dom_html_input_element_get_form(input);
+    if(form == NULL) {
+        return FALSE;
+    }
+    // Filter non-get forms.
+    gchar *method = webkit_dom_html_form_element_get_method(form);
+    gboolean ret = method == NULL ||
+        *method == '\0' ||
+        g_strcmp0(method, "get") == 0;
+    g_free(method);
+    return ret;
+}
+
+// Selects text input elements of forms.
+GList *
+select_form_text_variables(GHashTable *h, Exten *exten)
+{
+    return select_elements(is_form_text_variable, exten);
+}
+
+// is_clickable checks if a node may normally be clicked.
+static gboolean
+is_clickable(WebKitDOMNode *n)
+{
+    return (WEBKIT_DOM_IS_HTML_ANCHOR_ELEMENT(n) && has_href(n)) ||
+        WEBKIT_DOM_IS_HTML_INPUT_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_EMBED_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_BUTTON_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_TEXT_AREA_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_SELECT_ELEMENT(n);
+}
+
 // Selects all elements which may normally be clicked.
 // 
-// - Anchor elements
+// - Anchor elements with a href
 // - Input elements
 // - Embed elements
 // - Button elements
@@ -345,81 +680,241 @@ select_form_text_variables(GHashTable *h, Exten *exten)
 GList *
 select_clickable(GHashTable *h, Exten *exten)
 {
-    if(exten->document == NULL) {
-        return NULL;
-    }
-    const char* const tags[] = {
-        "A",
-        "INPUT",
-        "EMBED",
-        "BUTTON",
-        "TEXTAREA",
-        "SELECT",
-        NULL};
-    GList *ret = NULL;
-    GList *docs = NULL;
-    scan_documents(exten->document, &docs, exten);
+    return select_elements(is_clickable, exten);
+}
 
-    GList *l;
-    for(l = docs; l != NULL; l = l->next) {
+// Selects all anchor and area elements with a href.
+GList *
+select_links(GHashTable *h, Exten *exten)
+{
+    return select_elements(has_href, exten);
+}
+
+// is_image checks if a node is an image.
+static gboolean
+is_image(WebKitDOMNode *n)
+{
+    return WEBKIT_DOM_IS_HTML_IMAGE_ELEMENT(n);
+}
+
+// Selects all images.
+GList *
+select_images(GHashTable *h, Exten *exten)
+{
+    return select_elements(is_image, exten);
+}
+
+// is_text_input checks if an element accepts text input.
+static gboolean
+is_text_input(WebKitDOMNode *n)
+{
+    if(WEBKIT_DOM_IS_HTML_TEXT_AREA_ELEMENT(n)) {
+        return TRUE;
+    }
+    if(WEBKIT_DOM_IS_HTML_INPUT_ELEMENT(n)) {
+        const char* const types[] = {
+            "",
+            "text",
+            "search",
+            "email",
+            "password",
+            "url",
+            "tel",
+            "number",
+            NULL};
+        gchar *type = webkit_dom_html_input_element_get_input_type(
+                WEBKIT_DOM_HTML_INPUT_ELEMENT(n));
+        gboolean ret = FALSE;
         guint i;
-        for(i = 0; tags[i] != NULL; i++) {
-            WebKitDOMNodeList *nl =
-                webkit_dom_document_get_elements_by_tag_name(l->data, tags[i]);
-            gulong len = webkit_dom_node_list_get_length(nl);
-            gulong j;
-            for(j = 0; j < len; j++) {
-                WebKitDOMNode *item = webkit_dom_node_list_item(nl, j);
-                if(!is_visible(h, item)) {
-                    continue;
-                }
-                // special case for A elements: ignore those without href.
-                if(i == 0) {
-                    if(!WEBKIT_DOM_IS_HTML_ANCHOR_ELEMENT(item)) {
-                        continue;
-                    }
-                    gchar *href = webkit_dom_html_anchor_element_get_href(
-                            WEBKIT_DOM_HTML_ANCHOR_ELEMENT(item));
-                    if(href == NULL || *href == '\0') {
-                        g_free(href);
-                        continue;
-                    }
-                    g_free(href);
-                }
-                g_object_ref(item);
-                ret = g_list_prepend(ret, item);
+        for(i = 0; types[i] != NULL; i++) {
+            if(g_strcmp0(type == NULL ? "" : type, types[i]) == 0) {
+                ret = TRUE;
+                break;
             }
         }
+        g_free(type);
+        return ret;
     }
-    g_list_free(docs);
+    return WEBKIT_DOM_IS_HTML_ELEMENT(n) &&
+        webkit_dom_html_element_get_is_content_editable(
+                WEBKIT_DOM_HTML_ELEMENT(n));
+}
+
+// is_input_hint checks if a node accepts text input, and isn't contained in
+// another content editable element.
+static gboolean
+is_input_hint(WebKitDOMNode *n)
+{
+    if(!is_text_input(n)) {
+        return FALSE;
+    }
+    // Only the outermost content editable element is hinted.
+    WebKitDOMNode *parent = webkit_dom_node_get_parent_node(n);
+    return WEBKIT_DOM_IS_HTML_INPUT_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_TEXT_AREA_ELEMENT(n) ||
+        parent == NULL ||
+        !is_text_input(parent);
+}
+
+// Selects all elements accepting text input.
+//
+// - Text-like input elements
+// - TextArea elements
+// - Content editable elements
+GList *
+select_inputs(GHashTable *h, Exten *exten)
+{
+    return select_elements(is_input_hint, exten);
+}
+
+// is_hoverable checks if a node likely reacts to the mouse hovering over it.
+static gboolean
+is_hoverable(WebKitDOMNode *n)
+{
+    if(!WEBKIT_DOM_IS_ELEMENT(n)) {
+        return FALSE;
+    }
+    WebKitDOMElement *e = WEBKIT_DOM_ELEMENT(n);
+    return WEBKIT_DOM_IS_HTML_BUTTON_ELEMENT(n) ||
+        WEBKIT_DOM_IS_HTML_IMAGE_ELEMENT(n) ||
+        (WEBKIT_DOM_IS_HTML_ANCHOR_ELEMENT(n) && has_href(n)) ||
+        webkit_dom_element_has_attribute(e, "TITLE") ||
+        webkit_dom_element_has_attribute(e, "ONMOUSEOVER") ||
+        webkit_dom_element_has_attribute(e, "ONMOUSEENTER");
+}
+
+// Selects all elements which likely react to the mouse hovering over them.
+//
+// - Anchor elements with a href
+// - Button elements
+// - Image elements
+// - Elements with a title, or mouseover or mouseenter handler attributes
+GList *
+select_hoverable(GHashTable *h, Exten *exten)
+{
+    return select_elements(is_hoverable, exten);
+}
+
+// is_scrollable checks if an element has content overflowing it, which may be
+// scrolled to.
+static gboolean
+is_scrollable(WebKitDOMElement *e)
+{
+    gboolean overflows_y = webkit_dom_element_get_scroll_height(e) >
+        webkit_dom_element_get_client_height(e);
+    gboolean overflows_x = webkit_dom_element_get_scroll_width(e) >
+        webkit_dom_element_get_client_width(e);
+    if(!overflows_x && !overflows_y) {
+        return FALSE;
+    }
+    WebKitDOMDocument *doc = webkit_dom_node_get_owner_document(
+            WEBKIT_DOM_NODE(e));
+    WebKitDOMCSSStyleDeclaration *style = webkit_dom_dom_window_get_computed_style(
+            webkit_dom_document_get_default_view(doc), e, NULL);
+    if(style == NULL) {
+        return FALSE;
+    }
+    gboolean ret = FALSE;
+    const char* const props[] = {"overflow-y", "overflow-x", NULL};
+    gboolean overflows[] = {overflows_y, overflows_x};
+    guint i;
+    for(i = 0; props[i] != NULL; i++) {
+        if(!overflows[i]) {
+            continue;
+        }
+        gchar *overflow = webkit_dom_css_style_declaration_get_property_value(
+                style, props[i]);
+        if(g_strcmp0(overflow, "auto") == 0 ||
+                g_strcmp0(overflow, "scroll") == 0) {
+            ret = TRUE;
+        }
+        g_free(overflow);
+    }
+    g_object_unref(style);
     return ret;
 }
 
+// is_scroll_target_hint checks if a node is the body of a document, or a
+// scrollable container.
+static gboolean
+is_scroll_target_hint(WebKitDOMNode *n)
+{
+    return WEBKIT_DOM_IS_HTML_BODY_ELEMENT(n) ||
+        (WEBKIT_DOM_IS_ELEMENT(n) && is_scrollable(WEBKIT_DOM_ELEMENT(n)));
+}
+
+// Selects all scrollable containers, as well as the body of each document.
 GList *
-select_links(GHashTable *h, Exten *exten)
+select_scroll_targets(GHashTable *h, Exten *exten)
 {
-    if(exten->document == NULL) {
-        return NULL;
+    return select_elements(is_scroll_target_hint, exten);
+}
+
+// node_text retrieves the text a user would identify a node by.
+//
+// This is the text content of the node, or if it is empty, one of the
+// labelling attributes of the node.
+static gchar *
+node_text(WebKitDOMNode *n)
+{
+    gchar *text = webkit_dom_node_get_text_content(n);
+    if(text != NULL) {
+        g_strstrip(text);
+        if(*text != '\0') {
+            return text;
+        }
+        g_free(text);
     }
-    GList *ret = NULL;
-    GList *docs = NULL;
-    scan_documents(exten->document, &docs, exten);
-    GList *l;
-    for(l = docs; l != NULL; l = l->next) {
-        WebKitDOMHTMLCollection *coll = webkit_dom_document_get_links(l->data);
-        gulong len = webkit_dom_html_collection_get_length(coll);
-        gulong i;
-        for(i = 0; i < len; i++) {
-            WebKitDOMNode *item = webkit_dom_html_collection_item(coll, i);
-            if(!is_visible(h, item)) {
-                continue;
+    if(!WEBKIT_DOM_IS_ELEMENT(n)) {
+        return g_strdup("");
+    }
+    const char* const attrs[] = {
+        "aria-label",
+        "title",
+        "alt",
+        "value",
+        "placeholder",
+        NULL};
+    guint i;
+    for(i = 0; attrs[i] != NULL; i++) {
+        text = webkit_dom_element_get_attribute(WEBKIT_DOM_ELEMENT(n), attrs[i]);
+        if(text != NULL) {
+            g_strstrip(text);
+            if(*text != '\0') {
+                return text;
             }
-            g_object_ref(item);
-            ret = g_list_prepend(ret, item);
+            g_free(text);
         }
     }
-    g_list_free(docs);
-    return ret;
+    return g_strdup("");
+}
+
+// hint_candidate describes a visible node to be hinted.
+static void
+hint_candidate(WebKitDOMNode *n, HintCandidate *c)
+{
+    c->tag = webkit_dom_element_get_tag_name(WEBKIT_DOM_ELEMENT(n));
+    c->text = node_text(n);
+    c->href = NULL;
+    if(WEBKIT_DOM_IS_HTML_ANCHOR_ELEMENT(n)) {
+        c->href = webkit_dom_html_anchor_element_get_href(
+                WEBKIT_DOM_HTML_ANCHOR_ELEMENT(n));
+    } else if(WEBKIT_DOM_IS_HTML_AREA_ELEMENT(n)) {
+        c->href = webkit_dom_html_area_element_get_href(
+                WEBKIT_DOM_HTML_AREA_ELEMENT(n));
+    } else if(WEBKIT_DOM_IS_HTML_IMAGE_ELEMENT(n)) {
+        c->href = webkit_dom_html_image_element_get_src(
+                WEBKIT_DOM_HTML_IMAGE_ELEMENT(n));
+    }
+    if(c->href == NULL) {
+        c->href = g_strdup("");
+    }
+    Rect r = {0, 0, 0, 0};
+    viewport_rect(WEBKIT_DOM_ELEMENT(n), &r);
+    c->x = r.x;
+    c->y = r.y;
+    c->width = r.width;
+    c->height = r.height;
 }
 
 gint64
@@ -440,7 +935,30 @@ start_hints_mode(NodeSelecter ns, NodeExecuter ne, Exten *exten)
         ne(nodes->data, exten);
         return len;
     }
-    gchar **hints_texts = get_hints_labels(len, exten, &err);
+    HintCandidate *candidates = g_new(HintCandidate, len);
+    GList *l;
+    guint i = 0;
+    for(l = nodes; l != NULL; l = l->next) {
+        hint_candidate(l->data, candidates + i++);
+    }
+    glong vp_width, vp_height;
+    g_object_get(webkit_dom_document_get_default_view(exten->document),
+            "inner-width", &vp_width,
+            "inner-height", &vp_height,
+            NULL);
+    gchar **hints_texts = get_hints_labels(
+            candidates,
+            len,
+            vp_width,
+            vp_height,
+            exten,
+            &err);
+    for(i = 0; i < len; i++) {
+        g_free(candidates[i].tag);
+        g_free(candidates[i].text);
+        g_free(candidates[i].href);
+    }
+    g_free(candidates);
     if(err != NULL) {
         printf("Failed to get hints texts: %s\n", err->message);
         g_error_free(err);
@@ -448,11 +966,13 @@ start_hints_mode(NodeSelecter ns, NodeExecuter ne, Exten *exten)
         return -1;
     }
     GHashTable *hints = g_hash_table_new(NULL, NULL);
-    GList *l;
-    guint i = 0;
+    i = 0;
     for(l = nodes; l != NULL; l = l->next) {
         Hint *h = g_malloc(sizeof(Hint));
-        h->text = *(hints_texts + i++);
+        h->text = *(hints_texts + i);
+        h->id = g_strdup(h->text);
+        h->active = TRUE;
+        i++;
         WebKitDOMElement *div = NULL;
         WebKitDOMText *text = NULL;
         WebKitDOMDocument *doc = webkit_dom_node_get_owner_document(l->data);
@@ -512,6 +1032,7 @@ err:
             err = NULL;
         }
         g_free(h->text);
+        g_free(h->id);
         g_free(h);
         if(div != NULL) {
             g_object_unref(div);
@@ -530,32 +1051,44 @@ err:
     return len;
 }
 
+// execute_hint_node executes the hint of the given node.
+//
+// Returns TRUE if hints mode was ended as a result.
+static gboolean
+execute_hint_node(WebKitDOMNode *n, Exten *exten)
+{
+    if(exten->hints->executer(n, exten)) {
+        filter_hints_mode("", exten);
+        return FALSE;
+    }
+    end_hints_mode(exten);
+    return TRUE;
+}
+
 gboolean
 filter_hints_mode(const gchar *hints, Exten *exten)
 {
-    gchar *hints_ci = g_utf8_casefold(hints, -1);
     if(exten->hints == NULL) {
-        return;
+        return FALSE;
     }
+    gchar *hints_ci = g_utf8_casefold(hints, -1);
     GList *nodes = g_hash_table_get_keys(exten->hints->hints);
     GList *l;
     for(l = nodes; l != NULL; l = l->next) {
         Hint *h = g_hash_table_lookup(exten->hints->hints, l->data);
+        // Hints filtered out by their text stay hidden.
+        if(!h->active) {
+            continue;
+        }
         gchar *text_ci = g_utf8_casefold(h->text, -1);
         if(g_str_has_prefix(text_ci, hints_ci)) {
             // If the hints exactly match, execute it.
             if(g_strcmp0(text_ci, hints_ci) == 0) {
-                if(exten->hints->executer(l->data, exten)) {
-                    filter_hints_mode("", exten);
-                    g_free(hints_ci);
-                    g_free(text_ci);
-                    return FALSE;
-                } else {
-                    end_hints_mode(exten);
-                    g_free(hints_ci);
-                    g_free(text_ci);
-                    return TRUE;
-                }
+                WebKitDOMNode *n = l->data;
+                g_free(hints_ci);
+                g_free(text_ci);
+                g_list_free(nodes);
+                return execute_hint_node(n, exten);
             }
             webkit_dom_element_set_class_name(h->div, "__golem-hint");
             highlight(l->data);
@@ -570,6 +1103,77 @@ filter_hints_mode(const gchar *hints, Exten *exten)
     return FALSE;
 }
 
+// Shows only the hints with the given ids, labelling them with the
+// corresponding labels.
+//
+// Returns the number of hints shown.
+gint64
+show_hints(const gchar **ids, const gchar **labels, Exten *exten)
+{
+    if(exten->hints == NULL) {
+        return 0;
+    }
+    gint64 ret = 0;
+    GList *nodes = g_hash_table_get_keys(exten->hints->hints);
+    GList *l;
+    for(l = nodes; l != NULL; l = l->next) {
+        Hint *h = g_hash_table_lookup(exten->hints->hints, l->data);
+        const gchar *label = NULL;
+        guint i;
+        for(i = 0; ids[i] != NULL && labels[i] != NULL; i++) {
+            if(g_strcmp0(ids[i], h->id) == 0) {
+                label = labels[i];
+                break;
+            }
+        }
+        h->active = label != NULL;
+        if(!h->active) {
+            webkit_dom_element_set_class_name(h->div, "__golem-hide");
+            unhighlight(l->data);
+            continue;
+        }
+        ret++;
+        GError *err = NULL;
+        g_free(h->text);
+        h->text = g_strdup(label);
+        webkit_dom_node_set_text_content(
+                WEBKIT_DOM_NODE(h->div),
+                h->text,
+                &err);
+        if(err != NULL) {
+            printf("Failed to relabel hint: %s\n", err->message);
+            g_error_free(err);
+        }
+        webkit_dom_element_set_class_name(h->div, "__golem-hint");
+        highlight(l->data);
+    }
+    g_list_free(nodes);
+    return ret;
+}
+
+// Executes the hint with the given id.
+//
+// Returns TRUE if hints mode was ended as a result.
+gboolean
+execute_hint(const gchar *id, Exten *exten)
+{
+    if(exten->hints == NULL) {
+        return FALSE;
+    }
+    GList *nodes = g_hash_table_get_keys(exten->hints->hints);
+    GList *l;
+    for(l = nodes; l != NULL; l = l->next) {
+        Hint *h = g_hash_table_lookup(exten->hints->hints, l->data);
+        if(g_strcmp0(h->id, id) == 0) {
+            WebKitDOMNode *n = l->data;
+            g_list_free(nodes);
+            return execute_hint_node(n, exten);
+        }
+    }
+    g_list_free(nodes);
+    return FALSE;
+}
+
 void
 end_hints_mode(Exten *exten)
 {
@@ -582,6 +1186,7 @@ end_hints_mode(Exten *exten)
         Hint *h = g_hash_table_lookup(exten->hints->hints, l->data);
         GError *err = NULL;
         g_free(h->text);
+        g_free(h->id);
         // remove div
         WebKitDOMNode *p = webkit_dom_node_get_parent_node(WEBKIT_DOM_NODE(h->div));
         if(p != NULL) {
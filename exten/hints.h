@@ -12,7 +12,11 @@ typedef GList *(*NodeSelecter)(GHashTable*, Exten*);
 typedef gboolean (*NodeExecuter)(WebKitDOMNode*, Exten*);
 
 typedef struct _Hint {
+    // The label currently displayed.
     gchar            *text;
+    // The label initially assigned, which identifies the hint.
+    gchar            *id;
+    gboolean          active;
     WebKitDOMElement *div;
 } Hint;
 
@@ -27,18 +31,42 @@ gboolean hint_call_by_href(WebKitDOMNode*, Exten*);
 
 gboolean hint_call_by_click(WebKitDOMNode*, Exten*);
 
+gboolean hint_call_by_text(WebKitDOMNode*, Exten*);
+
+gboolean hint_call_by_src(WebKitDOMNode*, Exten*);
+
+gboolean hint_call_by_focus(WebKitDOMNode*, Exten*);
+
+gboolean hint_call_by_hover(WebKitDOMNode*, Exten*);
+
+gboolean hint_call_by_scroll_target(WebKitDOMNode*, Exten*);
+
 GList *select_form_text_variables(GHashTable*, Exten*);
 
 GList *select_clickable(GHashTable*, Exten*);
 
 GList *select_links(GHashTable*, Exten*);
 
+GList *select_images(GHashTable*, Exten*);
+
+GList *select_inputs(GHashTable*, Exten*);
+
+GList *select_hoverable(GHashTable*, Exten*);
+
+GList *select_scroll_targets(GHashTable*, Exten*);
+
 gint64
 start_hints_mode(NodeSelecter ns, NodeExecuter ne, Exten *exten);
 
 gboolean
 filter_hints_mode(const gchar *hints, Exten *exten);
 
+gint64
+show_hints(const gchar **ids, const gchar **labels, Exten *exten);
+
+gboolean
+execute_hint(const gchar *id, Exten *exten);
+
 void
 end_hints_mode(Exten *exten);
 
@@ -0,0 +1,38 @@
+#include <webkit2/webkit-web-extension.h>
+#include <glib.h>
+#include "page.h"
+
+gchar *
+get_page_html(Exten *exten)
+{
+    if(exten->document == NULL) {
+        return g_strdup("");
+    }
+    WebKitDOMElement *root = webkit_dom_document_get_document_element(
+            exten->document);
+    if(root == NULL) {
+        return g_strdup("");
+    }
+    gchar *ret = webkit_dom_element_get_outer_html(root);
+    if(ret == NULL) {
+        return g_strdup("");
+    }
+    return ret;
+}
+
+gchar *
+get_page_text(Exten *exten)
+{
+    if(exten->document == NULL) {
+        return g_strdup("");
+    }
+    WebKitDOMHTMLElement *body = webkit_dom_document_get_body(exten->document);
+    if(body == NULL) {
+        return g_strdup("");
+    }
+    gchar *ret = webkit_dom_html_element_get_inner_text(body);
+    if(ret == NULL) {
+        return g_strdup("");
+    }
+    return ret;
+}
@@ -0,0 +1,20 @@
+#ifndef GOLEM_PAGE_H
+#define GOLEM_PAGE_H
+
+#include <glib.h>
+#include "libgolem.h"
+
+// get_page_html retrieves the HTML source of the main document, as currently
+// displayed.
+//
+// The string is transferred to the caller and must be freed.
+gchar *
+get_page_html(Exten *exten);
+
+// get_page_text retrieves the rendered text of the main document.
+//
+// The string is transferred to the caller and must be freed.
+gchar *
+get_page_text(Exten *exten);
+
+#endif /* GOLEM_PAGE_H */
@@ -9,6 +9,8 @@ extern "C" {
 #include <webkit2/webkit-web-extension.h>
 #include "rpc.h"
 #include "hints.h"
+#include "caret.h"
+#include "page.h"
 #include "libgolem.h"
 }
 
@@ -186,6 +188,36 @@ try {
                         select_clickable,
                         hint_call_by_click,
                         exten)));
+    } else if(method == "GolemWebExtension.LinkTextHintsMode") {
+        req.result((long)main_context_call<gint64>(std::bind(
+                        start_hints_mode,
+                        select_links,
+                        hint_call_by_text,
+                        exten)));
+    } else if(method == "GolemWebExtension.ImageHintsMode") {
+        req.result((long)main_context_call<gint64>(std::bind(
+                        start_hints_mode,
+                        select_images,
+                        hint_call_by_src,
+                        exten)));
+    } else if(method == "GolemWebExtension.InputHintsMode") {
+        req.result((long)main_context_call<gint64>(std::bind(
+                        start_hints_mode,
+                        select_inputs,
+                        hint_call_by_focus,
+                        exten)));
+    } else if(method == "GolemWebExtension.HoverHintsMode") {
+        req.result((long)main_context_call<gint64>(std::bind(
+                        start_hints_mode,
+                        select_hoverable,
+                        hint_call_by_hover,
+                        exten)));
+    } else if(method == "GolemWebExtension.ScrollTargetHintsMode") {
+        req.result((long)main_context_call<gint64>(std::bind(
+                        start_hints_mode,
+                        select_scroll_targets,
+                        hint_call_by_scroll_target,
+                        exten)));
     } else if(method == "GolemWebExtension.EndHintsMode") {
         main_context_call_void(std::bind(end_hints_mode, exten));
         req.result(NULL);
@@ -196,6 +228,68 @@ try {
                     filter_hints_mode,
                     params.get<0>().c_str(),
                     exten)) == TRUE);
+    } else if(method == "GolemWebExtension.ShowHints") {
+        msgpack::type::tuple<
+            std::vector<std::string>,
+            std::vector<std::string> > params;
+        req.params().convert(&params);
+        std::vector<std::string> ids = params.get<0>();
+        std::vector<std::string> labels = params.get<1>();
+        std::vector<const gchar*> cIds;
+        std::vector<const gchar*> cLabels;
+        for(size_t i = 0; i < ids.size() && i < labels.size(); i++) {
+            cIds.push_back(ids[i].c_str());
+            cLabels.push_back(labels[i].c_str());
+        }
+        cIds.push_back(NULL);
+        cLabels.push_back(NULL);
+        req.result((long)main_context_call<gint64>(std::bind(
+                        show_hints,
+                        &cIds[0],
+                        &cLabels[0],
+                        exten)));
+    } else if(method == "GolemWebExtension.ExecuteHint") {
+        msgpack::type::tuple<std::string> params;
+        req.params().convert(&params);
+        req.result(main_context_call<gboolean>(std::bind(
+                    execute_hint,
+                    params.get<0>().c_str(),
+                    exten)) == TRUE);
+    } else if(method == "GolemWebExtension.StartCaret") {
+        req.result(main_context_call<gboolean>(std::bind(
+                    start_caret,
+                    exten)) == TRUE);
+    } else if(method == "GolemWebExtension.ModifySelection") {
+        msgpack::type::tuple<std::string, std::string, std::string> params;
+        req.params().convert(&params);
+        req.result(main_context_call<gboolean>(std::bind(
+                    modify_selection,
+                    params.get<0>().c_str(),
+                    params.get<1>().c_str(),
+                    params.get<2>().c_str(),
+                    exten)) == TRUE);
+    } else if(method == "GolemWebExtension.GetSelection") {
+        gchar *sel = main_context_call<gchar*>(std::bind(
+                    get_selection,
+                    exten));
+        std::string ret(sel);
+        g_free(sel);
+        req.result(ret);
+    } else if(method == "GolemWebExtension.GetPageHTML" ||
+            method == "GolemWebExtension.GetPageText") {
+        gchar *page = main_context_call<gchar*>(std::bind(
+                    method == "GolemWebExtension.GetPageHTML" ?
+                        get_page_html : get_page_text,
+                    exten));
+        std::string ret(page);
+        g_free(page);
+        req.result(ret);
+    } else if(method == "GolemWebExtension.CollapseSelection") {
+        main_context_call_void(std::bind(collapse_selection, exten));
+        req.result(NULL);
+    } else if(method == "GolemWebExtension.EndCaret") {
+        main_context_call_void(std::bind(end_caret, exten));
+        req.result(NULL);
     } else if(method == "GolemWebExtension.GetScrollTop" ||
             method == "GolemWebExtension.GetScrollLeft" ||
             method == "GolemWebExtension.GetScrollHeight" ||
@@ -263,14 +357,51 @@ try {
     return;
 }
 
-// get_hints_labels gets the labels for n hints.
+// get_hints_labels gets the labels for n hint candidates, given the size of
+// the top level viewport.
 gchar **
-get_hints_labels(guint n, Exten *exten, GError **err)
+get_hints_labels(
+        const HintCandidate *candidates,
+        guint n,
+        gdouble vp_width,
+        gdouble vp_height,
+        Exten *exten,
+        GError **err)
 {
     try {
+        typedef msgpack::type::tuple<
+            std::string,
+            std::string,
+            std::string,
+            double,
+            double,
+            double,
+            double> candidate;
+        std::vector<candidate> cs;
+        guint i;
+        for(i = 0; i < n; i++) {
+            const HintCandidate *c = candidates + i;
+            cs.push_back(candidate(
+                        std::string(c->tag),
+                        std::string(c->text),
+                        std::string(c->href),
+                        c->x,
+                        c->y,
+                        c->width,
+                        c->height));
+        }
+        msgpack::type::tuple<
+            unsigned long,
+            double,
+            double,
+            std::vector<candidate> > args(
+                    (unsigned long)exten->page_id,
+                    vp_width,
+                    vp_height,
+                    cs);
         std::vector<std::string> ret = exten->rpc_session->client->call(
                 "Golem.GetHintsLabels",
-                (long)n).get<std::vector<std::string> >();
+                args).get<std::vector<std::string> >();
         gchar **cret = (gchar**)g_malloc(sizeof(gchar*) * (ret.size() + 1));
         cret[ret.size()] = NULL;
         for(int i = 0; i < ret.size(); i++) {
@@ -471,7 +602,12 @@ rpc_acquire(Exten *exten, GCallback cb, gpointer user_data)
             // DO SHIT.
         }
     }
-    handshake(socks[0], "msgpack-rpc-client", &err);
+    // The client connection names its page, and may only act on it.
+    gchar *client_header = g_strdup_printf(
+            "msgpack-rpc-extension:%lu",
+            (unsigned long)exten->page_id);
+    handshake(socks[0], client_header, &err);
+    g_free(client_header);
     if(err) {
         // DO SHIT.
     }
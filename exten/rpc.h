@@ -20,9 +20,28 @@ typedef struct _RPCSession {
 #endif
 } RPCSession;
 
-// get_hints_labels gets the labels for n hints.
+// A HintCandidate describes an element to be hinted. Its position is relative
+// to the top level viewport.
+typedef struct _HintCandidate {
+    gchar   *tag;
+    gchar   *text;
+    gchar   *href;
+    gdouble  x;
+    gdouble  y;
+    gdouble  width;
+    gdouble  height;
+} HintCandidate;
+
+// get_hints_labels gets the labels for n hint candidates, given the size of
+// the top level viewport.
 gchar **
-get_hints_labels(guint n, Exten *exten, GError **err);
+get_hints_labels(
+        const HintCandidate *candidates,
+        guint n,
+        gdouble vp_width,
+        gdouble vp_height,
+        Exten *exten,
+        GError **err);
 
 // hint_call calls a hint with the given string.
 gboolean
@@ -0,0 +1,412 @@
+package golem
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/tkerber/golem/api"
+	ggtk "github.com/tkerber/golem/gtk"
+	"github.com/ugorji/go/codec"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber.
+// Further events are dropped until the subscriber catches up.
+const eventBufferSize = 64
+
+// An apiSession is the exported RPC object serving golem's public API.
+//
+// See the api package for documentation of the API itself.
+type apiSession struct {
+	session *RPCSession
+}
+
+// golem retrieves the golem instance the API is served for.
+func (s *apiSession) golem() (*Golem, error) {
+	if s.session.golem == nil {
+		return nil, errors.New("Golem is not yet initialized.")
+	}
+	return s.session.golem, nil
+}
+
+// withTab runs a function in glib's main context, with the given tab as the
+// target of commands.
+//
+// The tab ID 0 refers to the current tab of the first window.
+func (s *apiSession) withTab(id uint64, f func(w *Window, wv *webView)) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	var wv *webView
+	g.wMutex.Lock()
+	if id == 0 {
+		if len(g.windows) != 0 {
+			wv = g.windows[0].getWebView()
+		}
+	} else {
+		wv = g.webViews[id]
+	}
+	g.wMutex.Unlock()
+	if wv == nil {
+		return fmt.Errorf("No such tab: %d", id)
+	}
+	w := wv.window
+	if w == nil {
+		return errors.New("Tab is not attached to any window.")
+	}
+	ggtk.GlibMainContextInvoke(func() {
+		prevTarget := w.targetWebView
+		w.targetWebView = wv
+		f(w, wv)
+		w.targetWebView = prevTarget
+	})
+	return nil
+}
+
+// Version retrieves the version of the API.
+func (s *apiSession) Version(args *api.Nothing, ret *int) error {
+	*ret = api.Version
+	return nil
+}
+
+// Windows retrieves all windows and their tabs.
+func (s *apiSession) Windows(args *api.Nothing, ret *[]api.Window) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	g.wMutex.Lock()
+	windows := append([]*Window(nil), g.windows...)
+	g.wMutex.Unlock()
+	ggtk.GlibMainContextInvoke(func() {
+		*ret = make([]api.Window, len(windows))
+		for i, w := range windows {
+			tabs := make([]api.Tab, len(w.webViews))
+			for j, wv := range w.webViews {
+				tabs[j] = api.Tab{wv.id, wv.GetURI(), wv.GetTitle()}
+			}
+			(*ret)[i] = api.Window{i, w.currentWebView, tabs}
+		}
+	})
+	return nil
+}
+
+// URI retrieves the URI of a tab.
+func (s *apiSession) URI(id uint64, ret *string) error {
+	return s.withTab(id, func(w *Window, wv *webView) {
+		*ret = wv.GetURI()
+	})
+}
+
+// SetURI loads a URI in a tab.
+func (s *apiSession) SetURI(req *api.URIRequest, ret *api.Nothing) error {
+	return s.withTab(req.TabID, func(w *Window, wv *webView) {
+		wv.LoadURI(req.URI)
+	})
+}
+
+// Open opens a URI in a new tab, and returns the tab's ID.
+func (s *apiSession) Open(req *api.OpenRequest, ret *uint64) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	// As for NewTabs, searches may be passed.
+	parts, err := shellwords.Parse(req.URI)
+	if err != nil {
+		parts = []string{req.URI}
+	}
+	uri := g.OpenURI(parts)
+	g.wMutex.Lock()
+	var w *Window
+	if len(g.windows) != 0 {
+		w = g.windows[0]
+	}
+	g.wMutex.Unlock()
+	if req.Window || w == nil {
+		w, err = g.NewWindow(uri)
+		if err != nil {
+			return err
+		}
+		*ret = w.webViews[0].id
+		return nil
+	}
+	wvs, err := w.NewTabs(uri)
+	if err != nil {
+		return err
+	}
+	*ret = wvs[0].id
+	if !req.Background {
+		return w.TabGo(w.tabIndex(wvs[0]))
+	}
+	return nil
+}
+
+// CloseTab closes a tab.
+func (s *apiSession) CloseTab(id uint64, ret *api.Nothing) error {
+	return s.withTab(id, func(w *Window, wv *webView) {
+		i := w.tabIndex(wv)
+		w.tabsClose(i, i+1, false)
+	})
+}
+
+// Reload reloads a tab.
+func (s *apiSession) Reload(id uint64, ret *api.Nothing) error {
+	return s.withTab(id, func(w *Window, wv *webView) {
+		wv.Reload()
+	})
+}
+
+// Quit closes all of golem's windows, which quits golem.
+func (s *apiSession) Quit(args *api.Nothing, ret *api.Nothing) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	ggtk.GlibMainContextInvoke(g.Close)
+	return nil
+}
+
+// RunCommand runs a command in a tab.
+//
+// Errors in the command itself are returned instead of being displayed in
+// the tab's window.
+func (s *apiSession) RunCommand(
+	req *api.CommandRequest,
+	ret *api.Nothing) error {
+
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	var cmdErr error
+	err = s.withTab(req.TabID, func(w *Window, wv *webView) {
+		cmdErr = execCmd(w, g, req.Command)
+	})
+	if err != nil {
+		return err
+	}
+	return cmdErr
+}
+
+// setting retrieves the value of a setting as seen from a tab.
+//
+// If the key has no level, as in webkit:KEY, this is the value in effect for
+// the tab, with site settings applied. Otherwise, as in webkit:global:KEY, it
+// is the value set at the given level, as seen from the tab.
+func (g *Golem) setting(w *Window, wv *webView, key string) (
+	interface{},
+	error) {
+
+	keyParts := strings.Split(key, ":")
+	var getFunc func(obj interface{}) interface{}
+	var iterChan <-chan interface{}
+	var err error
+	switch keyParts[0] {
+	case "webkit", "w":
+		_, getFunc, iterChan, _, err = cmdSetWebkit(w, g, keyParts)
+	case "golem", "g":
+		_, getFunc, iterChan, _, err = cmdSetGolem(w, g, keyParts)
+	default:
+		return nil, fmt.Errorf("Unknown setting: '%v'", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Only the getter is needed.
+	for range iterChan {
+	}
+	qualifier, name, _ := cmdSetWebkitGetKeys(keyParts)
+	var obj interface{}
+	switch keyParts[0] {
+	case "webkit", "w":
+		switch {
+		case len(keyParts) == 2:
+			obj = wv.settings
+		case qualifier == qualifierGlobal:
+			obj = g.DefaultSettings
+		default:
+			obj = wv.baseSettings
+		}
+	default:
+		if len(keyParts) == 2 {
+			qualifier = golemSettingLevel(name)
+		}
+		switch qualifier {
+		case qualifierGlobal:
+			obj = g.globalCfg
+		case qualifierWindow:
+			obj = w.windowCfg
+		default:
+			obj = wv.tabCfg
+		}
+	}
+	return getFunc(obj), nil
+}
+
+// Setting retrieves the value of a setting as seen from a tab.
+func (s *apiSession) Setting(req *api.SettingRequest, ret *string) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	var getErr error
+	err = s.withTab(req.TabID, func(w *Window, wv *webView) {
+		var val interface{}
+		val, getErr = g.setting(w, wv, req.Key)
+		if getErr == nil {
+			*ret = fmt.Sprint(val)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return getErr
+}
+
+// SetSetting sets a setting as seen from a tab.
+func (s *apiSession) SetSetting(
+	req *api.SettingRequest,
+	ret *api.Nothing) error {
+
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	var setErr error
+	err = s.withTab(req.TabID, func(w *Window, wv *webView) {
+		setErr = runCmdParts(w, g, []string{"set", req.Key + "=" + req.Value})
+	})
+	if err != nil {
+		return err
+	}
+	return setErr
+}
+
+// History retrieves the browsing history, oldest entries first.
+func (s *apiSession) History(args *api.Nothing, ret *[]api.Entry) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	g.historyMutex.Lock()
+	defer g.historyMutex.Unlock()
+	*ret = make([]api.Entry, len(g.history))
+	for i, e := range g.history {
+		(*ret)[i] = api.Entry{e.uri, e.title}
+	}
+	return nil
+}
+
+// Bookmarks retrieves the bookmarks.
+func (s *apiSession) Bookmarks(args *api.Nothing, ret *[]api.Entry) error {
+	g, err := s.golem()
+	if err != nil {
+		return err
+	}
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	*ret = make([]api.Entry, len(g.bookmarks))
+	for i, e := range g.bookmarks {
+		(*ret)[i] = api.Entry{e.uri, e.title}
+	}
+	return nil
+}
+
+// An eventHub distributes events to the subscribers of the API's event
+// stream.
+type eventHub struct {
+	subscribers map[chan api.Event]map[string]bool
+	mutex       *sync.Mutex
+}
+
+// newEventHub creates a new eventHub without subscribers.
+func newEventHub() *eventHub {
+	return &eventHub{
+		make(map[chan api.Event]map[string]bool),
+		new(sync.Mutex),
+	}
+}
+
+// subscribe subscribes to a set of event types, or all event types if none
+// are given.
+func (h *eventHub) subscribe(types []string) chan api.Event {
+	var typeSet map[string]bool
+	if len(types) != 0 {
+		typeSet = make(map[string]bool, len(types))
+		for _, t := range types {
+			typeSet[t] = true
+		}
+	}
+	c := make(chan api.Event, eventBufferSize)
+	h.mutex.Lock()
+	h.subscribers[c] = typeSet
+	h.mutex.Unlock()
+	return c
+}
+
+// unsubscribe ends a subscription, and closes its channel.
+func (h *eventHub) unsubscribe(c chan api.Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.subscribers[c]; ok {
+		delete(h.subscribers, c)
+		close(c)
+	}
+}
+
+// emit sends an event to all subscribers of its type. It never blocks.
+func (h *eventHub) emit(e api.Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for c, typeSet := range h.subscribers {
+		if typeSet != nil && !typeSet[e.Type] {
+			continue
+		}
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
+
+// serve streams the events subscribed to over a connection, following the
+// msgpack-events handshake.
+func (h *eventHub) serve(c net.Conn, reader *bufio.Reader) {
+	msgpackHandle := new(codec.MsgpackHandle)
+	var types []string
+	err := codec.NewDecoder(reader, msgpackHandle).Decode(&types)
+	if err != nil {
+		Errlog.Printf("Failed to read event subscription: %v", err)
+		c.Close()
+		return
+	}
+	events := h.subscribe(types)
+	// Nothing further is read; the subscription ends once the connection
+	// is closed.
+	go func() {
+		io.Copy(ioutil.Discard, reader)
+		h.unsubscribe(events)
+	}()
+	enc := codec.NewEncoder(c, msgpackHandle)
+	for e := range events {
+		if err := enc.Encode(e); err != nil {
+			h.unsubscribe(events)
+			break
+		}
+	}
+	c.Close()
+}
+
+// emitEvent notifies the API's event subscribers of an event in a web view.
+func (wv *webView) emitEvent(eventType string) {
+	wv.parent.events.emit(
+		api.Event{eventType, wv.id, wv.GetURI(), wv.GetTitle()})
+}
@@ -0,0 +1,144 @@
+package golem
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tkerber/golem/api"
+)
+
+// dialTest connects an API client to golem's socket.
+func dialTest(t *testing.T, path, token string) *api.Client {
+	c, err := api.Dial(path, token)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestAPIChecksVersion(t *testing.T) {
+	path := listenTest(t, newTestGolem(""))
+	c := dialTest(t, path, tokenTest(t, path))
+	v, err := c.Version()
+	if err != nil || v != api.Version {
+		t.Errorf("Version returned %d, %v, expected %d.", v, err, api.Version)
+	}
+}
+
+func TestAPIChecksToken(t *testing.T) {
+	path := listenTest(t, newTestGolem("secret"))
+	if _, err := api.Dial(path, "wrong"); err != api.ErrDenied {
+		t.Errorf("Connecting with the wrong token returned %v.", err)
+	}
+	dialTest(t, path, "secret")
+}
+
+func TestAPIBookmarks(t *testing.T) {
+	g := newTestGolem("")
+	g.bookmarks = []uriEntry{
+		{"https://golang.org/", "Go"},
+		{"https://example.com/", ""},
+	}
+	path := listenTest(t, g)
+	c := dialTest(t, path, tokenTest(t, path))
+	bookmarks, err := c.Bookmarks()
+	expected := []api.Entry{
+		{"https://golang.org/", "Go"},
+		{"https://example.com/", ""},
+	}
+	if err != nil || !reflect.DeepEqual(bookmarks, expected) {
+		t.Errorf("Bookmarks returned %v, %v, expected %v.",
+			bookmarks, err, expected)
+	}
+}
+
+func TestAPIHistory(t *testing.T) {
+	g := newTestGolem("")
+	g.history = []uriEntry{
+		{"https://example.com/a", "A"},
+		{"https://example.com/b", "B"},
+	}
+	path := listenTest(t, g)
+	c := dialTest(t, path, tokenTest(t, path))
+	history, err := c.History()
+	expected := []api.Entry{
+		{"https://example.com/a", "A"},
+		{"https://example.com/b", "B"},
+	}
+	if err != nil || !reflect.DeepEqual(history, expected) {
+		t.Errorf("History returned %v, %v, expected %v.",
+			history, err, expected)
+	}
+}
+
+func TestAPIRejectsUnknownTabs(t *testing.T) {
+	path := listenTest(t, newTestGolem("", 1))
+	c := dialTest(t, path, tokenTest(t, path))
+	if _, err := c.URI(2); err == nil {
+		t.Error("URI succeeded for a tab which doesn't exist.")
+	}
+	if err := c.RunCommand(2, "reload"); err == nil {
+		t.Error("RunCommand succeeded for a tab which doesn't exist.")
+	}
+	// Without windows, there is no current tab.
+	if _, err := c.URI(0); err == nil {
+		t.Error("URI succeeded for the current tab without windows.")
+	}
+}
+
+func TestAPIBeforeInitialization(t *testing.T) {
+	// Before golem is initialized, clients are denied even with a valid
+	// token.
+	path := listenTest(t, nil)
+	token := tokenTest(t, path)
+	if c, err := api.Dial(path, token); err != api.ErrDenied {
+		if err == nil {
+			c.Close()
+		}
+		t.Errorf("Connecting before golem was initialized returned %v.", err)
+	}
+	sub, err := api.DialEvents(path, token, "load-finished")
+	if err != api.ErrDenied {
+		if err == nil {
+			sub.Close()
+		}
+		t.Errorf("Subscribing before golem was initialized returned %v.", err)
+	}
+}
+
+func TestAPIEvents(t *testing.T) {
+	g := newTestGolem("")
+	path := listenTest(t, g)
+	sub, err := api.DialEvents(path, tokenTest(t, path), "load-finished")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer func() {
+		sub.Close()
+		for range sub.Events {
+		}
+	}()
+
+	// The subscription is registered asynchronously, so events are emitted
+	// until one arrives. Events of other types are never received.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-sub.Events:
+			expected := api.Event{"load-finished", 1, "https://a/", "A"}
+			if e != expected {
+				t.Errorf("Received event %v, expected %v.", e, expected)
+			}
+			return
+		case <-ticker.C:
+			g.events.emit(api.Event{"title-changed", 1, "https://a/", "A"})
+			g.events.emit(api.Event{"load-finished", 1, "https://a/", "A"})
+		case <-timeout:
+			t.Fatal("No event received.")
+		}
+	}
+}
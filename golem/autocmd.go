@@ -0,0 +1,73 @@
+package golem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Events which autocommands can be registered for.
+const (
+	autocmdLoadStarted  = "LoadStarted"
+	autocmdLoadFinished = "LoadFinished"
+	autocmdTabFocus     = "TabFocus"
+)
+
+// autocmdEvents is a list of all valid autocommand events.
+var autocmdEvents = []string{
+	autocmdLoadStarted,
+	autocmdLoadFinished,
+	autocmdTabFocus,
+}
+
+// An autocmd is a command which is executed whenever an event occurs in a
+// tab with a matching uri.
+type autocmd struct {
+	event   string
+	pattern *uriPattern
+	// command is the already split command, which is interpolated only once
+	// the autocommand fires.
+	command []string
+}
+
+// parseAutocmdEvents parses a comma separated list of event names.
+//
+// Event names are case insensitive.
+func parseAutocmdEvents(str string) ([]string, error) {
+	split := strings.Split(str, ",")
+	events := make([]string, len(split))
+outer:
+	for i, name := range split {
+		for _, event := range autocmdEvents {
+			if strings.EqualFold(name, event) {
+				events[i] = event
+				continue outer
+			}
+		}
+		return nil, fmt.Errorf("Unknown autocommand event: %v", name)
+	}
+	return events, nil
+}
+
+// fireAutocmds runs all autocommands for the given event which match the
+// web view's uri.
+//
+// The commands are executed in the context of the web view's window, and any
+// commands acting on the current tab act on this web view instead.
+func (wv *webView) fireAutocmds(event string) {
+	w := wv.window
+	if w == nil {
+		return
+	}
+	uri := wv.GetURI()
+	// Copy, as autocommands may register further autocommands.
+	autocmds := append([]*autocmd(nil), wv.parent.autocmds...)
+	for _, au := range autocmds {
+		if au.event != event || !au.pattern.matches(uri) {
+			continue
+		}
+		prevTarget := w.targetWebView
+		w.targetWebView = wv
+		w.logCmdError(runCmdParts(w, wv.parent, au.command))
+		w.targetWebView = prevTarget
+	}
+}
@@ -35,6 +35,19 @@ func builtinsFor(w *Window) cmd.Builtins {
 		"addSearchEngine":      {w.builtinAddSearchEngine, "Finds and adds a new search engine on the page"},
 		"backgroundEditURI":    {w.builtinBackgroundEditURI, "Edits URI and opens in a background tab"},
 		"backgroundOpen":       {w.builtinBackgroundOpen, "Opens URI in a background tab"},
+		"cmdlineBackwardWord":  {w.builtinCmdlineBackwardWord, "Moves the command line cursor back a word"},
+		"cmdlineEnd":           {w.builtinCmdlineEnd, "Moves the command line cursor to the end"},
+		"cmdlineForwardWord":   {w.builtinCmdlineForwardWord, "Moves the command line cursor forward a word"},
+		"cmdlineHome":          {w.builtinCmdlineHome, "Moves the command line cursor to the start"},
+		"cmdlineKillToEnd":     {w.builtinCmdlineKillToEnd, "Kills the command line after the cursor"},
+		"cmdlineKillToStart":   {w.builtinCmdlineKillToStart, "Kills the command line before the cursor"},
+		"cmdlineKillWord":      {w.builtinCmdlineKillWord, "Kills the word before the command line cursor"},
+		"cmdlineSearchHistory": {w.builtinCmdlineSearchHistory, "Searches the command line history"},
+		"cmdlineTranspose":     {w.builtinCmdlineTranspose, "Swaps the keys around the command line cursor"},
+		"cmdlineUndo":          {w.builtinCmdlineUndo, "Undoes the last command line edit"},
+		"cmdlineYank":          {w.builtinCmdlineYank, "Inserts the last killed text into the command line"},
+		"cmdlineYankPop":       {w.builtinCmdlineYankPop, "Replaces the yanked text with older killed text"},
+		"caretMode":            {w.builtinCaretMode, "Enters caret mode to select text"},
 		"commandMode":          {w.builtinCommandMode, "Enters command mode"},
 		"cutClipboard":         {w.builtinCutClipboard, "Cuts tabs to clipboard selection"},
 		"cutPrimary":           {w.builtinCutPrimary, "Cuts tabs to primary selection"},
@@ -42,15 +55,30 @@ func builtinsFor(w *Window) cmd.Builtins {
 		"goBack":               {w.builtinGoBack, "Goes back in browser history"},
 		"goForward":            {w.builtinGoForward, "Goes forward in browser history"},
 		"hintsBackground":      {w.builtinHintsBackground, "Follows a link in a background tab"},
+		"hintsDownload":        {w.builtinHintsDownload, "Downloads the target of a link"},
+		"hintsFocusInput":      {w.builtinHintsFocusInput, "Focuses an input field"},
 		"hintsFollow":          {w.builtinHintsFollow, "Clicks something"},
+		"hintsHover":           {w.builtinHintsHover, "Moves the mouse over something"},
+		"hintsImage":           {w.builtinHintsImage, "Opens the source of an image"},
 		"hintsRapid":           {w.builtinHintsRapid, "Follows several links in background tabs"},
+		"hintsScrollTarget":    {w.builtinHintsScrollTarget, "Selects a container to scroll"},
 		"hintsTab":             {w.builtinHintsTab, "Follows a link in a new tab"},
+		"hintsTextFilter":      {w.builtinHintsTextFilter, "Toggles filtering hints by their text"},
 		"hintsWindow":          {w.builtinHintsWindow, "Follows a link in a new window"},
+		"hintsYankText":        {w.builtinHintsYankText, "Yanks the text of a link"},
+		"hintsYankURI":         {w.builtinHintsYankURI, "Yanks the uri of a link"},
 		"insertMode":           {w.builtinInsertMode, "Enters intert mode"},
+		"macroRecord":          {w.builtinMacroRecord, "Starts or stops recording a macro"},
+		"macroReplay":          {w.builtinMacroReplay, "Replays a macro"},
+		"markJump":             {w.builtinMarkJump, "Jumps to a mark"},
+		"markJumpLine":         {w.builtinMarkJumpLine, "Jumps to the line of a mark"},
+		"markSet":              {w.builtinMarkSet, "Sets a mark"},
 		"noh":                  {w.builtinNoh, "Removes all highlighting"},
+		"normalMode":           {w.builtinNormalMode, "Enters normal mode"},
 		"nop":                  {w.builtinNop, "Does nothing"},
 		"open":                 {w.builtinOpen, "Opens a new page"},
 		"panic":                {w.builtinPanic, "Crashes golem"},
+		"passThroughMode":      {w.builtinPassThroughMode, "Enters pass-through mode"},
 		"pasteClipboard":       {w.builtinPasteClipboard, "Pastes from clipboard selection into current tab"},
 		"pastePrimary":         {w.builtinPastePrimary, "Pastes from primary selection into current tab"},
 		"quickmarks":           {w.builtinQuickmarks, "Opens a quickmark"},
@@ -59,11 +87,15 @@ func builtinsFor(w *Window) cmd.Builtins {
 		"quickmarksRapid":      {w.builtinQuickmarksRapid, "Opens several quickmarks in background tabs"},
 		"reload":               {w.builtinReload, "Reloads the page"},
 		"reloadNoCache":        {w.builtinReloadNoCache, "Reloads the page, ignoring the cache"},
+		"repeat":               {w.builtinRepeat, "Repeats the last binding or command"},
 		"scrollDown":           {w.builtinScrollDown, "Scrolls down"},
+		"scrollHalfPageDown":   {w.builtinScrollHalfPageDown, "Scrolls down half a page"},
+		"scrollHalfPageUp":     {w.builtinScrollHalfPageUp, "Scrolls up half a page"},
 		"scrollLeft":           {w.builtinScrollLeft, "Scrolls up"},
 		"scrollRight":          {w.builtinScrollRight, "Scrolls right"},
 		"scrollPageDown":       {w.builtinScrollPageDown, "Scrolls down a page"},
 		"scrollPageUp":         {w.builtinScrollPageUp, "Scrolls up a page"},
+		"scrollPercent":        {w.builtinScrollPercent, "Scrolls to a percentage of a page"},
 		"scrollToBottom":       {w.builtinScrollToBottom, "Scrolls to the bottom of a page"},
 		"scrollToTop":          {w.builtinScrollToTop, "Scrolls to the top of a page"},
 		"scrollUp":             {w.builtinScrollUp, "Scrolls up"},
@@ -125,6 +157,9 @@ func getWithDefault(ptr *int, def, minv, maxv int) int {
 // startHint starts a hints mode. It possibly terminates it again quickly,
 // if hints mode fails to start.
 func (w *Window) startHint(hm *states.HintsMode, c <-chan error) {
+	if w.parent.hintsTextFilter {
+		hm = hm.WithTextFilter(true)
+	}
 	w.setState(hm)
 	err, ok := <-c
 	if ok {
@@ -193,6 +228,108 @@ func (w *Window) builtinBackgroundOpen(_ *int) {
 		w.runCmd))
 }
 
+// builtinCmdlineBackwardWord moves the command line cursor back a word.
+func (w *Window) builtinCmdlineBackwardWord(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).BackwardWord)
+}
+
+// builtinCmdlineEnd moves the command line cursor to the end.
+func (w *Window) builtinCmdlineEnd(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).EndOfLine)
+}
+
+// builtinCmdlineForwardWord moves the command line cursor forward a word.
+func (w *Window) builtinCmdlineForwardWord(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).ForwardWord)
+}
+
+// builtinCmdlineHome moves the command line cursor to the start.
+func (w *Window) builtinCmdlineHome(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).BeginningOfLine)
+}
+
+// builtinCmdlineKillToEnd kills the command line after the cursor.
+func (w *Window) builtinCmdlineKillToEnd(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).KillToEnd)
+}
+
+// builtinCmdlineKillToStart kills the command line before the cursor.
+func (w *Window) builtinCmdlineKillToStart(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).KillToStart)
+}
+
+// builtinCmdlineKillWord kills the word before the command line cursor.
+func (w *Window) builtinCmdlineKillWord(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).KillWord)
+}
+
+// builtinCmdlineSearchHistory starts a search through the command line
+// history, or searches for the next match if one is already running.
+func (w *Window) builtinCmdlineSearchHistory(_ *int) {
+	switch s := w.State.(type) {
+	case *cmd.CommandLineMode:
+		w.setState(cmd.NewHistorySearchMode(s))
+	case *cmd.HistorySearchMode:
+		w.setState(s.Next())
+	}
+}
+
+// builtinCmdlineTranspose swaps the keys around the command line cursor.
+func (w *Window) builtinCmdlineTranspose(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).Transpose)
+}
+
+// builtinCmdlineUndo undoes the last command line edit.
+func (w *Window) builtinCmdlineUndo(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).Undo)
+}
+
+// builtinCmdlineYank inserts the last killed text into the command line.
+func (w *Window) builtinCmdlineYank(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).Yank)
+}
+
+// builtinCmdlineYankPop replaces the yanked text with older killed text.
+func (w *Window) builtinCmdlineYankPop(_ *int) {
+	w.editCommandLine((*cmd.CommandLineMode).YankPop)
+}
+
+// editCommandLine applies an edit to the command line, if it is currently
+// active. A running history search is accepted before the edit is applied.
+func (w *Window) editCommandLine(
+	edit func(*cmd.CommandLineMode) *cmd.CommandLineMode) {
+
+	switch s := w.State.(type) {
+	case *cmd.CommandLineMode:
+		w.setState(edit(s))
+	case *cmd.HistorySearchMode:
+		w.setState(edit(s.Accept()))
+	}
+}
+
+// builtinCaretMode enters caret mode, from which text can be selected and
+// yanked to the clipboard selection.
+func (w *Window) builtinCaretMode(_ *int) {
+	wv := w.getWebView()
+	go func() {
+		cm, c := states.NewCaretMode(
+			w.State,
+			wv,
+			func(str string) {
+				w.yankTextTo(gdk.SELECTION_CLIPBOARD, str)
+			})
+		w.setState(cm)
+		if err, ok := <-c; ok {
+			// As with hints, don't overwrite any other state.
+			if _, ok := w.State.(states.CaretState); !ok {
+				return
+			}
+			w.setState(cmd.NewNormalMode(cm))
+			w.logError(err.Error())
+		}
+	}()
+}
+
 // builtinCommandMode initiates command mode.
 func (w *Window) builtinCommandMode(_ *int) {
 	w.setState(cmd.NewCommandLineMode(
@@ -267,6 +404,38 @@ func (w *Window) builtinHintsBackground(_ *int) {
 	}()
 }
 
+// builtinHintsDownload enters hints mode to download the target of a link.
+func (w *Window) builtinHintsDownload(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateDownload,
+			w.getWebView(),
+			func(uri string) bool {
+				ggtk.GlibMainContextInvoke(func() {
+					webkit.GetDefaultWebContext().DownloadURI(uri)
+				})
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
+// builtinHintsFocusInput enters hints mode to focus an input field.
+func (w *Window) builtinHintsFocusInput(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateFocusInput,
+			w.getWebView(),
+			func(uri string) bool {
+				w.logErrorf("Hints callback on callbackless hint type.")
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
 // builtinHintsFollow enters hints mode click something.
 func (w *Window) builtinHintsFollow(_ *int) {
 	go func() {
@@ -282,6 +451,37 @@ func (w *Window) builtinHintsFollow(_ *int) {
 	}()
 }
 
+// builtinHintsHover enters hints mode to move the mouse over something.
+func (w *Window) builtinHintsHover(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateHover,
+			w.getWebView(),
+			func(uri string) bool {
+				w.logErrorf("Hints callback on callbackless hint type.")
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
+// builtinHintsImage enters hints mode to open the source of an image.
+func (w *Window) builtinHintsImage(_ *int) {
+	wv := w.getWebView()
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateImage,
+			wv,
+			func(uri string) bool {
+				ggtk.GlibMainContextInvoke(wv.LoadURI, uri)
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
 // builtinHintsRapid enters hints mode to follow several links in background
 // tabs.
 func (w *Window) builtinHintsRapid(_ *int) {
@@ -301,6 +501,22 @@ func (w *Window) builtinHintsRapid(_ *int) {
 	}()
 }
 
+// builtinHintsScrollTarget enters hints mode to select the container which
+// is scrolled.
+func (w *Window) builtinHintsScrollTarget(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateScrollTarget,
+			w.getWebView(),
+			func(uri string) bool {
+				w.logErrorf("Hints callback on callbackless hint type.")
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
 // builtinHintsTab enters hints mode to follow a link in a new tab.
 func (w *Window) builtinHintsTab(_ *int) {
 	go func() {
@@ -320,6 +536,17 @@ func (w *Window) builtinHintsTab(_ *int) {
 	}()
 }
 
+// builtinHintsTextFilter toggles whether the current hints are filtered by
+// the text typed.
+func (w *Window) builtinHintsTextFilter(_ *int) {
+	hm, ok := w.State.(*states.HintsMode)
+	if !ok {
+		w.logErrorf("Not in hints mode.")
+		return
+	}
+	w.setState(hm.WithTextFilter(!hm.TextFilter))
+}
+
 // builtinHintsWindow enters hints mode to follow a link in a new window.
 func (w *Window) builtinHintsWindow(_ *int) {
 	go func() {
@@ -335,25 +562,131 @@ func (w *Window) builtinHintsWindow(_ *int) {
 	}()
 }
 
+// builtinHintsYankText enters hints mode to yank the text of a link to the
+// clipboard.
+func (w *Window) builtinHintsYankText(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateYankText,
+			w.getWebView(),
+			func(text string) bool {
+				w.yankTextTo(gdk.SELECTION_CLIPBOARD, text)
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
+// builtinHintsYankURI enters hints mode to yank the uri of a link to the
+// clipboard.
+func (w *Window) builtinHintsYankURI(_ *int) {
+	go func() {
+		hm, c := states.NewHintsMode(
+			w.State,
+			states.HintsSubstateYankURI,
+			w.getWebView(),
+			func(uri string) bool {
+				w.yankTextTo(gdk.SELECTION_CLIPBOARD, uri)
+				return false
+			})
+		w.startHint(hm, c)
+	}()
+}
+
 // builtinInsertMode initiates insert mode.
 func (w *Window) builtinInsertMode(_ *int) {
 	w.setState(cmd.NewInsertMode(w.State, cmd.SubstateDefault))
 }
 
+// builtinNormalMode initiates normal mode.
+func (w *Window) builtinNormalMode(_ *int) {
+	w.setState(cmd.NewNormalMode(w.State))
+}
+
+// builtinMacroRecord starts recording a macro into a register, or stops the
+// current recording.
+func (w *Window) builtinMacroRecord(_ *int) {
+	ggtk.GlibMainContextInvoke(func() {
+		if w.recording != nil {
+			w.stopRecording()
+			return
+		}
+		w.setState(states.NewRegisterMode(
+			w.State,
+			states.RegisterSubstateRecord,
+			w.startRecording))
+	})
+}
+
+// builtinMacroReplay replays the macro in a register n times.
+func (w *Window) builtinMacroReplay(n *int) {
+	times := 1
+	if n != nil {
+		times = *n
+	}
+	ggtk.GlibMainContextInvoke(func() {
+		w.setState(states.NewRegisterMode(
+			w.State,
+			states.RegisterSubstateReplay,
+			func(register rune) {
+				w.replayMacro(register, times)
+			}))
+	})
+}
+
+// builtinMarkJump jumps to the position of a mark.
+func (w *Window) builtinMarkJump(_ *int) {
+	w.markMode(states.MarkSubstateJump, func(name rune) {
+		w.jumpToMark(name, false)
+	})
+}
+
+// builtinMarkJumpLine jumps to the vertical position of a mark.
+func (w *Window) builtinMarkJumpLine(_ *int) {
+	w.markMode(states.MarkSubstateJumpLine, func(name rune) {
+		w.jumpToMark(name, true)
+	})
+}
+
+// builtinMarkSet sets a mark at the current position.
+func (w *Window) builtinMarkSet(_ *int) {
+	w.markMode(states.MarkSubstateSet, w.setMark)
+}
+
+// markMode enters mark mode, running f with the name of the mark entered.
+func (w *Window) markMode(st cmd.Substate, f func(rune)) {
+	ggtk.GlibMainContextInvoke(func() {
+		w.setState(states.NewMarkMode(w.State, st, func(name rune) {
+			// Marks require communicating with the web extension, which
+			// mustn't block the main thread.
+			go f(name)
+		}))
+	})
+}
+
 // builtinNoh removes all active highlighting from the page.
 func (w *Window) builtinNoh(_ *int) {
-	cmdNoHLSearch(w, w.parent, nil)
+	w.logCmdError(cmdNoHLSearch(w, w.parent, nil))
 }
 
 // builtinNop does nothing. It is occasionally useful as a binding.
 func (w *Window) builtinNop(_ *int) {}
 
 // builtinOpen initiates command mode, primed with an open command.
-func (w *Window) builtinOpen(_ *int) {
+//
+// With a count, the command is primed with the range of the next n tabs
+// (including the current).
+func (w *Window) builtinOpen(n *int) {
+	prefix := "open "
+	if n != nil {
+		i, j := w.numTabsToIndicies(getWithDefault(n, 1, 1, len(w.webViews)))
+		prefix = fmt.Sprintf("%d,%dopen ", i+1, j)
+	}
 	w.setState(cmd.NewPartialCommandLineMode(
 		w.State,
 		states.CommandLineSubstateCommand,
-		"open ",
+		prefix,
 		"",
 		w.runCmd))
 }
@@ -363,6 +696,13 @@ func (w *Window) builtinPanic(_ *int) {
 	panic("Builtin 'panic' called.")
 }
 
+// builtinPassThroughMode initiates pass-through mode.
+func (w *Window) builtinPassThroughMode(_ *int) {
+	w.setState(cmd.NewPassThroughMode(
+		w.State,
+		states.PassThroughSubstateManual))
+}
+
 // builtinPasteClipboard pastes uris stored in the clipboard into the current
 // tab (any more than one into new tabs).
 //
@@ -445,14 +785,25 @@ func (w *Window) builtinQuickmarksRapid(_ *int) {
 		w.State, states.NormalSubstateQuickmarksRapid))
 }
 
-// builtinReload reloads the current page.
-func (w *Window) builtinReload(_ *int) {
-	w.getWebView().Reload()
+// builtinReload reloads the next n tabs (including the current).
+func (w *Window) builtinReload(n *int) {
+	if n == nil {
+		w.getWebView().Reload()
+		return
+	}
+	i, j := w.numTabsToIndicies(getWithDefault(n, 1, 0, len(w.webViews)))
+	w.logCmdError(cmdReload(w, w.parent, []string{"reload"}, i, j))
 }
 
-// builtinReloadNoCache reloads the current page, bypassing the cache.
-func (w *Window) builtinReloadNoCache(_ *int) {
-	w.getWebView().ReloadBypassCache()
+// builtinReloadNoCache reloads the next n tabs (including the current),
+// bypassing the cache.
+func (w *Window) builtinReloadNoCache(n *int) {
+	if n == nil {
+		w.getWebView().ReloadBypassCache()
+		return
+	}
+	i, j := w.numTabsToIndicies(getWithDefault(n, 1, 0, len(w.webViews)))
+	w.logCmdError(cmdReload(w, w.parent, []string{"reload!"}, i, j))
 }
 
 // builtinScrollDown scrolls down.
@@ -461,6 +812,16 @@ func (w *Window) builtinScrollDown(n *int) {
 	w.scrollDelta(int(wv.scrollDelta)*getWithDefault(n, 1, 0, 1<<20), true)
 }
 
+// builtinScrollHalfPageDown scrolls down half a page.
+func (w *Window) builtinScrollHalfPageDown(n *int) {
+	w.scrollPages(0.5 * float64(getWithDefault(n, 1, 0, 1<<20)))
+}
+
+// builtinScrollHalfPageUp scrolls up half a page.
+func (w *Window) builtinScrollHalfPageUp(n *int) {
+	w.scrollPages(-0.5 * float64(getWithDefault(n, 1, 0, 1<<20)))
+}
+
 // builtinScrollLeft scrolls left.
 func (w *Window) builtinScrollLeft(n *int) {
 	wv := w.getWebView()
@@ -475,31 +836,42 @@ func (w *Window) builtinScrollRight(n *int) {
 
 // builtinScrollPageDown scrolls down 80% of the page.
 func (w *Window) builtinScrollPageDown(n *int) {
-	// TODO with different target areas this will scroll way too much.
-	w.scrollDelta(
-		int(float64(w.Window.WebView.GetWebView().GetAllocatedHeight())*
-			0.8*
-			float64(getWithDefault(n, 1, 0, 1<<20))),
-		true)
+	w.scrollPages(0.8 * float64(getWithDefault(n, 1, 0, 1<<20)))
 }
 
 // builtinScrollPageUp scrolls up 80% of the page.
 func (w *Window) builtinScrollPageUp(n *int) {
-	w.scrollDelta(
-		int(-float64(w.Window.WebView.GetWebView().GetAllocatedHeight())*
-			0.8*
-			float64(getWithDefault(n, 1, 0, 1<<20))),
+	w.scrollPages(-0.8 * float64(getWithDefault(n, 1, 0, 1<<20)))
+}
+
+// builtinScrollPercent scrolls to n percent of the page.
+func (w *Window) builtinScrollPercent(n *int) {
+	wv := w.getWebView()
+	height, err := wv.getScrollTargetHeight()
+	if err != nil {
+		w.logErrorf("Error scrolling: %v", err)
+		return
+	}
+	w.setJumpMark()
+	err = wv.scrollTo(
+		height*int64(getWithDefault(n, 0, 0, 100))/100,
+		true,
 		true)
+	if err != nil {
+		w.logErrorf("Error scrolling: %v", err)
+	}
 }
 
 // builtinScrollToBottom scrolls to the bottom of the page.
 func (w *Window) builtinScrollToBottom(_ *int) {
-	ext := w.getWebView()
-	height, err := ext.getScrollTargetHeight()
+	wv := w.getWebView()
+	height, err := wv.getScrollTargetHeight()
 	if err != nil {
 		w.logErrorf("Error scrolling: %v", err)
+		return
 	}
-	err = ext.setScrollTargetTop(height)
+	w.setJumpMark()
+	err = wv.scrollTo(height, true, true)
 	if err != nil {
 		w.logErrorf("Error scrolling: %v", err)
 	}
@@ -507,9 +879,10 @@ func (w *Window) builtinScrollToBottom(_ *int) {
 
 // builtinScrollTotop scrolls to the top of the page.
 func (w *Window) builtinScrollToTop(_ *int) {
-	err := w.getWebView().setScrollTargetTop(0)
+	w.setJumpMark()
+	err := w.getWebView().scrollTo(0, true, true)
 	if err != nil {
-		w.logErrorf("Error scrolling %v", err)
+		w.logErrorf("Error scrolling: %v", err)
 	}
 }
 
@@ -681,24 +1054,51 @@ func (w *Window) builtinTabPrev(n *int) {
 }
 
 // builtinToggleBookmark toggles the bookmark state of the current site.
-func (w *Window) builtinToggleBookmark(_ *int) {
-	wv := w.getWebView()
-	uri := wv.GetURI()
-	title := wv.GetTitle()
-	if _, ok := w.parent.isBookmark[uri]; ok {
+//
+// With a count, the next n tabs (including the current) are toggled
+// together: if the current site is bookmarked, the bookmarks of all of them
+// are removed, and otherwise all of them are bookmarked.
+func (w *Window) builtinToggleBookmark(n *int) {
+	i, j := w.numTabsToIndicies(getWithDefault(n, 1, 1, len(w.webViews)))
+	wvs := w.webViews[i:j]
+	if _, ok := w.parent.isBookmark[w.getWebView().GetURI()]; ok {
+		uris := make([]string, len(wvs))
+		for k, wv := range wvs {
+			uris[k] = wv.GetURI()
+		}
+		msg := "Are you sure you want to remove the bookmark for this page?"
+		if len(uris) > 1 {
+			msg = fmt.Sprintf(
+				"Are you sure you want to remove the bookmarks for %d pages?",
+				len(uris))
+		}
 		b := false
 		w.setState(cmd.NewYesNoConfirmMode(
 			w.State,
 			cmd.SubstateDefault,
-			"Are you sure you want to remove the bookmark for this page?",
+			msg,
 			&b,
 			func(b bool) {
-				if b {
-					cmdRemoveBookmark(w, w.parent, []string{"", uri})
+				if !b {
+					return
+				}
+				for _, uri := range uris {
+					if _, ok := w.parent.isBookmark[uri]; ok {
+						w.logCmdError(
+							cmdRemoveBookmark(w, w.parent, []string{"", uri}))
+					}
 				}
 			}))
 	} else {
-		cmdAddBookmark(w, w.parent, []string{"", title, uri})
+		for _, wv := range wvs {
+			uri := wv.GetURI()
+			if _, ok := w.parent.isBookmark[uri]; !ok {
+				w.logCmdError(cmdAddBookmark(
+					w,
+					w.parent,
+					[]string{"", wv.GetTitle(), uri}))
+			}
+		}
 	}
 }
 
@@ -716,7 +1116,8 @@ func (w *Window) builtinToggleQuickmark(_ *int) {
 			&b,
 			func(b bool) {
 				if b {
-					cmdRemoveQuickmark(w, w.parent, []string{"", uri})
+					w.logCmdError(
+						cmdRemoveQuickmark(w, w.parent, []string{"", uri}))
 				}
 			}))
 	} else {
@@ -849,26 +1250,27 @@ func (w *Window) builtinWindowPastePrimary(_ *int) {
 // to the clipboard.
 func (w *Window) builtinYankClipboard(n *int) {
 	i, j := w.numTabsToIndicies(getWithDefault(n, 1, 0, len(w.webViews)))
-	str := yankTabs(w.webViews[i:j])
-	ggtk.GlibMainContextInvoke(func() {
-		clip, err := gtk.ClipboardGet(gdk.SELECTION_CLIPBOARD)
-		if err != nil {
-			w.logErrorf("Failed to yank to clipboard: %v", err)
-			return
-		}
-		clip.SetText(str)
-	})
+	w.yankTabsTo(gdk.SELECTION_CLIPBOARD, i, j)
 }
 
 // builtinYankPrimary yanks the next n tabs (including the current) uris
 // to the primary selection.
 func (w *Window) builtinYankPrimary(n *int) {
 	i, j := w.numTabsToIndicies(getWithDefault(n, 1, 0, len(w.webViews)))
-	str := yankTabs(w.webViews[i:j])
+	w.yankTabsTo(gdk.SELECTION_PRIMARY, i, j)
+}
+
+// yankTabsTo yanks the uris of the tabs [i, j) to a selection.
+func (w *Window) yankTabsTo(selection gdk.Atom, i, j int) {
+	w.yankTextTo(selection, yankTabs(w.webViews[i:j]))
+}
+
+// yankTextTo places text in the given selection.
+func (w *Window) yankTextTo(selection gdk.Atom, str string) {
 	ggtk.GlibMainContextInvoke(func() {
-		clip, err := gtk.ClipboardGet(gdk.SELECTION_PRIMARY)
+		clip, err := gtk.ClipboardGet(selection)
 		if err != nil {
-			w.logErrorf("Failed to yank to primary selection: %v", err)
+			w.logErrorf("Failed to yank to selection: %v", err)
 			return
 		}
 		clip.SetText(str)
@@ -932,27 +1334,28 @@ func (w *Window) numTabsToIndicies(n int) (i, j int) {
 
 // scrollDelta scrolls a given amount of pixes either vertically or
 // horizontally.
+//
+// If a smooth scroll is in progress, the amount is scrolled on from where it
+// ends, so that repeated scrolls add up.
 func (w *Window) scrollDelta(delta int, vertical bool) {
-	var curr int64
-	var err error
 	wv := w.getWebView()
-	if vertical {
-		curr, err = wv.getScrollTargetTop()
-	} else {
-		curr, err = wv.getScrollTargetLeft()
-	}
+	curr, err := wv.scrollDestination(vertical, true)
 	if err != nil {
 		w.logErrorf("Error scrolling: %v", err)
 		return
 	}
-	curr += int64(delta)
-	if vertical {
-		err = wv.setScrollTargetTop(curr)
-	} else {
-		err = wv.setScrollTargetLeft(curr)
-	}
+	err = wv.scrollTo(curr+int64(delta), vertical, true)
 	if err != nil {
 		w.logErrorf("Error scrolling: %v", err)
 		return
 	}
 }
+
+// scrollPages scrolls vertically by a given number of pages.
+func (w *Window) scrollPages(pages float64) {
+	// TODO with different target areas this will scroll way too much.
+	w.scrollDelta(
+		int(float64(w.Window.WebView.GetWebView().GetAllocatedHeight())*
+			pages),
+		true)
+}
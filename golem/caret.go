@@ -0,0 +1,20 @@
+package golem
+
+import ggtk "github.com/tkerber/golem/gtk"
+
+// StartCaret enables caret browsing, to display the caret, and places it in
+// the page.
+func (wv *webView) StartCaret() (bool, error) {
+	ggtk.GlibMainContextInvoke(
+		wv.settings.SetBool,
+		"enable-caret-browsing",
+		true)
+	return wv.webExtension.StartCaret()
+}
+
+// EndCaret removes the caret from the page, and restores caret browsing to
+// its configured value.
+func (wv *webView) EndCaret() error {
+	ggtk.GlibMainContextInvoke(wv.applySiteSettings)
+	return wv.webExtension.EndCaret()
+}
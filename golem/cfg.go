@@ -31,16 +31,38 @@ type globalCfg struct {
 	profile      string
 	pdfjsEnabled bool
 	maxHistLen   uint
+	watchRc      bool
+	// maxCmdHistLen is the maximum length of the command and search
+	// histories.
+	maxCmdHistLen uint
+	// bindingTimeout is the time in milliseconds after which an ambiguous
+	// binding is executed, or the continuations of an incomplete one are
+	// shown.
+	bindingTimeout uint
+	// whichKey is whether the continuations of an incomplete binding are
+	// shown after bindingTimeout.
+	whichKey bool
+	// hintsTextFilter is whether hints are initially filtered by the text
+	// typed, rather than by their labels alone.
+	hintsTextFilter bool
+	// hintsChars is the alphabet of hints labels, or the name of one.
+	hintsChars string
+	// remoteToken, if set, is a token remote control clients may present
+	// when connecting to golem's socket, in addition to golem's own.
+	remoteToken string
 }
 
 // typeOf gets the reflect.Kind associated with the given setting.
 func (c *globalCfg) typeOf(cfg string) (reflect.Kind, error) {
 	switch cfg {
-	case "profile":
+	case "profile", "hints-chars", "remote-token":
 		return reflect.String, nil
-	case "pdf.js-enabled":
+	case "pdf.js-enabled", "watch-rc", "which-key", "hints-text-filter":
 		return reflect.Bool, nil
-	case "max-history-length":
+	case "max-history-length",
+		"max-command-history-length",
+		"binding-timeout":
+
 		return reflect.Uint, nil
 	default:
 		return c.windowCfg.typeOf(cfg)
@@ -54,8 +76,22 @@ func (c *globalCfg) get(cfg string) interface{} {
 		return c.profile
 	case "pdf.js-enabled":
 		return c.pdfjsEnabled
+	case "watch-rc":
+		return c.watchRc
 	case "max-history-length":
 		return c.maxHistLen
+	case "max-command-history-length":
+		return c.maxCmdHistLen
+	case "binding-timeout":
+		return c.bindingTimeout
+	case "which-key":
+		return c.whichKey
+	case "hints-text-filter":
+		return c.hintsTextFilter
+	case "hints-chars":
+		return c.hintsChars
+	case "remote-token":
+		return c.remoteToken
 	default:
 		return c.windowCfg.get(cfg)
 	}
@@ -68,8 +104,22 @@ func (c *globalCfg) set(cfg string, v interface{}) {
 		c.profile = v.(string)
 	case "pdf.js-enabled":
 		c.pdfjsEnabled = v.(bool)
+	case "watch-rc":
+		c.watchRc = v.(bool)
 	case "max-history-length":
 		c.maxHistLen = v.(uint)
+	case "max-command-history-length":
+		c.maxCmdHistLen = v.(uint)
+	case "binding-timeout":
+		c.bindingTimeout = v.(uint)
+	case "which-key":
+		c.whichKey = v.(bool)
+	case "hints-text-filter":
+		c.hintsTextFilter = v.(bool)
+	case "hints-chars":
+		c.hintsChars = v.(string)
+	case "remote-token":
+		c.remoteToken = v.(string)
 	default:
 		c.windowCfg.set(cfg, v)
 	}
@@ -80,11 +130,20 @@ func (c *globalCfg) getSettings(t reflect.Kind) []string {
 	children := c.windowCfg.getSettings(t)
 	switch t {
 	case reflect.String:
-		return append(children, "profile")
+		return append(children, "profile", "hints-chars", "remote-token")
 	case reflect.Bool:
-		return append(children, "pdf.js-enabled")
+		return append(
+			children,
+			"pdf.js-enabled",
+			"watch-rc",
+			"which-key",
+			"hints-text-filter")
 	case reflect.Uint:
-		return append(children, "max-history-length")
+		return append(
+			children,
+			"max-history-length",
+			"max-command-history-length",
+			"binding-timeout")
 	default:
 		return children
 	}
@@ -149,12 +208,15 @@ func (c *windowCfg) clone() *windowCfg {
 // tabCfg contains the configuration for a single tab.
 type tabCfg struct {
 	scrollDelta uint
+	// scrollDuration is the duration of smooth scrolling, in milliseconds.
+	// Zero disables smooth scrolling.
+	scrollDuration uint
 }
 
 // typeOf gets the reflect.Kind associated with the given setting.
 func (c *tabCfg) typeOf(cfg string) (reflect.Kind, error) {
 	switch cfg {
-	case "scroll-delta":
+	case "scroll-delta", "scroll-duration":
 		return reflect.Uint, nil
 	default:
 		return reflect.Invalid, fmt.Errorf("Unknown setting: %s", cfg)
@@ -166,6 +228,8 @@ func (c *tabCfg) get(cfg string) interface{} {
 	switch cfg {
 	case "scroll-delta":
 		return c.scrollDelta
+	case "scroll-duration":
+		return c.scrollDuration
 	default:
 		panic(fmt.Sprintf("Unknown setting: %s", cfg))
 	}
@@ -176,6 +240,8 @@ func (c *tabCfg) set(cfg string, v interface{}) {
 	switch cfg {
 	case "scroll-delta":
 		c.scrollDelta = v.(uint)
+	case "scroll-duration":
+		c.scrollDuration = v.(uint)
 	default:
 		panic(fmt.Sprintf("Unknown setting: %s", cfg))
 	}
@@ -185,7 +251,7 @@ func (c *tabCfg) set(cfg string, v interface{}) {
 func (c *tabCfg) getSettings(t reflect.Kind) []string {
 	switch t {
 	case reflect.Uint:
-		return []string{"scroll-delta"}
+		return []string{"scroll-delta", "scroll-duration"}
 	default:
 		return nil
 	}
@@ -195,20 +261,36 @@ func (c *tabCfg) getSettings(t reflect.Kind) []string {
 func (c *tabCfg) clone() *tabCfg {
 	return &tabCfg{
 		c.scrollDelta,
+		c.scrollDuration,
+	}
+}
+
+// resetCfg sets all settings of a config to their values in another config.
+//
+// The profile is left as it is, as it cannot change while golem is running.
+func resetCfg(c cfg, to cfg) {
+	kinds := []reflect.Kind{reflect.Bool, reflect.String, reflect.Uint}
+	for _, kind := range kinds {
+		for _, setting := range c.getSettings(kind) {
+			if setting != "profile" {
+				c.set(setting, to.get(setting))
+			}
+		}
 	}
 }
 
 // The defaultCfg is used when golem is started, and typically overwritten
 // with rc commands.
-var defaultCfg *globalCfg
+var defaultCfg = newDefaultCfg()
 
-// init initializes the default config.
-func init() {
+// newDefaultCfg creates a config with the default values of all settings.
+func newDefaultCfg() *globalCfg {
 	_, err := Asset("srv/pdf.js/enabled")
-	defaultCfg = &globalCfg{
+	return &globalCfg{
 		&windowCfg{
 			&tabCfg{
 				40,
+				150,
 			},
 			"http://github.com/tkerber/golem",
 		},
@@ -220,5 +302,12 @@ func init() {
 		"default",
 		err == nil,
 		500,
+		false,
+		500,
+		500,
+		true,
+		false,
+		HintsChars,
+		"",
 	}
 }
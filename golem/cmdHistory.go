@@ -0,0 +1,130 @@
+package golem
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/states"
+)
+
+// A lineHistory is the history of a single kind of command line, persisted
+// to a file.
+type lineHistory struct {
+	path    string
+	entries []string
+	mutex   *sync.Mutex
+}
+
+// loadLineHistory loads a line history from its file.
+func loadLineHistory(path string) (*lineHistory, error) {
+	h := &lineHistory{path, make([]string, 0), new(sync.Mutex)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// No history to load. Nothing to do.
+	} else if err != nil {
+		return nil, err
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+	}
+	return h, nil
+}
+
+// get retrieves a copy of the history entries, oldest first.
+func (h *lineHistory) get() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ret := make([]string, len(h.entries))
+	copy(ret, h.entries)
+	return ret
+}
+
+// add adds a new entry to the history, and writes the history file.
+//
+// If the entry is already in the history, it is moved to the end. At most
+// maxLen entries are kept.
+func (h *lineHistory) add(line string, maxLen uint) {
+	if maxLen == 0 || line == "" || strings.Contains(line, "\n") {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, entry := range h.entries {
+		if entry == line {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+	h.entries = append(h.entries, line)
+	if uint(len(h.entries)) > maxLen {
+		h.entries = h.entries[uint(len(h.entries))-maxLen:]
+	}
+	err := ioutil.WriteFile(
+		h.path,
+		[]byte(strings.Join(h.entries, "\n")+"\n"),
+		0600)
+	if err != nil {
+		(*Window)(nil).logErrorf("Failed to write history file: %v", err)
+	}
+}
+
+// commandLineHistory keeps the histories of golem's command lines. Commands
+// and searches have separate histories.
+//
+// It implements cmd.History.
+type commandLineHistory struct {
+	*globalCfg
+	commands *lineHistory
+	searches *lineHistory
+}
+
+// loadCommandLineHistory loads the command and search histories.
+func (g *Golem) loadCommandLineHistory() (*commandLineHistory, error) {
+	commands, err := loadLineHistory(g.files.cmdHistfile)
+	if err != nil {
+		return nil, err
+	}
+	searches, err := loadLineHistory(g.files.searchHistfile)
+	if err != nil {
+		return nil, err
+	}
+	return &commandLineHistory{g.globalCfg, commands, searches}, nil
+}
+
+// forSubstate retrieves the history used for a command line substate, or nil
+// if the substate doesn't keep a history.
+func (h *commandLineHistory) forSubstate(st cmd.Substate) *lineHistory {
+	switch st {
+	case states.CommandLineSubstateCommand:
+		return h.commands
+	case states.CommandLineSubstateSearch,
+		states.CommandLineSubstateBackSearch:
+		return h.searches
+	default:
+		return nil
+	}
+}
+
+// Entries retrieves the history of a command line substate, oldest first.
+func (h *commandLineHistory) Entries(st cmd.Substate) []string {
+	lh := h.forSubstate(st)
+	if lh == nil {
+		return nil
+	}
+	return lh.get()
+}
+
+// Add adds an accepted command line to the history of its substate.
+func (h *commandLineHistory) Add(st cmd.Substate, line string) {
+	lh := h.forSubstate(st)
+	if lh == nil {
+		return
+	}
+	lh.add(line, h.maxCmdHistLen)
+}
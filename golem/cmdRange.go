@@ -0,0 +1,108 @@
+package golem
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tabAddressPattern matches a single tab address in a command range.
+//
+// An address is a tab number (starting at 1), '.' for the current tab or '$'
+// for the last tab, optionally followed by an offset, or just an offset from
+// the current tab.
+const tabAddressPattern = `(?:(?:\d+|[.$])(?:[+-]\d+)?|[+-]\d+)`
+
+// cmdRangeRegex matches the range of tabs at the start of a command, e.g.
+// "3,5" in "3,5tabclose". '%' selects all tabs.
+var cmdRangeRegex = regexp.MustCompile(
+	`^\s*(%|(` + tabAddressPattern + `)(?:,(` + tabAddressPattern + `))?)`)
+
+// parseCmdRange splits the range of tabs off the start of a command.
+//
+// The range is returned as indicies [i, j) into the window's web views. ok
+// is false if the command has no range.
+func (w *Window) parseCmdRange(
+	command string) (i, j int, rest string, ok bool, err error) {
+
+	m := cmdRangeRegex.FindStringSubmatch(command)
+	if m == nil {
+		return 0, 0, command, false, nil
+	}
+	rest = command[len(m[0]):]
+	if w == nil {
+		return 0, 0, rest, true, errors.New(
+			"Command ranges can only be used in a window.")
+	}
+	if m[1] == "%" {
+		return 0, len(w.webViews), rest, true, nil
+	}
+	i, err = w.resolveTabAddress(m[2])
+	if err != nil {
+		return 0, 0, rest, true, err
+	}
+	end := i
+	if m[3] != "" {
+		end, err = w.resolveTabAddress(m[3])
+		if err != nil {
+			return 0, 0, rest, true, err
+		}
+	}
+	if end < i {
+		return 0, 0, rest, true, fmt.Errorf("Backwards range: %v", m[1])
+	}
+	return i, end + 1, rest, true, nil
+}
+
+// resolveTabAddress converts a tab address into an index into the window's
+// web views.
+func (w *Window) resolveTabAddress(addr string) (int, error) {
+	index := w.currentWebView
+	offset := addr
+	switch addr[0] {
+	case '.':
+		offset = addr[1:]
+	case '$':
+		index = len(w.webViews) - 1
+		offset = addr[1:]
+	case '+', '-':
+	default:
+		end := strings.IndexAny(addr, "+-")
+		if end == -1 {
+			end = len(addr)
+		}
+		n, err := strconv.Atoi(addr[:end])
+		if err != nil {
+			return 0, err
+		}
+		index = n - 1
+		offset = addr[end:]
+	}
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil {
+			return 0, err
+		}
+		index += n
+	}
+	if index < 0 || index >= len(w.webViews) {
+		return 0, fmt.Errorf("Tab out of range: %v", addr)
+	}
+	return index, nil
+}
+
+// currentTabCommand converts a command acting on a range of tabs into one
+// acting on the current tab, for use without a range.
+func currentTabCommand(
+	f func(*Window, *Golem, []string, int, int) error) func(
+	*Window, *Golem, []string) error {
+
+	return func(w *Window, g *Golem, args []string) error {
+		if w == nil {
+			return errNonGlobalCommand
+		}
+		return f(w, g, args, w.currentWebView, w.currentWebView+1)
+	}
+}
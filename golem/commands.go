@@ -1,17 +1,18 @@
 package golem
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
-	"github.com/mattn/go-shellwords"
+	"github.com/conformal/gotk3/gdk"
 	"github.com/tkerber/golem/cmd"
-	"github.com/tkerber/golem/golem/version"
+	"github.com/tkerber/golem/golem/rcstore"
+	"github.com/tkerber/golem/golem/states"
 	"github.com/tkerber/golem/webkit"
 )
 
@@ -26,8 +27,36 @@ var looksLikeURIRegex = regexp.MustCompile(`(\S+\.\S+|localhost)(:\d+)?(/.*)?`)
 
 var commandNames []string
 
+// uninterpolatedCommands are commands whose arguments are not interpolated
+// before the command is run. Besides commands which interpolate their
+// arguments later, these are the records of bookmarks, quickmarks and search
+// engines, which are written to rc files verbatim.
+var uninterpolatedCommands = map[string]bool{
+	"au":           true,
+	"autocmd":      true,
+	"alias":        true,
+	"com":          true,
+	"com!":         true,
+	"command":      true,
+	"command!":     true,
+	"b":            true,
+	"bm":           true,
+	"bookmark":     true,
+	"qm":           true,
+	"quickmark":    true,
+	"se":           true,
+	"searchengine": true,
+}
+
 // commands maps a command name to the command's function.
-var commands map[string]func(*Window, *Golem, []string)
+var commands map[string]func(*Window, *Golem, []string) error
+
+// rangeCommands maps the name of a command which accepts a range of tabs to
+// the command's function. The function acts on the tabs [i, j).
+//
+// Without a range, these commands act on the current tab.
+var rangeCommands map[string]func(
+	w *Window, g *Golem, args []string, i, j int) error
 
 // init initializes commands;
 //
@@ -35,7 +64,7 @@ var commands map[string]func(*Window, *Golem, []string)
 // are used during initialization, it is fine for them to reside in init,
 // (which is executed after constant/variabel initialization.
 func init() {
-	commands = map[string]func(*Window, *Golem, []string){
+	commands = map[string]func(*Window, *Golem, []string) error{
 		"cmdfmt":              cmdCommandFormat,
 		"ab":                  cmdAddBookmark,
 		"abm":                 cmdAddBookmark,
@@ -63,8 +92,6 @@ func init() {
 		"nohlsearch":         cmdNoHLSearch,
 		"aqm":                cmdAddQuickmark,
 		"addquickmark":       cmdAddQuickmark,
-		"o":                  cmdOpen,
-		"open":               cmdOpen,
 		"t":                  cmdTabOpen,
 		"topen":              cmdTabOpen,
 		"tabopen":            cmdTabOpen,
@@ -78,7 +105,33 @@ func init() {
 		"windowopen":         cmdWindowOpen,
 		"newwindow":          cmdWindowOpen,
 		"bind":               cmdBind,
+		"unbind":             cmdUnbind,
+		"mapclear":           cmdMapclear,
 		"set":                cmdSet,
+		"so":                 cmdSource,
+		"source":             cmdSource,
+		"reload-config":      cmdReloadConfig,
+		"let":                cmdLet,
+		"unlet":              cmdUnlet,
+		"reg":                cmdRegisters,
+		"keytest":            cmdKeytest,
+		"registers":          cmdRegisters,
+		"com":                cmdCommand,
+		"com!":               cmdCommand,
+		"command":            cmdCommand,
+		"command!":           cmdCommand,
+		"alias":              cmdAlias,
+		"delc":               cmdDelcommand,
+		"delcommand":         cmdDelcommand,
+		"passthrough":        cmdPassthrough,
+		"passthrough!":       cmdPassthroughClear,
+		"au":                 cmdAutocmd,
+		"autocmd":            cmdAutocmd,
+		"au!":                cmdAutocmdClear,
+		"autocmd!":           cmdAutocmdClear,
+		"styles":             cmdStyles,
+		"scripts-user":       cmdScriptsUser,
+		"spawn":              cmdSpawn,
 		"rmqm":               cmdRemoveQuickmark,
 		"removequickmark":    cmdRemoveQuickmark,
 		"q":                  cmdQuit,
@@ -88,84 +141,114 @@ func init() {
 		"qm":                 cmdQuickmark,
 		"quickmark":          cmdQuickmark,
 	}
+	rangeCommands = map[string]func(*Window, *Golem, []string, int, int) error{
+		"tabc":     cmdTabClose,
+		"tabclose": cmdTabClose,
+		"y":        cmdYank,
+		"yank":     cmdYank,
+		"re":       cmdReload,
+		"reload":   cmdReload,
+		"reload!":  cmdReload,
+		"o":        cmdOpen,
+		"open":     cmdOpen,
+	}
+	for name, f := range rangeCommands {
+		commands[name] = currentTabCommand(f)
+	}
 	commandNames = make([]string, 0, len(commands))
 	for c := range commands {
 		commandNames = append(commandNames, c)
 	}
 }
 
-// logInvalidArgs prints a log message indicating that the arguments given
-// where invalid.
-func (w *Window) logInvalidArgs(args []string) {
-	w.logErrorf("Invalid arguments recieved for command %v.", args[0])
+// cmdTabClose closes a range of tabs.
+func cmdTabClose(w *Window, g *Golem, args []string, i, j int) error {
+	if len(args) != 1 {
+		return invalidArgs(args)
+	}
+	w.tabsClose(i, j, false)
+	return nil
+}
+
+// cmdYank yanks the uris of a range of tabs to the clipboard, or to the
+// primary selection if the argument "primary" is given.
+func cmdYank(w *Window, g *Golem, args []string, i, j int) error {
+	selection := gdk.SELECTION_CLIPBOARD
+	switch {
+	case len(args) == 1:
+	case len(args) == 2 && args[1] == "primary":
+		selection = gdk.SELECTION_PRIMARY
+	default:
+		return invalidArgs(args)
+	}
+	w.yankTabsTo(selection, i, j)
+	return nil
 }
 
-// logNonGlobalCommand prints a log message indicating that a command should
-// not have been executed in a global context (i.e. in golem's rc)
-func logNonGlobalCommand() {
-	(*Window)(nil).logError("Non global command executed in a global context.")
+// cmdReload reloads a range of tabs. "reload!" bypasses the cache.
+func cmdReload(w *Window, g *Golem, args []string, i, j int) error {
+	if len(args) != 1 {
+		return invalidArgs(args)
+	}
+	for _, wv := range w.webViews[i:j] {
+		if args[0] == "reload!" {
+			wv.ReloadBypassCache()
+		} else {
+			wv.Reload()
+		}
+	}
+	return nil
 }
 
-// cmdFormatReplacer replaces "format" variables with the appropriate
-// constants.
-var cmdFormatReplacer = strings.NewReplacer(
-	"{golem_version}", version.Version,
-	"{golem_version_name}", version.Name,
-)
+// invalidArgs returns an error indicating that the arguments given to a
+// command where invalid.
+func invalidArgs(args []string) error {
+	return fmt.Errorf("Invalid arguments recieved for command %v.", args[0])
+}
 
-// cmdCommandFormat formats the command passed to it by replacing the following:
+// errNonGlobalCommand is returned by commands which should not have been
+// executed in a global context (i.e. in golem's rc)
+var errNonGlobalCommand = errors.New(
+	"Non global command executed in a global context.")
+
+// cmdCommandFormat runs the command passed to it.
 //
-// {golem_version} with golem's version string
-// {golem_version_name} with golem's version name
-func cmdCommandFormat(w *Window, g *Golem, args []string) {
+// As the arguments of all commands are interpolated, this is equivalent to
+// running the command directly, and only kept for compatibility.
+func cmdCommandFormat(w *Window, g *Golem, args []string) error {
 	args = args[1:]
 	if len(args) == 0 {
-		w.logErrorf("No command given.")
-		return
-	}
-	for i, arg := range args {
-		args[i] = cmdFormatReplacer.Replace(arg)
+		return errors.New("No command given.")
 	}
-	f, ok := commands[args[0]]
-	if !ok {
-		w.logErrorf("Error: Failed to run command '%v': No such command.",
-			args[0])
-	}
-	f(w, g, args)
+	return runCmdParts(w, g, args)
 }
 
 // cmdBookmark bookmarks a site for and adds it to a bookmark rc file.
-func cmdAddBookmark(w *Window, g *Golem, args []string) {
+func cmdAddBookmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 3 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	if _, ok := g.isBookmark[args[2]]; ok {
-		w.logErrorf("'%s' already bookmarked.", args[2])
-		return
+		return fmt.Errorf("'%s' already bookmarked.", args[2])
+	}
+	if err := cmdBookmark(w, g, args); err != nil {
+		return err
 	}
-	cmdBookmark(w, g, args)
 	// Append bookmark to bookmarks config file.
-	f, err := os.OpenFile(g.files.bookmarks, os.O_APPEND|os.O_WRONLY, 0600)
+	err := g.files.bookmarksStore.Append(rcstore.Bookmark{args[1], args[2]})
 	if err != nil {
-		w.logError(err.Error())
-		return
+		return fmt.Errorf("Failed to write to bookmarks file: %v", err)
 	}
-	defer f.Close()
-	fmt.Fprintf(f, "bm\t%s\t%s\n",
-		strconv.Quote(args[1]),
-		strconv.Quote(args[2]))
+	return nil
 }
 
 // cmdBookmark bookmarks a site for the session.
-func cmdBookmark(w *Window, g *Golem, args []string) {
+func cmdBookmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 3 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	if _, ok := g.isBookmark[args[2]]; ok {
-		w.logErrorf("'%s' already bookmarked.", args[2])
-		return
+		return fmt.Errorf("'%s' already bookmarked.", args[2])
 	}
 	g.wMutex.Lock()
 	g.bookmarks = append(g.bookmarks, uriEntry{args[2], args[1]})
@@ -174,17 +257,16 @@ func cmdBookmark(w *Window, g *Golem, args []string) {
 	if w != nil {
 		go w.UpdateLocation()
 	}
+	return nil
 }
 
 // cmdRemoveBookmark removes a bookmark.
-func cmdRemoveBookmark(w *Window, g *Golem, args []string) {
+func cmdRemoveBookmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 2 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	if _, ok := g.isBookmark[args[1]]; !ok {
-		w.logErrorf("Bookmark '%s' does not exist.", args[2])
-		return
+		return fmt.Errorf("Bookmark '%s' does not exist.", args[1])
 	}
 	bookmarksCp := make([]uriEntry, len(g.bookmarks), cap(g.bookmarks))
 	copy(bookmarksCp, g.bookmarks)
@@ -202,48 +284,27 @@ func cmdRemoveBookmark(w *Window, g *Golem, args []string) {
 	if w != nil {
 		go w.UpdateLocation()
 	}
-	// We also run through the bookmarks file and delete matching lines.
-	data, err := ioutil.ReadFile(g.files.searchEngines)
+	// We also remove matching records from the bookmarks file.
+	_, err := g.files.bookmarksStore.Remove(func(r rcstore.Record) bool {
+		bm, ok := r.(rcstore.Bookmark)
+		return ok && bm.URI == args[1]
+	})
 	if err != nil {
-		w.logErrorf("Failed to read bookmarks file.")
-		return
-	}
-	lines := strings.Split(string(data), "\n")
-	for i := 0; i < len(lines); i++ {
-		parts, err := shellwords.Parse(lines[i])
-		if err != nil || len(parts) != 3 {
-			continue
-		}
-		if parts[0] != "bm" && parts[0] != "bookmark" {
-			continue
-		}
-		if parts[1] == args[1] {
-			copy(lines[i:len(lines)-1], lines[i+1:])
-			lines = lines[:len(lines)-1]
-			i--
-		}
-	}
-	err = ioutil.WriteFile(
-		g.files.searchEngines,
-		[]byte(strings.Join(lines, "\n")),
-		0600)
-	if err != nil {
-		w.logErrorf("Failed to write to bookmarks file.")
+		return fmt.Errorf("Failed to write to bookmarks file: %v", err)
 	}
+	return nil
 }
 
 // cmdAddSearchEngine adds a new search engine.
-func cmdAddSearchEngine(w *Window, g *Golem, args []string) {
+func cmdAddSearchEngine(w *Window, g *Golem, args []string) error {
 	if len(args) != 4 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	sanitizedKeys := cmd.KeysString(cmd.ParseKeys(args[1]))
 	if se, ok := g.searchEngines.searchEngines[sanitizedKeys]; ok {
 		if w == nil {
-			w.logErrorf("Attempted interactive search engine replace in " +
-				"non-interactive context. Dropping.")
-			return
+			return errors.New("Attempted interactive search engine replace " +
+				"in non-interactive context. Dropping.")
 		}
 		b := false
 		w.setState(cmd.NewYesNoConfirmMode(
@@ -257,105 +318,82 @@ func cmdAddSearchEngine(w *Window, g *Golem, args []string) {
 			&b,
 			func(b bool) {
 				if b {
-					cmdRemoveSearchEngine(w, g, []string{"", args[1]})
-					cmdAddSearchEngine(w, g, args)
+					err := cmdRemoveSearchEngine(w, g, []string{"", args[1]})
+					if err == nil {
+						err = cmdAddSearchEngine(w, g, args)
+					}
+					w.logCmdError(err)
 				}
 			}))
-		return
+		return nil
 	}
 	// Add search engine to current session
-	cmdSearchEngine(w, g, args)
+	if err := cmdSearchEngine(w, g, args); err != nil {
+		return err
+	}
 	// Append search engine to searchengine config file.
-	f, err := os.OpenFile(g.files.searchEngines, os.O_APPEND|os.O_WRONLY, 0600)
+	err := g.files.searchEnginesStore.Append(
+		rcstore.SearchEngine{sanitizedKeys, args[2], args[3]})
 	if err != nil {
-		w.logError(err.Error())
-		return
+		return fmt.Errorf("Failed to write to searchengines file: %v", err)
 	}
-	defer f.Close()
-	fmt.Fprintf(f, "se\t%s\t%s\t%s\n",
-		strconv.Quote(sanitizedKeys),
-		strconv.Quote(args[2]),
-		strconv.Quote(args[3]))
+	return nil
 }
 
 // cmdDefaultSearchEngine sets the default search engine.
-func cmdDefaultSearchEngine(w *Window, g *Golem, args []string) {
+func cmdDefaultSearchEngine(w *Window, g *Golem, args []string) error {
 	if len(args) != 2 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	if se, ok := g.searchEngines.searchEngines[args[1]]; ok {
 		g.searchEngines.defaultSearchEngine = se
-	} else {
-		w.logErrorf("Search engine with shorthand '%s' does not exist.",
-			args[1])
+		return nil
 	}
+	return fmt.Errorf("Search engine with shorthand '%s' does not exist.",
+		args[1])
 }
 
 // cmdRemoveSearchEngine removes a search engine. It will refuse to remove
 // the default search engine.
-func cmdRemoveSearchEngine(w *Window, g *Golem, args []string) {
+func cmdRemoveSearchEngine(w *Window, g *Golem, args []string) error {
 	if len(args) != 2 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	sanitizedKeys := cmd.KeysString(cmd.ParseKeys(args[1]))
 	if se, ok := g.searchEngines.searchEngines[sanitizedKeys]; ok {
 		if se == g.searchEngines.defaultSearchEngine {
-			w.logErrorf("Cannot remove default search engine. Use " +
+			return errors.New("Cannot remove default search engine. Use " +
 				"'defaultsearchengine SHORTHAND' to set a new default first.")
-		} else {
-			delete(g.searchEngines.searchEngines, sanitizedKeys)
 		}
+		delete(g.searchEngines.searchEngines, sanitizedKeys)
 	} else {
-		w.logErrorf("Search engine with shorthand '%s' does not exist.",
+		return fmt.Errorf("Search engine with shorthand '%s' does not exist.",
 			sanitizedKeys)
 	}
-	// We also run through the search engine file and delete matching lines.
-	data, err := ioutil.ReadFile(g.files.searchEngines)
-	if err != nil {
-		w.logErrorf("Failed to read searchengines file.")
-		return
-	}
-	lines := strings.Split(string(data), "\n")
-	for i := 0; i < len(lines); i++ {
-		parts, err := shellwords.Parse(lines[i])
-		if err != nil || len(parts) != 3 {
-			continue
-		}
-		if parts[0] != "se" && parts[0] != "searchengine" {
-			continue
-		}
-		if parts[1] == sanitizedKeys {
-			copy(lines[i:len(lines)-1], lines[i+1:])
-			lines = lines[:len(lines)-1]
-			i--
-		}
-	}
-	err = ioutil.WriteFile(
-		g.files.searchEngines,
-		[]byte(strings.Join(lines, "\n")),
-		0600)
+	// We also remove matching records from the searchengines file.
+	_, err := g.files.searchEnginesStore.Remove(func(r rcstore.Record) bool {
+		se, ok := r.(rcstore.SearchEngine)
+		return ok && cmd.KeysString(cmd.ParseKeys(se.Shorthand)) == sanitizedKeys
+	})
 	if err != nil {
-		w.logErrorf("Failed to write to searchengines file.")
+		return fmt.Errorf("Failed to write to searchengines file: %v", err)
 	}
+	return nil
 }
 
 // cmdSearchEngine registers a search engine. The arguments passed, in order
 // are: shorthand (e.g. 'g'), full name (e.g. 'Google'), format string
 // (e.g. 'http://google.com/search?q=%s'). Format string is a standard go
 // format string, which will be passed exactly one string argument.
-func cmdSearchEngine(w *Window, g *Golem, args []string) {
+func cmdSearchEngine(w *Window, g *Golem, args []string) error {
 	if len(args) != 4 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	sanitizedKeys := cmd.KeysString(cmd.ParseKeys(args[1]))
 	if se, ok := g.searchEngines.searchEngines[sanitizedKeys]; ok {
 		if w == nil {
-			w.logErrorf("Attempted interactive search engine replace in " +
-				"non-interactive context. Dropping.")
-			return
+			return errors.New("Attempted interactive search engine replace " +
+				"in non-interactive context. Dropping.")
 		}
 		b := false
 		w.setState(cmd.NewYesNoConfirmMode(
@@ -379,7 +417,7 @@ func cmdSearchEngine(w *Window, g *Golem, args []string) {
 					}
 				}
 			}))
-		return
+		return nil
 	} else {
 		se := &searchEngine{
 			args[2],
@@ -390,42 +428,41 @@ func cmdSearchEngine(w *Window, g *Golem, args []string) {
 			g.searchEngines.defaultSearchEngine = se
 		}
 	}
+	return nil
 }
 
 // cmdNoHLSearch removes all active highlighting from the page.
-func cmdNoHLSearch(w *Window, g *Golem, args []string) {
+func cmdNoHLSearch(w *Window, g *Golem, args []string) error {
 	if w == nil {
-		logNonGlobalCommand()
-		return
+		return errNonGlobalCommand
 	}
 	w.getWebView().GetFindController().SearchFinish()
+	return nil
 }
 
 // cmdBackgroundOpen opens a new tab in the background.
-func cmdBackgroundOpen(w *Window, g *Golem, args []string) {
+func cmdBackgroundOpen(w *Window, g *Golem, args []string) error {
 	if w == nil {
-		logNonGlobalCommand()
-		return
+		return errNonGlobalCommand
 	}
 	uri := g.OpenURI(args[1:])
 	_, err := w.NewTabs(uri)
 	if err != nil {
-		w.logErrorf("Failed to open new tab: %v", err)
+		return fmt.Errorf("Failed to open new tab: %v", err)
 	}
+	return nil
 }
 
 // cmdAddQuickmark adds a new quickmark and records it in the quickmarks file.
-func cmdAddQuickmark(w *Window, g *Golem, args []string) {
+func cmdAddQuickmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 4 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	sanitizedKeys := cmd.KeysString(cmd.ParseKeys(args[1]))
 	if uri, ok := g.quickmarks[sanitizedKeys]; ok {
 		if w == nil {
-			w.logErrorf("Attempted interactive quickmark replace in " +
-				"non-interactive context. Dropping.")
-			return
+			return errors.New("Attempted interactive quickmark replace " +
+				"in non-interactive context. Dropping.")
 		}
 		b := false
 		w.setState(cmd.NewYesNoConfirmMode(
@@ -439,11 +476,14 @@ func cmdAddQuickmark(w *Window, g *Golem, args []string) {
 			&b,
 			func(b bool) {
 				if b {
-					cmdRemoveQuickmark(w, g, []string{"", args[1]})
-					cmdAddQuickmark(w, g, args)
+					err := cmdRemoveQuickmark(w, g, []string{"", args[1]})
+					if err == nil {
+						err = cmdAddQuickmark(w, g, args)
+					}
+					w.logCmdError(err)
 				}
 			}))
-		return
+		return nil
 	}
 	// Add quickmark to current session
 	g.quickmark(sanitizedKeys, args[2], args[3])
@@ -451,29 +491,24 @@ func cmdAddQuickmark(w *Window, g *Golem, args []string) {
 		go w.UpdateLocation()
 	}
 	// Append quickmark to quickmarks config file.
-	f, err := os.OpenFile(g.files.quickmarks, os.O_APPEND|os.O_WRONLY, 0600)
+	err := g.files.quickmarksStore.Append(
+		rcstore.Quickmark{sanitizedKeys, args[2], args[3]})
 	if err != nil {
-		w.logError(err.Error())
-		return
+		return fmt.Errorf("Failed to write to quickmarks file: %v", err)
 	}
-	defer f.Close()
-	fmt.Fprintf(f, "qm\t%s\t%s\t%s\n",
-		strconv.Quote(sanitizedKeys),
-		strconv.Quote(args[2]),
-		strconv.Quote(args[3]))
+	return nil
 }
 
 // cmdRemoveQuickmark removes a quickmark from golem and (if found) from the
 // quickmarks file.
-func cmdRemoveQuickmark(w *Window, g *Golem, args []string) {
+func cmdRemoveQuickmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 2 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	g.wMutex.Lock()
 	// First we guess that a key sequence is given, and try to delete that.
 	keyStr := cmd.KeysString(cmd.ParseKeys(args[1]))
-	if _, ok := g.quickmarks[args[1]]; ok {
+	if _, ok := g.quickmarks[keyStr]; ok {
 		delete(g.hasQuickmark, g.quickmarks[keyStr].uri)
 		delete(g.quickmarks, keyStr)
 	} else {
@@ -489,56 +524,37 @@ func cmdRemoveQuickmark(w *Window, g *Golem, args []string) {
 		}
 		if !found {
 			g.wMutex.Unlock()
-			w.logErrorf("Failed to delete quickmark '%s': Not found.", args[1])
-			return
+			return fmt.Errorf(
+				"Failed to delete quickmark '%s': Not found.",
+				args[1])
 		}
 	}
 	g.wMutex.Unlock()
 	if w != nil {
 		go w.UpdateLocation()
 	}
-	// We also run through the quickmarks file and delete matching lines.
-	data, err := ioutil.ReadFile(g.files.quickmarks)
+	// We also remove matching records from the quickmarks file.
+	_, err := g.files.quickmarksStore.Remove(func(r rcstore.Record) bool {
+		qm, ok := r.(rcstore.Quickmark)
+		return ok && (cmd.KeysString(cmd.ParseKeys(qm.Keys)) == keyStr ||
+			qm.URI == args[1])
+	})
 	if err != nil {
-		w.logErrorf("Failed to read quickmarks file.")
-		return
-	}
-	lines := strings.Split(string(data), "\n")
-	for i := 0; i < len(lines); i++ {
-		parts, err := shellwords.Parse(lines[i])
-		if err != nil || len(parts) != 4 {
-			continue
-		}
-		if parts[0] != "qm" && parts[0] != "quickmark" {
-			continue
-		}
-		if parts[1] == keyStr || parts[3] == args[1] {
-			copy(lines[i:len(lines)-1], lines[i+1:])
-			lines = lines[:len(lines)-1]
-			i--
-		}
-	}
-	err = ioutil.WriteFile(
-		g.files.quickmarks,
-		[]byte(strings.Join(lines, "\n")),
-		0600)
-	if err != nil {
-		w.logErrorf("Failed to write to quickmarks file.")
+		return fmt.Errorf("Failed to write to quickmarks file: %v", err)
 	}
+	return nil
 }
 
 // cmdQuickmark adds a new quickmark to golem.
-func cmdQuickmark(w *Window, g *Golem, args []string) {
+func cmdQuickmark(w *Window, g *Golem, args []string) error {
 	if len(args) != 4 {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
 	}
 	sanitizedKeys := cmd.KeysString(cmd.ParseKeys(args[1]))
 	if uri, ok := g.quickmarks[sanitizedKeys]; ok {
 		if w == nil {
-			w.logErrorf("Attempted interactive quickmark replace in " +
-				"non-interactive context. Dropping.")
-			return
+			return errors.New("Attempted interactive quickmark replace " +
+				"in non-interactive context. Dropping.")
 		}
 		b := false
 		w.setState(cmd.NewYesNoConfirmMode(
@@ -552,73 +568,76 @@ func cmdQuickmark(w *Window, g *Golem, args []string) {
 			&b,
 			func(b bool) {
 				if b {
-					cmdRemoveQuickmark(w, g, []string{"", args[1]})
-					cmdQuickmark(w, g, args)
+					err := cmdRemoveQuickmark(w, g, []string{"", args[1]})
+					if err == nil {
+						err = cmdQuickmark(w, g, args)
+					}
+					w.logCmdError(err)
 				}
 			}))
-		return
+		return nil
 	}
 	g.quickmark(sanitizedKeys, args[2], args[3])
 	if w != nil {
 		go w.UpdateLocation()
 	}
+	return nil
 }
 
 // cmdQuit quit closes the active window.
-func cmdQuit(w *Window, g *Golem, _ []string) {
+func cmdQuit(w *Window, g *Golem, _ []string) error {
 	if w == nil {
-		logNonGlobalCommand()
-		return
+		return errNonGlobalCommand
 	}
 	w.Close()
+	return nil
 }
 
 // cmdQuitAll closes all of golems windows.
-func cmdQuitAll(w *Window, g *Golem, _ []string) {
+func cmdQuitAll(w *Window, g *Golem, _ []string) error {
 	g.Close()
+	return nil
 }
 
-// cmdOpen opens a uri in the current tab.
+// cmdOpen opens a uri in a range of tabs.
 //
 // cmdOpen is "smart" and guesses the uri's protocol, as well as interprets
 // searches entered.
 //
 // Searches prefixed with the name of the search engine will be run through
 // that search engine.
-func cmdOpen(w *Window, g *Golem, args []string) {
-	if w == nil {
-		logNonGlobalCommand()
-		return
-	}
+func cmdOpen(w *Window, g *Golem, args []string, i, j int) error {
 	uri := g.OpenURI(args[1:])
 	if uri == "" {
-		w.logInvalidArgs(args)
-		return
+		return invalidArgs(args)
+	}
+	for _, wv := range w.webViews[i:j] {
+		wv.LoadURI(uri)
 	}
-	w.getWebView().LoadURI(uri)
+	return nil
 }
 
 // cmdTabOpen behaves like cmdOpen, but opens the uri in a new tab. If no
 // uri is given, it opens the new tab page instead.
-func cmdTabOpen(w *Window, g *Golem, args []string) {
+func cmdTabOpen(w *Window, g *Golem, args []string) error {
 	if w == nil {
-		logNonGlobalCommand()
-		return
+		return errNonGlobalCommand
 	}
 	uri := g.OpenURI(args[1:])
 	_, err := w.NewTabs(uri)
 	if err != nil {
-		w.logErrorf("Failed to open new tab: %v", err)
-		return
+		return fmt.Errorf("Failed to open new tab: %v", err)
 	}
 	w.TabNext()
+	return nil
 }
 
 // cmdWindowOpen behaves like cmdOpen, but opens the uri in a new window. If
 // no uri is given, it opens the new tab page instead.
-func cmdWindowOpen(w *Window, g *Golem, args []string) {
+func cmdWindowOpen(w *Window, g *Golem, args []string) error {
 	uri := g.OpenURI(args[1:])
 	g.NewWindow(uri)
+	return nil
 }
 
 // OpenURI gets the uri to go to for a command of the "open" class.
@@ -642,13 +661,61 @@ func (g *Golem) OpenURI(args []string) string {
 	}
 }
 
+// cmdParseMode parses the optional "-m MODE" argument of binding commands.
+//
+// It returns the mode (normal mode by default) and the remaining arguments.
+func cmdParseMode(args []string) (cmd.Mode, []string, error) {
+	if len(args) >= 2 && args[0] == "-m" {
+		mode, err := cmd.ParseMode(args[1])
+		return mode, args[2:], err
+	}
+	return cmd.ModeNormal, args, nil
+}
+
 // cmdBind adds a binding, globally to golem.
-func cmdBind(w *Window, g *Golem, args []string) {
-	if len(args) != 3 {
-		w.logInvalidArgs(args)
-		return
+//
+// Takes the form "bind [-m MODE] KEYS BINDING". MODE is one of normal,
+// insert, command-line, hints or pass-through (see cmd.ParseMode). Bindings in modes other
+// than normal mode must be a single key, and take precedence over the mode's
+// own handling of the key.
+func cmdBind(w *Window, g *Golem, args []string) error {
+	mode, rest, err := cmdParseMode(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return invalidArgs(args)
 	}
-	g.bind(args[1], args[2])
+	return g.bind(mode, rest[0], rest[1])
+}
+
+// cmdUnbind removes a binding.
+//
+// Takes the form "unbind [-m MODE] KEYS".
+func cmdUnbind(w *Window, g *Golem, args []string) error {
+	mode, rest, err := cmdParseMode(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return invalidArgs(args)
+	}
+	return g.unbind(mode, rest[0])
+}
+
+// cmdMapclear removes all bindings of a mode.
+//
+// Takes the form "mapclear [-m MODE]".
+func cmdMapclear(w *Window, g *Golem, args []string) error {
+	mode, rest, err := cmdParseMode(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return invalidArgs(args)
+	}
+	g.mapclear(mode)
+	return nil
 }
 
 // These constants describe whether a setting should be set for all of golem,
@@ -677,10 +744,20 @@ const (
 //
 // NAMESPACE may be one of: webkit, golem or w, g as shorthand
 //
-// If NAMESPACE is webkit, QUALIFIER may be one of global, window, tab
-// or g, w, t as shorthand. By default, global is used.
+// QUALIFIER may be one of global, window, tab or g, w, t as shorthand. By
+// default, global is used. For the golem namespace, the qualifier must not be
+// narrower than the level the setting is defined at (e.g. golem:tab:profile
+// is invalid).
+//
+// Webkit settings may also be set for specific sites, with
 //
-// Currently the namespace golem doesn't accept any qualifiers.
+// site:PATTERN:webkit:KEY=VALUE
+//
+// PATTERN is matched against the uri of each tab whenever it navigates (see
+// uriPattern for the syntax). Site settings take precedence over global,
+// window and tab settings. If several site settings for the same key match,
+// the one defined last takes precedence. Only "=" may be used with site
+// settings.
 //
 // Depending on the type of setting, VALUE will be parsed differently:
 //
@@ -693,17 +770,26 @@ const (
 //
 // String expressions are not parsed and taken as-is.
 //
-// An error will be logged if parsing this fails, but execution will continue
-// normally.
-func cmdSet(w *Window, g *Golem, args []string) {
+// An error will be returned if parsing this fails, but the remaining settings
+// are still applied.
+func cmdSet(w *Window, g *Golem, args []string) error {
+	var errs errorList
 	for _, arg := range args[1:len(args)] {
 		op, keyParts, valueStr, err := cmdSetSplitOperator(arg)
 		if err != nil {
-			w.logErrorf("%v: '%v'", err, arg)
+			errs.add(fmt.Errorf("%v: '%v'", err, arg))
 			continue
 		}
 		namespace := keyParts[0]
 
+		if namespace == "site" {
+			err = cmdSetSite(g, op, keyParts, valueStr)
+			if err != nil {
+				errs.add(fmt.Errorf("%v: '%v'", err, arg))
+			}
+			continue
+		}
+
 		var setFunc func(obj interface{}, val interface{})
 		var getFunc func(obj interface{}) interface{}
 		var iterChan <-chan interface{}
@@ -714,34 +800,80 @@ func cmdSet(w *Window, g *Golem, args []string) {
 			setFunc, getFunc, iterChan, valueType, err =
 				cmdSetWebkit(w, g, keyParts)
 			if err != nil {
-				w.logErrorf("%v: '%v'", err, arg)
+				errs.add(fmt.Errorf("%v: '%v'", err, arg))
 				continue
 			}
 		case "golem", "g":
-			// TODO Not yet implemented.
-			fallthrough
+			setFunc, getFunc, iterChan, valueType, err =
+				cmdSetGolem(w, g, keyParts)
+			if err != nil {
+				errs.add(fmt.Errorf("%v: '%v'", err, arg))
+				continue
+			}
 		default:
-			w.logErrorf("Failed to parse set instruction: '%v'", arg)
+			errs.add(fmt.Errorf("Failed to parse set instruction: '%v'", arg))
 			continue
 		}
 
 		operatorFunc, err :=
 			cmdSetOperatorFunc(op, setFunc, getFunc, valueType)
 		if err != nil {
-			w.logErrorf("%v: '%v'", err, arg)
+			errs.add(fmt.Errorf("%v: '%v'", err, arg))
 			continue
 		}
 
 		// Parse value according to the type and apply.
 		value, err := cmdSetParseValueString(valueStr, valueType)
 		if err != nil {
-			w.logError(err.Error())
+			errs.add(err)
 			continue
 		}
 		for obj := range iterChan {
 			operatorFunc(obj, value)
 		}
+		if namespace == "webkit" || namespace == "w" {
+			// Site settings must continue to take precedence.
+			for _, wv := range g.webViews {
+				wv.applySiteSettings()
+			}
+		}
 	}
+	return errs.err()
+}
+
+// cmdSetSite adds a site setting from the given key parts.
+func cmdSetSite(g *Golem, op uint, keyParts []string, valueStr string) error {
+	if op != setOpSet {
+		return fmt.Errorf("Only '=' may be used with site settings")
+	}
+	// The pattern itself may contain colons, the namespace and key are
+	// always the last two parts.
+	if len(keyParts) < 4 {
+		return fmt.Errorf("Failed to parse set instruction")
+	}
+	patternStr := strings.Join(keyParts[1:len(keyParts)-2], ":")
+	namespace := keyParts[len(keyParts)-2]
+	key := keyParts[len(keyParts)-1]
+	if namespace != "webkit" && namespace != "w" {
+		return fmt.Errorf("Only webkit settings may be set per site")
+	}
+	valueType, err := webkit.GetSettingsType(key)
+	if err != nil {
+		return err
+	}
+	value, err := cmdSetParseValueString(valueStr, valueType)
+	if err != nil {
+		return err
+	}
+	pattern, err := newURIPattern(patternStr)
+	if err != nil {
+		return err
+	}
+	g.siteRules.add(pattern, key, value)
+	for _, wv := range g.webViews {
+		wv.applySiteSettings()
+	}
+	return nil
 }
 
 // cmdSetOperatorFunc combines setter and getter functions for a specifies
@@ -915,22 +1047,115 @@ func cmdSetWebkit(
 		case qualifierGlobal:
 			iterChan <- g.DefaultSettings
 			for _, wv := range g.webViews {
-				iterChan <- wv.GetSettings()
+				iterChan <- wv.baseSettings
 			}
 		case qualifierWindow:
 			for _, wv := range w.webViews {
-				iterChan <- wv.GetSettings()
+				iterChan <- wv.baseSettings
+			}
+		case qualifierTab:
+			iterChan <- w.getWebView().baseSettings
+		}
+		close(iterChan)
+	}()
+	return setFunc, getFunc, iterChan, valueType, nil
+}
+
+// cmdSetGolem retrieves getter and setter functions as well as an iterator
+// and the type of the value for specified key parts to access golem settings.
+func cmdSetGolem(
+	w *Window,
+	g *Golem,
+	keyParts []string) (
+
+	func(obj interface{}, val interface{}),
+	func(obj interface{}) interface{},
+	<-chan interface{},
+	reflect.Type,
+	error) {
+
+	qualifier, key, err := cmdSetWebkitGetKeys(keyParts)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	kind, err := g.globalCfg.typeOf(key)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var valueType reflect.Type
+	switch kind {
+	case reflect.Bool:
+		valueType = reflect.TypeOf(false)
+	case reflect.String:
+		valueType = reflect.TypeOf("")
+	case reflect.Uint:
+		valueType = reflect.TypeOf(uint(0))
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("Unsupported setting type: %v",
+			kind)
+	}
+
+	level := golemSettingLevel(key)
+	if qualifier > level {
+		return nil, nil, nil, nil, fmt.Errorf(
+			"Setting cannot be applied at this level")
+	}
+	if qualifier != qualifierGlobal && w == nil {
+		return nil, nil, nil, nil, fmt.Errorf(
+			"Attempted to set non-global setting in global context.")
+	}
+
+	setFunc := func(obj interface{}, val interface{}) {
+		obj.(cfg).set(key, val)
+	}
+	getFunc := func(obj interface{}) interface{} {
+		return obj.(cfg).get(key)
+	}
+
+	iterChan := make(chan interface{})
+	go func() {
+		switch qualifier {
+		case qualifierGlobal:
+			iterChan <- g.globalCfg
+			if level >= qualifierWindow {
+				for _, w := range g.windows {
+					iterChan <- w.windowCfg
+				}
+			}
+			if level == qualifierTab {
+				for _, wv := range g.webViews {
+					iterChan <- wv.tabCfg
+				}
+			}
+		case qualifierWindow:
+			iterChan <- w.windowCfg
+			if level == qualifierTab {
+				for _, wv := range w.webViews {
+					iterChan <- wv.tabCfg
+				}
 			}
 		case qualifierTab:
-			iterChan <- w.getWebView().GetSettings()
+			iterChan <- w.getWebView().tabCfg
 		}
 		close(iterChan)
 	}()
 	return setFunc, getFunc, iterChan, valueType, nil
 }
 
-// cmdSetWebkitGetKeys converts key parts for a webkit set operation into
-// the context level of the operation and the key to set.
+// golemSettingLevel retrieves the narrowest qualifier a golem setting can be
+// applied at.
+func golemSettingLevel(key string) uint {
+	if _, err := new(tabCfg).typeOf(key); err == nil {
+		return qualifierTab
+	} else if _, err := (&windowCfg{new(tabCfg), ""}).typeOf(key); err == nil {
+		return qualifierWindow
+	}
+	return qualifierGlobal
+}
+
+// cmdSetWebkitGetKeys converts key parts for a set operation into the
+// context level of the operation and the key to set.
 func cmdSetWebkitGetKeys(keyParts []string) (uint, string, error) {
 	var qualifier uint
 	var key string
@@ -955,3 +1180,290 @@ func cmdSetWebkitGetKeys(keyParts []string) (uint, string, error) {
 	}
 	return qualifier, key, nil
 }
+
+// cmdSource executes an rc file.
+//
+// Relative paths are taken relative to golem's config directory. If executed
+// from another rc file, errors are reported as part of that file.
+func cmdSource(w *Window, g *Golem, args []string) error {
+	if len(args) != 2 {
+		return invalidArgs(args)
+	}
+	errs, err := g.useRcFile(w, g.resolveRcPath(args[1]))
+	if err != nil {
+		return fmt.Errorf("Failed to source rc file: %v", err)
+	}
+	return errorList(errs).err()
+}
+
+// cmdReloadConfig resets golem's configuration, and re-executes all rc
+// files.
+func cmdReloadConfig(w *Window, g *Golem, args []string) error {
+	if len(args) != 1 {
+		return invalidArgs(args)
+	}
+	if atomic.LoadInt32(&g.rcDepth) != 0 {
+		return errors.New("Cannot reload the config from within an rc file.")
+	}
+	if errs := g.reloadConfig(w); len(errs) != 0 {
+		return errorList(errs)
+	}
+	if w != nil {
+		w.setState(cmd.NewStatusMode(
+			w.State,
+			states.StatusSubstateMinor,
+			"Config reloaded."))
+	}
+	return nil
+}
+
+// letNameRegex matches valid names for variables defined with let.
+var letNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// cmdLet defines a variable, which can then be referred to as {NAME} in
+// commands.
+//
+// Takes the form "let NAME = VALUE". Spaces around the "=" are optional.
+//
+// "let @R = KEYS" instead sets the macro stored in register R.
+func cmdLet(w *Window, g *Golem, args []string) error {
+	split := strings.SplitN(strings.Join(args[1:], " "), "=", 2)
+	if len(split) != 2 {
+		return invalidArgs(args)
+	}
+	name := strings.TrimSpace(split[0])
+	if register, ok := parseRegisterName(name); ok {
+		g.registers.set(register, cmd.ParseKeys(strings.TrimSpace(split[1])))
+		return nil
+	}
+	if !letNameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid variable name: '%v'", name)
+	}
+	g.wMutex.Lock()
+	g.rcVars[name] = strings.TrimSpace(split[1])
+	g.wMutex.Unlock()
+	return nil
+}
+
+// cmdUnlet removes variables defined with let, or clears registers given as
+// @R.
+func cmdUnlet(w *Window, g *Golem, args []string) error {
+	if len(args) < 2 {
+		return invalidArgs(args)
+	}
+	var errs errorList
+	for _, name := range args[1:] {
+		if register, ok := parseRegisterName(name); ok {
+			g.registers.set(register, nil)
+			continue
+		}
+		g.wMutex.Lock()
+		_, ok := g.rcVars[name]
+		delete(g.rcVars, name)
+		g.wMutex.Unlock()
+		if !ok {
+			errs.add(fmt.Errorf("No such variable: '%v'", name))
+		}
+	}
+	return errs.err()
+}
+
+// cmdRegisters displays the contents of all registers.
+func cmdRegisters(w *Window, g *Golem, args []string) error {
+	if w == nil {
+		return errNonGlobalCommand
+	}
+	msg := g.registers.String()
+	if msg == "" {
+		msg = "No registers."
+	}
+	w.setState(cmd.NewStatusMode(w.State, states.StatusSubstateMinor, msg))
+	return nil
+}
+
+// cmdKeytest enters key test mode, displaying how keys pressed are parsed.
+func cmdKeytest(w *Window, g *Golem, args []string) error {
+	if w == nil {
+		return errNonGlobalCommand
+	}
+	w.setState(cmd.NewKeyTestMode(w.State, cmd.SubstateDefault))
+	return nil
+}
+
+// cmdCommand defines a user command, or lists user commands.
+//
+// Takes the form "command NAME [NARGS] BODY". NARGS is the number of
+// arguments the command takes: a digit, '*' for any number (the default),
+// '?' for at most one or '+' for at least one. In BODY, $1 to $9 refer to
+// the arguments, $@ to all arguments and $$ to a literal $. The body is
+// interpolated when the command is run, so it may also refer to e.g. {uri},
+// {title} or {selection}.
+//
+// An existing user command is only redefined by "command!". Without a BODY,
+// the matching user commands are listed instead.
+func cmdCommand(w *Window, g *Golem, args []string) error {
+	if len(args) <= 2 {
+		if w == nil {
+			return errNonGlobalCommand
+		}
+		cs := g.sortedUserCommands()
+		if len(args) == 2 {
+			filtered := make([]*userCommand, 0, 1)
+			for _, c := range cs {
+				if strings.HasPrefix(c.name, args[1]) {
+					filtered = append(filtered, c)
+				}
+			}
+			cs = filtered
+		}
+		w.showUserCommands(cs)
+		return nil
+	}
+	name := args[1]
+	nargs := "*"
+	body := args[2:]
+	if userCommandNargsRegex.MatchString(body[0]) && len(body) > 1 {
+		nargs = body[0]
+		body = body[1:]
+	}
+	if !userCommandNameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid command name: '%v'", name)
+	}
+	if _, ok := commands[name]; ok {
+		return fmt.Errorf("Cannot redefine built-in command: %v", name)
+	}
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	if _, ok := g.userCommands[name]; ok && !strings.HasSuffix(args[0], "!") {
+		return fmt.Errorf(
+			"Command already exists: %v. Use command! to redefine it.",
+			name)
+	}
+	g.userCommands[name] = &userCommand{name, nargs, joinQuoted(body), false}
+	return nil
+}
+
+// cmdAlias defines an alias for a command.
+//
+// Takes the form "alias NAME COMMAND...". Any arguments given to the alias
+// are appended to the command. Aliases of commands accepting a range of tabs
+// accept one as well.
+func cmdAlias(w *Window, g *Golem, args []string) error {
+	if len(args) < 3 {
+		return invalidArgs(args)
+	}
+	name := args[1]
+	if !userCommandNameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid command name: '%v'", name)
+	}
+	if _, ok := commands[name]; ok {
+		return fmt.Errorf("Cannot redefine built-in command: %v", name)
+	}
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	g.userCommands[name] = &userCommand{name, "*", joinQuoted(args[2:]), true}
+	return nil
+}
+
+// cmdDelcommand deletes user commands and aliases.
+func cmdDelcommand(w *Window, g *Golem, args []string) error {
+	if len(args) < 2 {
+		return invalidArgs(args)
+	}
+	var errs errorList
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	for _, name := range args[1:] {
+		if _, ok := g.userCommands[name]; !ok {
+			errs.add(fmt.Errorf("No such user command: %v", name))
+			continue
+		}
+		delete(g.userCommands, name)
+	}
+	return errs.err()
+}
+
+// parseRegisterName parses a register name of the form @a.
+func parseRegisterName(name string) (rune, bool) {
+	r := []rune(name)
+	if len(r) != 2 || r[0] != '@' || !states.IsRegisterName(r[1]) {
+		return 0, false
+	}
+	return r[1], true
+}
+
+// cmdAutocmd registers a command to run whenever an event occurs in a tab
+// with a matching uri.
+//
+// Takes the form "autocmd EVENT[,EVENT...] PATTERN COMMAND...". EVENT is one
+// of LoadStarted, LoadFinished or TabFocus. The command is interpolated when
+// the event occurs, and commands acting on the current tab act on the tab the
+// event occurred in.
+func cmdAutocmd(w *Window, g *Golem, args []string) error {
+	if len(args) < 4 {
+		return invalidArgs(args)
+	}
+	events, err := parseAutocmdEvents(args[1])
+	if err != nil {
+		return err
+	}
+	pattern, err := newURIPattern(args[2])
+	if err != nil {
+		return fmt.Errorf("Invalid pattern '%v': %v", args[2], err)
+	}
+	if _, ok := commands[args[3]]; !ok {
+		return fmt.Errorf("No such command: '%v'", args[3])
+	}
+	for _, event := range events {
+		g.autocmds = append(g.autocmds, &autocmd{event, pattern, args[3:]})
+	}
+	return nil
+}
+
+// cmdAutocmdClear removes all autocommands, or all autocommands for the given
+// events.
+func cmdAutocmdClear(w *Window, g *Golem, args []string) error {
+	if len(args) > 2 {
+		return invalidArgs(args)
+	}
+	if len(args) == 1 {
+		g.autocmds = nil
+		return nil
+	}
+	events, err := parseAutocmdEvents(args[1])
+	if err != nil {
+		return err
+	}
+	kept := make([]*autocmd, 0, len(g.autocmds))
+outer:
+	for _, au := range g.autocmds {
+		for _, event := range events {
+			if au.event == event {
+				continue outer
+			}
+		}
+		kept = append(kept, au)
+	}
+	g.autocmds = kept
+	return nil
+}
+
+// cmdStyles lists, enables, disables or reloads user style sheets.
+//
+// See userContentCommand for details.
+func cmdStyles(w *Window, g *Golem, args []string) error {
+	g.userContentCommand(w, args, func() []*userContent {
+		return g.userContents.styles
+	})
+	return nil
+}
+
+// cmdScriptsUser lists, enables, disables or reloads user scripts.
+//
+// See userContentCommand for details.
+func cmdScriptsUser(w *Window, g *Golem, args []string) error {
+	g.userContentCommand(w, args, func() []*userContent {
+		return g.userContents.scripts
+	})
+	return nil
+}
@@ -2,6 +2,7 @@ package golem
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -96,7 +97,11 @@ func (g *Golem) completeCommandLineMode(
 	keyStr := cmd.KeysStringSelective(s.CurrentKeys[:s.CursorPos], false)
 	switch s.Substate {
 	case states.CommandLineSubstateCommand:
-		return g.completionWrapCommandLine(g.completeCommand(keyStr), s)
+		return g.completionWrapCommandLine(
+			chainCompletions(
+				g.completeCommandHistory(keyStr),
+				g.completeCommand(keyStr)),
+			s)
 	default:
 		return func() (cmd.State, string, bool) {
 			return nil, "", false
@@ -116,17 +121,7 @@ func (g *Golem) completionWrapCommandLine(
 			return nil, "", false
 		}
 		keys := cmd.ParseKeys(keyStr)
-		return &cmd.CommandLineMode{
-				s.StateIndependant,
-				s.Substate,
-				keys,
-				len(keys),
-				s.CursorHome,
-				0,
-				s.Finalizer,
-			},
-			desc,
-			true
+		return s.Edit(keys), desc, true
 	}
 }
 
@@ -185,9 +180,33 @@ func (g *Golem) completeOptionSet(
 			return "", "", false
 		}
 	}
+	golemSettings := make([]string, 0)
+	golemTypes := make([]reflect.Kind, 0)
+	for _, t := range []reflect.Kind{reflect.Bool, reflect.String, reflect.Uint} {
+		for _, setting := range g.globalCfg.getSettings(t) {
+			golemSettings = append(golemSettings, setting)
+			golemTypes = append(golemTypes, t)
+		}
+	}
+	j := -1
 	i := -1
 	return func() (string, string, bool) {
 		for {
+			j++
+			if j < len(golemSettings) {
+				setting := golemSettings[j]
+				if strings.HasPrefix("g:"+setting, parts[1]) ||
+					strings.HasPrefix("golem:"+setting, parts[1]) {
+
+					return parts[0] + " golem:" + setting,
+						fmt.Sprintf(
+							"%s\t%v\tGolem",
+							setting,
+							golemTypes[j]),
+						true
+				}
+				continue
+			}
 			i++
 			if i >= len(webkit.SettingNames) {
 				return "", "", false
@@ -246,11 +265,12 @@ func (g *Golem) completeBinding(
 			return "", "", false
 		}
 	}
+	commandNames := g.allCommandNames()
 	i := -1
 	return func() (string, string, bool) {
 		for {
 			i++
-			if i > len(commandNames)+len(builtinNames) {
+			if i >= len(commandNames)+len(builtinNames) {
 				return "", "", false
 			} else if i < len(builtinNames) {
 				if strings.HasPrefix("builtin:"+builtinNames[i], opt) ||
@@ -381,17 +401,46 @@ func stringCompleteAgainstList(
 	}
 }
 
+// completeCommandHistory completes a command from the command history, most
+// recent first.
+func (g *Golem) completeCommandHistory(
+	command string) func() (string, string, bool) {
+
+	entries := g.cmdHistory.Entries(states.CommandLineSubstateCommand)
+	i := len(entries)
+	return func() (string, string, bool) {
+		for i > 0 {
+			i--
+			if entries[i] != command && strings.HasPrefix(entries[i], command) {
+				return entries[i], entries[i] + "\tHistory", true
+			}
+		}
+		return "", "", false
+	}
+}
+
+// chainCompletions combines several completion functions, exhausting each in
+// turn.
+func chainCompletions(
+	fs ...func() (string, string, bool)) func() (string, string, bool) {
+
+	return func() (string, string, bool) {
+		for len(fs) > 0 {
+			str, desc, ok := fs[0]()
+			if ok {
+				return str, desc, true
+			}
+			fs = fs[1:]
+		}
+		return "", "", false
+	}
+}
+
 // completeCommandCommand completes the actual command of a command mode.
 func (g *Golem) completeCommandCommand(
 	cmd string) func() (string, string, bool) {
 
-	commandNames := make([]string, len(commands))
-	i := 0
-	for command := range commands {
-		commandNames[i] = command
-		i++
-	}
-	return stringCompleteAgainstList(cmd, commandNames)
+	return stringCompleteAgainstList(cmd, g.allCommandNames())
 }
 
 // completeNormalMode completes a normal mode state.
@@ -5,21 +5,32 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/tkerber/golem/golem/rcstore"
 	"github.com/tkerber/golem/xdg"
 )
 
 // files keeps track of all files golem uses.
 type files struct {
-	configDir     string
-	cacheDir      string
-	cookies       string
-	rc            string
-	searchEngines string
-	quickmarks    string
-	bookmarks     string
-	histfile      string
-	downloadDir   string
-	filterlistDir string
+	configDir      string
+	cacheDir       string
+	cookies        string
+	rc             string
+	searchEngines  string
+	quickmarks     string
+	bookmarks      string
+	histfile       string
+	cmdHistfile    string
+	searchHistfile string
+	registers      string
+	marks          string
+	downloadDir    string
+	filterlistDir  string
+	userstylesDir  string
+	userscriptsDir string
+
+	searchEnginesStore *rcstore.File
+	quickmarksStore    *rcstore.File
+	bookmarksStore     *rcstore.File
 }
 
 // configFiles is an array of all of golems config files.
@@ -51,6 +62,18 @@ func (g *Golem) newFiles() (*files, error) {
 		return nil, err
 	}
 
+	userstylesDir := filepath.Join(configDir, "userstyles")
+	err = os.MkdirAll(userstylesDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	userscriptsDir := filepath.Join(configDir, "userscripts")
+	err = os.MkdirAll(userscriptsDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
 	cacheDir := xdg.GetUserCacheDir()
 	cacheDir = filepath.Join(cacheDir, "golem", g.profile)
 	err = os.MkdirAll(cacheDir, 0700)
@@ -65,6 +88,14 @@ func (g *Golem) newFiles() (*files, error) {
 		return nil, err
 	}
 
+	stores := make([]*rcstore.File, 3)
+	for i, path := range configFiles[1:] {
+		stores[i], err = rcstore.Open(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &files{
 		configDir,
 		cacheDir,
@@ -74,8 +105,17 @@ func (g *Golem) newFiles() (*files, error) {
 		configFiles[2],
 		configFiles[3],
 		filepath.Join(configDir, "history"),
+		filepath.Join(configDir, "command-history"),
+		filepath.Join(configDir, "search-history"),
+		filepath.Join(configDir, "registers"),
+		filepath.Join(configDir, "marks"),
 		downloads,
 		filterlistDir,
+		userstylesDir,
+		userscriptsDir,
+		stores[0],
+		stores[1],
+		stores[2],
 	}, nil
 }
 
@@ -1,7 +1,6 @@
 package golem
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -59,7 +58,7 @@ type Golem struct {
 	closeChan          chan<- *Window
 	Quit               chan bool
 	wMutex             *sync.Mutex
-	rawBindings        []cmd.RawBinding
+	rawBindings        map[cmd.Mode][]cmd.RawBinding
 
 	DefaultSettings *webkit.Settings
 	files           *files
@@ -72,10 +71,36 @@ type Golem struct {
 
 	historyMutex *sync.Mutex
 	history      []uriEntry
+	cmdHistory   *commandLineHistory
+	registers    *registers
+	marks        *marks
 
 	silentDownloads map[uintptr]bool
 
 	adblocker *adblock.Blocker
+
+	// rcErrors are errors which occurred while executing the rc files at
+	// startup, and are displayed in the first window.
+	rcErrors    []error
+	reloadMutex *sync.Mutex
+	// rcDepth is the number of rc files currently being executed.
+	rcDepth int32
+
+	// rcVars are the variables defined with let.
+	rcVars   map[string]string
+	autocmds []*autocmd
+
+	siteRules *siteRules
+	// passThroughSites are the patterns of sites which enter pass-through
+	// mode automatically.
+	passThroughSites []*uriPattern
+
+	userContents *userContents
+
+	// userCommands are the commands defined with the command and alias
+	// commands.
+	userCommands     map[string]*userCommand
+	userCommandDepth int32
 }
 
 // New creates a new instance of golem.
@@ -84,26 +109,6 @@ func New(session *RPCSession, profile string) (*Golem, error) {
 	if err != nil {
 		return nil, err
 	}
-	css, err := webkit.NewUserStyleSheet(
-		scrollbarHideCSS,
-		webkit.UserContentInjectTopFrame,
-		webkit.UserStyleLevelUser,
-		nil,
-		nil)
-	if err != nil {
-		return nil, err
-	}
-	ucm.AddStyleSheet(css)
-	css, err = webkit.NewUserStyleSheet(
-		injectedHTMLCSS,
-		webkit.UserContentInjectAllFrames,
-		webkit.UserStyleLevelAuthor,
-		nil,
-		nil)
-	if err != nil {
-		return nil, err
-	}
-	ucm.AddStyleSheet(css)
 
 	closeChan := make(chan *Window)
 	quitChan := make(chan bool)
@@ -117,7 +122,7 @@ func New(session *RPCSession, profile string) (*Golem, error) {
 		closeChan,
 		quitChan,
 		new(sync.Mutex),
-		make([]cmd.RawBinding, 0, 100),
+		make(map[cmd.Mode][]cmd.RawBinding),
 		webkit.NewSettings(),
 		nil,
 		"",
@@ -127,10 +132,26 @@ func New(session *RPCSession, profile string) (*Golem, error) {
 		"",
 		new(sync.Mutex),
 		make([]uriEntry, 0, defaultCfg.maxHistLen),
+		nil,
+		nil,
+		nil,
 		make(map[uintptr]bool, 10),
 		nil,
+		nil,
+		new(sync.Mutex),
+		0,
+		make(map[string]string),
+		nil,
+		newSiteRules(),
+		nil,
+		&userContents{nil, nil, new(sync.Mutex)},
+		make(map[string]*userCommand),
+		0,
 	}
 
+	if err := session.writeToken(); err != nil {
+		return nil, fmt.Errorf("Failed to write token file: %v", err)
+	}
 	session.golem = g
 
 	g.profile = profile
@@ -143,16 +164,41 @@ func New(session *RPCSession, profile string) (*Golem, error) {
 	if err != nil {
 		return nil, err
 	}
+	g.cmdHistory, err = g.loadCommandLineHistory()
+	if err != nil {
+		return nil, err
+	}
+	g.registers, err = loadRegisters(g.files.registers)
+	if err != nil {
+		return nil, err
+	}
+	g.marks, err = loadMarks(g.files.marks)
+	if err != nil {
+		return nil, err
+	}
 
 	g.adblocker = adblock.NewBlocker(g.files.filterlistDir)
 
+	err = g.loadUserContent(nil)
+	if err != nil {
+		return nil, err
+	}
+
 	g.webkitInit()
 
 	for _, rcfile := range g.files.rcFiles() {
-		err := g.useRcFile(rcfile)
+		errs, err := g.useRcFile(nil, rcfile)
 		if err != nil {
 			return nil, err
 		}
+		g.rcErrors = append(g.rcErrors, errs...)
+	}
+
+	changes, err := g.watchRcFiles()
+	if err != nil {
+		(*Window)(nil).logErrorf("Failed to watch rc files: %v", err)
+	} else {
+		go g.handleRcChanges(changes)
 	}
 
 	return g, nil
@@ -274,38 +320,70 @@ func (g *Golem) pasteWebViews() (wvs []*webView) {
 	return wvs
 }
 
-// useRcFile reads and executes an rc file.
-func (g *Golem) useRcFile(file string) error {
-	f, err := os.Open(file)
-	if err != nil {
-		return err
+// bind creates a new key binding in the given mode.
+//
+// Bindings in modes other than normal mode must consist of a single key.
+func (g *Golem) bind(mode cmd.Mode, from string, to string) error {
+	if mode != cmd.ModeNormal && len(cmd.ParseKeys(from)) != 1 {
+		return fmt.Errorf(
+			"Bindings in %v mode must consist of a single key.", mode)
 	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		runCmd(nil, g, scanner.Text())
+	// We check if the key has been bound before. If so, we replace the
+	// binding.
+	index := g.bindingIndex(mode, from)
+
+	g.wMutex.Lock()
+	if index != -1 {
+		g.rawBindings[mode][index] = cmd.RawBinding{from, to}
+	} else {
+		g.rawBindings[mode] = append(
+			g.rawBindings[mode],
+			cmd.RawBinding{from, to})
+	}
+	g.wMutex.Unlock()
+
+	for _, w := range g.windows {
+		w.rebuildBindings()
 	}
 	return nil
 }
 
-// bind creates a new key binding.
-func (g *Golem) bind(from string, to string) {
-	// We check if the key has been bound before. If so, we replace the
-	// binding.
-	index := -1
-	for i, b := range g.rawBindings {
-		if from == b.From {
-			index = i
-			break
+// bindingIndex retrieves the index of the binding of a key sequence in the
+// given mode, or -1 if it isn't bound.
+func (g *Golem) bindingIndex(mode cmd.Mode, from string) int {
+	keyStr := cmd.KeysString(cmd.ParseKeys(from))
+	for i, b := range g.rawBindings[mode] {
+		if keyStr == cmd.KeysString(cmd.ParseKeys(b.From)) {
+			return i
 		}
 	}
+	return -1
+}
+
+// unbind removes the key binding of a key sequence in the given mode.
+func (g *Golem) unbind(mode cmd.Mode, from string) error {
+	index := g.bindingIndex(mode, from)
+	if index == -1 {
+		return fmt.Errorf("No binding for '%v' in %v mode.", from, mode)
+	}
 
 	g.wMutex.Lock()
-	if index != -1 {
-		g.rawBindings[index] = cmd.RawBinding{from, to}
-	} else {
-		g.rawBindings = append(g.rawBindings, cmd.RawBinding{from, to})
+	bindings := g.rawBindings[mode]
+	g.rawBindings[mode] = append(
+		bindings[:index:index],
+		bindings[index+1:]...)
+	g.wMutex.Unlock()
+
+	for _, w := range g.windows {
+		w.rebuildBindings()
 	}
+	return nil
+}
+
+// mapclear removes all key bindings in the given mode.
+func (g *Golem) mapclear(mode cmd.Mode) {
+	g.wMutex.Lock()
+	delete(g.rawBindings, mode)
 	g.wMutex.Unlock()
 
 	for _, w := range g.windows {
@@ -0,0 +1,204 @@
+package golem
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tkerber/golem/golem/states"
+)
+
+// HintsChars is the default alphabet of hints labels.
+const HintsChars = "FDSARTGBVECWXQZIOPMNHYULKJ"
+
+// hintsAlphabets are the named alphabets hints-chars may be set to.
+var hintsAlphabets = map[string]string{
+	"home-row": "FJDKSLAGH",
+	"numeric":  "1234567890",
+}
+
+// hintsAlphabet retrieves the alphabet hints labels are made of.
+//
+// This is the hints-chars setting, or the alphabet it names, in upper case
+// and with duplicate characters removed. If it contains less than two
+// characters, the default alphabet is used instead.
+func (g *Golem) hintsAlphabet() []rune {
+	chars := g.hintsChars
+	if named, ok := hintsAlphabets[chars]; ok {
+		chars = named
+	}
+	alphabet := make([]rune, 0, len(chars))
+	seen := make(map[rune]bool, len(chars))
+	for _, r := range strings.ToUpper(chars) {
+		if !seen[r] {
+			seen[r] = true
+			alphabet = append(alphabet, r)
+		}
+	}
+	if len(alphabet) < 2 {
+		log.Printf("Hints alphabet '%s' too small, using default.", chars)
+		return []rune(HintsChars)
+	}
+	return alphabet
+}
+
+// A hintCandidate describes an element to be hinted. Its position is relative
+// to the top level viewport.
+type hintCandidate struct {
+	Tag    string
+	Text   string
+	Href   string
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// distance gets the distance of the centre of the candidate to the centre of
+// a viewport of the given size.
+func (c hintCandidate) distance(width, height float64) float64 {
+	return math.Hypot(
+		c.X+c.Width/2-width/2,
+		c.Y+c.Height/2-height/2)
+}
+
+// hintsSet is the set of hints displayed in a web view.
+type hintsSet struct {
+	width      float64
+	height     float64
+	candidates []hintCandidate
+	// ids are the labels the candidates were initially given, which
+	// identify them.
+	ids []string
+}
+
+// newHintsSet labels a set of hint candidates in a viewport of the given
+// size.
+func (g *Golem) newHintsSet(
+	width, height float64,
+	candidates []hintCandidate) *hintsSet {
+
+	distances := make([]float64, len(candidates))
+	for i, c := range candidates {
+		distances[i] = c.distance(width, height)
+	}
+	return &hintsSet{
+		width,
+		height,
+		candidates,
+		hintsLabels(g.hintsAlphabet(), distances),
+	}
+}
+
+// HintsTexts retrieves the ids and texts of the hints displayed, in the order
+// of the elements in the page.
+//
+// Elements without text, such as images, are identified by their uri
+// instead.
+func (wv *webView) HintsTexts() ([]states.HintText, error) {
+	wv.hintsMutex.Lock()
+	defer wv.hintsMutex.Unlock()
+	if wv.hints == nil {
+		return nil, errors.New("No hints displayed.")
+	}
+	ret := make([]states.HintText, len(wv.hints.candidates))
+	for i, c := range wv.hints.candidates {
+		text := c.Text
+		if text == "" {
+			text = c.Href
+		}
+		ret[i] = states.HintText{wv.hints.ids[i], text}
+	}
+	return ret, nil
+}
+
+// ShowHints displays only the hints with the given ids, labelling them
+// afresh.
+func (wv *webView) ShowHints(ids []string) (int64, error) {
+	wv.hintsMutex.Lock()
+	if wv.hints == nil {
+		wv.hintsMutex.Unlock()
+		return 0, errors.New("No hints displayed.")
+	}
+	indices := make(map[string]int, len(wv.hints.ids))
+	for i, id := range wv.hints.ids {
+		indices[id] = i
+	}
+	distances := make([]float64, 0, len(ids))
+	shown := make([]string, 0, len(ids))
+	for _, id := range ids {
+		i, ok := indices[id]
+		if !ok {
+			continue
+		}
+		shown = append(shown, id)
+		distances = append(distances, wv.hints.candidates[i].distance(
+			wv.hints.width,
+			wv.hints.height))
+	}
+	wv.hintsMutex.Unlock()
+	labels := hintsLabels(wv.parent.hintsAlphabet(), distances)
+	return wv.relabelHints(shown, labels)
+}
+
+// EndHintsMode ends hints mode, and forgets the hints displayed.
+func (wv *webView) EndHintsMode() error {
+	wv.hintsMutex.Lock()
+	wv.hints = nil
+	wv.hintsMutex.Unlock()
+	return wv.webExtension.EndHintsMode()
+}
+
+// byDistance sorts indices of hints by the distance of their elements.
+type byDistance struct {
+	indices   []int
+	distances []float64
+}
+
+// Len returns the number of hints.
+func (s byDistance) Len() int {
+	return len(s.indices)
+}
+
+// Less checks if the element of hint i is closer than that of hint j.
+func (s byDistance) Less(i, j int) bool {
+	return s.distances[s.indices[i]] < s.distances[s.indices[j]]
+}
+
+// Swap swaps the hints i and j.
+func (s byDistance) Swap(i, j int) {
+	s.indices[i], s.indices[j] = s.indices[j], s.indices[i]
+}
+
+// hintsLabels generates a label for each hinted element, given the
+// distances of the elements to the centre of the viewport.
+//
+// The labels are prefix-free, so any label typed in full selects its
+// element at once. They are the leaves of a tree over the alphabet which is
+// as shallow as possible: Starting from the empty label, the shortest label
+// is repeatedly replaced with its extensions by each character, until there
+// are enough labels. Shorter labels are given to closer elements, with ties
+// broken by the order of the elements.
+func hintsLabels(alphabet []rune, distances []float64) []string {
+	n := len(distances)
+	labels := []string{""}
+	for len(labels) < n || len(labels) == 1 {
+		prefix := labels[0]
+		labels = labels[1:]
+		for _, r := range alphabet {
+			labels = append(labels, prefix+string(r))
+		}
+	}
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.Stable(byDistance{indices, distances})
+	ret := make([]string, n)
+	for i, index := range indices {
+		ret[index] = labels[i]
+	}
+	return ret
+}
@@ -0,0 +1,96 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// testHintsAlphabet is the alphabet hints labels are tested with. Prefixes
+// of at least two characters of it are used.
+const testHintsAlphabet = "asdfghjkl"
+
+// checkHintsLabels generates labels for the given distances over an
+// alphabet of between 2 and 9 characters, and checks them with f.
+func checkHintsLabels(
+	t *testing.T,
+	f func(alphabet []rune, distances []float64, labels []string) bool) {
+
+	err := quick.Check(func(size uint8, distances []float64) bool {
+		alphabet := []rune(testHintsAlphabet)[:2+int(size)%8]
+		return f(alphabet, distances, hintsLabels(alphabet, distances))
+	}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHintsLabelsOnePerElement(t *testing.T) {
+	checkHintsLabels(t, func(
+		alphabet []rune,
+		distances []float64,
+		labels []string) bool {
+
+		if len(labels) != len(distances) {
+			return false
+		}
+		for _, l := range labels {
+			if l == "" || strings.Trim(l, string(alphabet)) != "" {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestHintsLabelsDistinct(t *testing.T) {
+	checkHintsLabels(t, func(_ []rune, _ []float64, labels []string) bool {
+		seen := make(map[string]bool, len(labels))
+		for _, l := range labels {
+			if seen[l] {
+				return false
+			}
+			seen[l] = true
+		}
+		return true
+	})
+}
+
+func TestHintsLabelsPrefixFree(t *testing.T) {
+	checkHintsLabels(t, func(_ []rune, _ []float64, labels []string) bool {
+		for i, l1 := range labels {
+			for j, l2 := range labels {
+				if i != j && strings.HasPrefix(l2, l1) {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+func TestHintsLabelsShorterWhenNearer(t *testing.T) {
+	checkHintsLabels(t, func(
+		_ []rune,
+		distances []float64,
+		labels []string) bool {
+
+		for i := range distances {
+			for j := range distances {
+				if distances[i] < distances[j] &&
+					len(labels[i]) > len(labels[j]) {
+
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+func TestHintsLabelsSingleElement(t *testing.T) {
+	labels := hintsLabels([]rune("ab"), []float64{3})
+	if len(labels) != 1 || labels[0] != "a" {
+		t.Errorf("Got labels %q for one element, expected [\"a\"].", labels)
+	}
+}
@@ -0,0 +1,186 @@
+package golem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/states"
+)
+
+// maxReplayDepth is the maximum depth of macros replaying other macros.
+const maxReplayDepth = 20
+
+// registers store the key sequences of macros, persisted to a file.
+type registers struct {
+	path  string
+	keys  map[rune][]cmd.Key
+	mutex *sync.Mutex
+}
+
+// loadRegisters loads the registers from their file.
+func loadRegisters(path string) (*registers, error) {
+	r := &registers{path, make(map[rune][]cmd.Key), new(sync.Mutex)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// No registers to load. Nothing to do.
+	} else if err != nil {
+		return nil, err
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			split := strings.SplitN(line, "\t", 2)
+			name := []rune(split[0])
+			if len(split) != 2 ||
+				len(name) != 1 ||
+				!states.IsRegisterName(name[0]) {
+
+				continue
+			}
+			r.keys[name[0]] = cmd.ParseKeys(split[1])
+		}
+	}
+	return r, nil
+}
+
+// get retrieves the keys stored in a register.
+func (r *registers) get(name rune) ([]cmd.Key, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	keys, ok := r.keys[unicode.ToLower(name)]
+	return keys, ok
+}
+
+// set stores keys in a register, and writes the registers file.
+//
+// Upper case register names append to the lower case register of the same
+// name.
+func (r *registers) set(name rune, keys []cmd.Key) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if unicode.IsUpper(name) {
+		name = unicode.ToLower(name)
+		keys = append(append([]cmd.Key(nil), r.keys[name]...), keys...)
+	}
+	if len(keys) == 0 {
+		delete(r.keys, name)
+	} else {
+		r.keys[name] = keys
+	}
+	err := ioutil.WriteFile(r.path, []byte(r.format("\n")+"\n"), 0600)
+	if err != nil {
+		(*Window)(nil).logErrorf("Failed to write registers file: %v", err)
+	}
+}
+
+// String formats the contents of all registers for display.
+func (r *registers) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.format("  ")
+}
+
+// format formats all registers as sorted "NAME\tKEYS" entries, separated by
+// sep. The mutex must be held.
+func (r *registers) format(sep string) string {
+	names := make([]string, 0, len(r.keys))
+	for name := range r.keys {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = fmt.Sprintf(
+			"%s\t%s",
+			name,
+			cmd.KeysString(r.keys[[]rune(name)[0]]))
+	}
+	return strings.Join(entries, sep)
+}
+
+// A macroRecording is a macro currently being recorded in a window.
+type macroRecording struct {
+	register rune
+	keys     []cmd.Key
+	// bindingStart is the number of keys recorded before the key sequence
+	// of the current normal mode binding started.
+	bindingStart int
+}
+
+// startRecording starts recording a macro into a register.
+func (w *Window) startRecording(register rune) {
+	w.recording = &macroRecording{register, make([]cmd.Key, 0), 0}
+	w.setState(cmd.NewStatusMode(
+		w.State,
+		states.StatusSubstateMajor,
+		fmt.Sprintf("Recording @%c", register)))
+}
+
+// stopRecording stops recording a macro, and stores it in its register.
+//
+// As recording is stopped by a normal mode binding, the keys of this binding
+// are not stored.
+func (w *Window) stopRecording() {
+	rec := w.recording
+	if rec == nil {
+		return
+	}
+	w.recording = nil
+	w.parent.registers.set(rec.register, rec.keys[:rec.bindingStart])
+	w.setState(cmd.NewStatusMode(
+		w.State,
+		states.StatusSubstateMinor,
+		fmt.Sprintf("Recorded @%c", rec.register)))
+}
+
+// recordKey records a key press into a recording, if it is still running.
+//
+// before is the state before the key was processed.
+func (w *Window) recordKey(
+	rec *macroRecording,
+	before cmd.State,
+	key cmd.RealKey) {
+
+	if rec == nil || rec != w.recording {
+		return
+	}
+	// Status messages are dismissed by the key press, and the key is
+	// processed by the state below.
+	if sm, ok := before.(*cmd.StatusMode); ok {
+		before = sm.State
+	}
+	if nm, ok := before.(*cmd.NormalMode); ok && len(nm.CurrentKeys) == 0 {
+		rec.bindingStart = len(rec.keys)
+	}
+	rec.keys = append(rec.keys, key)
+}
+
+// replayMacro replays the keys stored in a register n times.
+func (w *Window) replayMacro(register rune, n int) {
+	keys, ok := w.parent.registers.get(register)
+	if !ok {
+		w.logErrorf("Register @%c is empty.", register)
+		return
+	}
+	if w.replayDepth >= maxReplayDepth {
+		w.logErrorf("Macro recursion too deep in @%c.", register)
+		return
+	}
+	w.replayDepth++
+	defer func() {
+		w.replayDepth--
+	}()
+	w.State.GetStateIndependant().Synchronously(func() {
+		for i := 0; i < n; i++ {
+			for _, key := range keys {
+				if rk, ok := key.(cmd.RealKey); ok {
+					w.processKey(rk)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package golem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tkerber/golem/golem/rcstore"
+	"github.com/tkerber/golem/golem/states"
+)
+
+// jumpMark is the name of the mark of the position before the last jump.
+const jumpMark = '\''
+
+// A mark is a scroll position on a page.
+type mark struct {
+	uri  string
+	top  int64
+	left int64
+}
+
+// marks stores the marks set, and persists them in a file.
+//
+// Lower case marks and the jump mark are local to the page they were set on,
+// upper case marks are global.
+type marks struct {
+	path string
+	// local maps uris to the marks set on them.
+	local  map[string]map[rune]mark
+	global map[rune]mark
+	mutex  *sync.Mutex
+}
+
+// isGlobalMark checks if the name of a mark names a global mark.
+func isGlobalMark(name rune) bool {
+	return unicode.IsUpper(name)
+}
+
+// loadMarks loads the marks from their file.
+//
+// Each line of the file is of the form "NAME\tTOP\tLEFT\tURI".
+func loadMarks(path string) (*marks, error) {
+	m := &marks{
+		path,
+		make(map[string]map[rune]mark),
+		make(map[rune]mark),
+		new(sync.Mutex),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// No marks to load. Nothing to do.
+	} else if err != nil {
+		return nil, err
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			split := strings.SplitN(line, "\t", 4)
+			if len(split) != 4 {
+				continue
+			}
+			name := []rune(split[0])
+			top, err1 := strconv.ParseInt(split[1], 10, 64)
+			left, err2 := strconv.ParseInt(split[2], 10, 64)
+			if len(name) != 1 ||
+				!states.IsMarkName(name[0]) ||
+				err1 != nil ||
+				err2 != nil {
+
+				continue
+			}
+			m.put(name[0], mark{split[3], top, left})
+		}
+	}
+	return m, nil
+}
+
+// put stores a mark, without writing the marks file. The mutex must be held.
+func (m *marks) put(name rune, mk mark) {
+	if name == '`' {
+		name = jumpMark
+	}
+	if isGlobalMark(name) {
+		m.global[name] = mk
+		return
+	}
+	local, ok := m.local[mk.uri]
+	if !ok {
+		local = make(map[rune]mark)
+		m.local[mk.uri] = local
+	}
+	local[name] = mk
+}
+
+// get retrieves a mark. Local marks are retrieved for the given uri.
+func (m *marks) get(name rune, uri string) (mark, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if name == '`' {
+		name = jumpMark
+	}
+	if isGlobalMark(name) {
+		mk, ok := m.global[name]
+		return mk, ok
+	}
+	mk, ok := m.local[uri][name]
+	return mk, ok
+}
+
+// set stores a mark, and writes the marks file.
+func (m *marks) set(name rune, mk mark) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.put(name, mk)
+	err := rcstore.WriteFile(m.path, []byte(m.format()))
+	if err != nil {
+		(*Window)(nil).logErrorf("Failed to write marks file: %v", err)
+	}
+}
+
+// format formats all marks as sorted lines of the marks file. The mutex must
+// be held.
+func (m *marks) format() string {
+	lines := make([]string, 0, len(m.global))
+	add := func(name rune, mk mark) {
+		lines = append(lines, fmt.Sprintf(
+			"%c\t%d\t%d\t%s", name, mk.top, mk.left, mk.uri))
+	}
+	for name, mk := range m.global {
+		add(name, mk)
+	}
+	for _, local := range m.local {
+		for name, mk := range local {
+			add(name, mk)
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// markPosition retrieves the current scroll position of a web view as a
+// mark.
+func (wv *webView) markPosition() (mark, error) {
+	top, err := wv.getScrollTop()
+	if err != nil {
+		return mark{}, err
+	}
+	left, err := wv.getScrollLeft()
+	if err != nil {
+		return mark{}, err
+	}
+	return mark{wv.GetURI(), top, left}, nil
+}
+
+// setMark sets a mark at the current scroll position.
+func (w *Window) setMark(name rune) {
+	mk, err := w.getWebView().markPosition()
+	if err != nil {
+		w.logErrorf("Failed to set mark: %v", err)
+		return
+	}
+	w.parent.marks.set(name, mk)
+}
+
+// setJumpMark remembers the current scroll position before a jump, so that
+// it can be jumped back to.
+//
+// The position is remembered by the window, so that jumping back works
+// after a jump to another page, and is also persisted for the current page.
+func (w *Window) setJumpMark() {
+	mk, err := w.getWebView().markPosition()
+	if err != nil {
+		w.logErrorf("Failed to set mark: %v", err)
+		return
+	}
+	w.wMutex.Lock()
+	w.lastJump = &mk
+	w.wMutex.Unlock()
+	w.parent.marks.set(jumpMark, mk)
+}
+
+// getMark retrieves a mark for the current page of the window.
+//
+// The jump mark is the window's last jump if there is one, and is otherwise
+// the jump mark persisted for the page.
+func (w *Window) getMark(name rune) (mark, bool) {
+	if name == jumpMark || name == '`' {
+		w.wMutex.Lock()
+		last := w.lastJump
+		w.wMutex.Unlock()
+		if last != nil {
+			return *last, true
+		}
+	}
+	return w.parent.marks.get(name, w.getWebView().GetURI())
+}
+
+// jumpToMark jumps to a mark. If line is set, only the vertical position is
+// jumped to.
+//
+// Marks set on a different page load the page first.
+func (w *Window) jumpToMark(name rune, line bool) {
+	wv := w.getWebView()
+	mk, ok := w.getMark(name)
+	if !ok {
+		w.logErrorf("Mark not set: %c", name)
+		return
+	}
+	w.setJumpMark()
+	if mk.uri != wv.GetURI() {
+		wv.pendingMarkMutex.Lock()
+		wv.pendingMark = &mk
+		wv.pendingMarkLine = line
+		wv.pendingMarkMutex.Unlock()
+		wv.LoadURI(mk.uri)
+		return
+	}
+	wv.scrollToMark(mk, line)
+}
+
+// scrollToMark scrolls a web view to the position of a mark.
+func (wv *webView) scrollToMark(mk mark, line bool) {
+	var err error
+	// Only one axis is scrolled smoothly at a time, so the horizontal
+	// position is jumped to directly.
+	if !line {
+		err = wv.setScrollLeft(mk.left)
+	}
+	if err == nil {
+		err = wv.scrollTo(mk.top, true, false)
+	}
+	if err != nil {
+		wv.window.logErrorf("Error scrolling: %v", err)
+	}
+}
+
+// applyPendingMark scrolls to the mark jumped to once its page finished
+// loading.
+func (wv *webView) applyPendingMark() {
+	wv.pendingMarkMutex.Lock()
+	mk := wv.pendingMark
+	line := wv.pendingMarkLine
+	wv.pendingMark = nil
+	wv.pendingMarkMutex.Unlock()
+	if mk != nil && mk.uri == wv.GetURI() {
+		go wv.scrollToMark(*mk, line)
+	}
+}
@@ -0,0 +1,114 @@
+package golem
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/states"
+)
+
+// isPassThroughSite checks if a uri matches any of the pass-through sites.
+func (g *Golem) isPassThroughSite(uri string) bool {
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	for _, p := range g.passThroughSites {
+		if p.matches(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPassThrough enters pass-through mode in the web view's window if it is
+// displayed and its uri matches a pass-through site.
+//
+// If it doesn't match, pass-through mode is left if it was entered
+// automatically.
+func (wv *webView) applyPassThrough() {
+	w := wv.window
+	if w == nil || w.getWebView() != wv {
+		return
+	}
+	match := wv.parent.isPassThroughSite(wv.GetURI())
+	pm, ok := w.State.(*cmd.PassThroughMode)
+	if match && !ok {
+		w.setState(cmd.NewPassThroughMode(
+			w.State,
+			states.PassThroughSubstateAuto))
+	} else if !match && ok && pm.Substate == states.PassThroughSubstateAuto {
+		w.setState(cmd.NewNormalMode(w.State))
+	}
+}
+
+// applyPassThroughAll applies the pass-through sites to the displayed web
+// view of each window.
+func (g *Golem) applyPassThroughAll() {
+	for _, w := range g.windows {
+		w.getWebView().applyPassThrough()
+	}
+}
+
+// cmdPassthrough adds sites which enter pass-through mode automatically, or
+// lists them.
+//
+// Takes the form "passthrough [PATTERN]...". See uriPattern for the form of
+// patterns.
+func cmdPassthrough(w *Window, g *Golem, args []string) error {
+	if len(args) == 1 {
+		if w == nil {
+			return errNonGlobalCommand
+		}
+		g.wMutex.Lock()
+		strs := make([]string, len(g.passThroughSites))
+		for i, p := range g.passThroughSites {
+			strs[i] = p.String()
+		}
+		g.wMutex.Unlock()
+		msg := "No pass-through sites."
+		if len(strs) > 0 {
+			msg = "Pass-through sites: " + strings.Join(strs, " ")
+		}
+		w.setState(cmd.NewStatusMode(w.State, states.StatusSubstateMinor, msg))
+		return nil
+	}
+	var errs errorList
+	for _, arg := range args[1:] {
+		pattern, err := newURIPattern(arg)
+		if err != nil {
+			errs.add(fmt.Errorf("Invalid pattern '%v': %v", arg, err))
+			continue
+		}
+		g.wMutex.Lock()
+		g.passThroughSites = append(g.passThroughSites, pattern)
+		g.wMutex.Unlock()
+	}
+	g.applyPassThroughAll()
+	return errs.err()
+}
+
+// cmdPassthroughClear removes sites from the pass-through sites, or all of
+// them if none are given.
+//
+// Takes the form "passthrough! [PATTERN]...".
+func cmdPassthroughClear(w *Window, g *Golem, args []string) error {
+	g.wMutex.Lock()
+	if len(args) == 1 {
+		g.passThroughSites = nil
+	} else {
+		kept := make([]*uriPattern, 0, len(g.passThroughSites))
+	outer:
+		for _, p := range g.passThroughSites {
+			for _, arg := range args[1:] {
+				if p.String() == arg {
+					continue outer
+				}
+			}
+			kept = append(kept, p)
+		}
+		g.passThroughSites = kept
+	}
+	g.wMutex.Unlock()
+	g.applyPassThroughAll()
+	return nil
+}
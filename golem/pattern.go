@@ -0,0 +1,64 @@
+package golem
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// A uriPattern is a glob pattern which uris can be matched against.
+//
+// In patterns, "*" matches any sequence of characters, and "?" matches any
+// single character. If the pattern contains no "/", it is matched against the
+// host of the uri only; otherwise it is matched against the full uri. A
+// leading "*." in a host pattern also matches the domain itself, i.e.
+// "*.example.com" matches both "example.com" and "www.example.com".
+type uriPattern struct {
+	raw      string
+	regex    *regexp.Regexp
+	hostOnly bool
+}
+
+// newURIPattern compiles a uri pattern.
+func newURIPattern(pattern string) (*uriPattern, error) {
+	hostOnly := !strings.Contains(pattern, "/")
+	glob := pattern
+	prefix := "^"
+	if hostOnly && strings.HasPrefix(glob, "*.") {
+		glob = glob[2:]
+		prefix = `^(.*\.)?`
+	}
+	regexStr := prefix
+	for _, r := range glob {
+		switch r {
+		case '*':
+			regexStr += ".*"
+		case '?':
+			regexStr += "."
+		default:
+			regexStr += regexp.QuoteMeta(string(r))
+		}
+	}
+	regex, err := regexp.Compile(regexStr + "$")
+	if err != nil {
+		return nil, err
+	}
+	return &uriPattern{pattern, regex, hostOnly}, nil
+}
+
+// matches checks if a uri matches the pattern.
+func (p *uriPattern) matches(uri string) bool {
+	if !p.hostOnly {
+		return p.regex.MatchString(uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return p.regex.MatchString(u.Hostname())
+}
+
+// String returns the pattern as it was written.
+func (p *uriPattern) String() string {
+	return p.raw
+}
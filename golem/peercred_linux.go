@@ -0,0 +1,32 @@
+package golem
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerCredentials retrieves the credentials of the process at the other end
+// of a connection to golem's socket.
+func peerCredentials(c net.Conn) (*syscall.Ucred, error) {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return nil, errors.New("Not a unix socket connection.")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(
+			int(fd),
+			syscall.SOL_SOCKET,
+			syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cred, credErr
+}
@@ -0,0 +1,542 @@
+package golem
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/conformal/gotk3/gdk"
+	"github.com/conformal/gotk3/gtk"
+	"github.com/mattn/go-shellwords"
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/rcstore"
+	"github.com/tkerber/golem/golem/states"
+	"github.com/tkerber/golem/golem/version"
+	ggtk "github.com/tkerber/golem/gtk"
+	"github.com/tkerber/golem/webkit"
+	"github.com/tkerber/golem/xdg"
+)
+
+// rcReloadDelay is the time to wait after a change to an rc file was detected
+// before reloading. Further changes within this time are merged.
+const rcReloadDelay = 100 * time.Millisecond
+
+// An rcError is an error which occurred while executing a line of an rc file.
+type rcError struct {
+	file string
+	line int
+	msg  string
+}
+
+// Error returns the error message, prefixed with the file and line number.
+func (e *rcError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.file, e.line, e.msg)
+}
+
+// An rcSource is an rc file which is currently being executed.
+type rcSource struct {
+	file  string
+	line  int
+	errs  []error
+	conds []*rcCond
+}
+
+// An rcCond is a single if block of an rc file.
+type rcCond struct {
+	// active is true if lines in the current branch of the block are
+	// executed.
+	active bool
+	// done is true if no further branches of the block can be executed.
+	done bool
+}
+
+// active checks if lines in the current position of the file are executed.
+func (src *rcSource) active() bool {
+	for _, c := range src.conds {
+		if !c.active {
+			return false
+		}
+	}
+	return true
+}
+
+// logError records an error against the current line of the rc file.
+func (src *rcSource) logError(msg string) {
+	src.errs = append(src.errs, &rcError{src.file, src.line, msg})
+}
+
+// addError records an error returned by a command on the current line of the
+// rc file, if any.
+//
+// Errors which already belong to a line of a file sourced by the rc file are
+// kept as they are.
+func (src *rcSource) addError(err error) {
+	var errs errorList
+	errs.add(err)
+	for _, err := range errs {
+		if rcErr, ok := err.(*rcError); ok {
+			src.errs = append(src.errs, rcErr)
+		} else {
+			src.logError(err.Error())
+		}
+	}
+}
+
+// useRcFile reads and executes an rc file.
+//
+// Errors which occur while executing individual lines are collected from the
+// commands run, and returned separately from errors accessing the file.
+func (g *Golem) useRcFile(w *Window, file string) ([]error, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	atomic.AddInt32(&g.rcDepth, 1)
+	defer atomic.AddInt32(&g.rcDepth, -1)
+
+	src := &rcSource{file, 0, nil, nil}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		src.line++
+		line := scanner.Text()
+		if g.rcConditional(w, src, line) || !src.active() {
+			continue
+		}
+		src.addError(execCmd(w, g, line))
+	}
+	if len(src.conds) != 0 {
+		src.logError("Missing endif at end of file.")
+	}
+	return src.errs, scanner.Err()
+}
+
+// rcConditional handles the if, elseif, else and endif lines of an rc file.
+//
+// It returns false if the line is not one of these.
+func (g *Golem) rcConditional(w *Window, src *rcSource, line string) bool {
+	if blankLineRegex.MatchString(line) {
+		return false
+	}
+	parts, err := shellwords.Parse(line)
+	if err != nil || len(parts) == 0 {
+		return false
+	}
+	if len(parts[0]) == 0 {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	var top *rcCond
+	if len(src.conds) != 0 {
+		top = src.conds[len(src.conds)-1]
+	}
+	switch parts[0] {
+	case "if":
+		parentActive := src.active()
+		cond := false
+		if parentActive {
+			cond = g.evalRcCondition(w, src, parts[1:])
+		}
+		src.conds = append(src.conds, &rcCond{cond, !parentActive || cond})
+	case "elseif":
+		if top == nil {
+			src.logError("elseif without if.")
+		} else if top.done {
+			top.active = false
+		} else {
+			// If the block isn't done, the enclosing block is active.
+			top.active = g.evalRcCondition(w, src, parts[1:])
+			top.done = top.active
+		}
+	case "else":
+		if top == nil {
+			src.logError("else without if.")
+		} else {
+			top.active = !top.done
+			top.done = true
+		}
+	case "endif":
+		if top == nil {
+			src.logError("endif without if.")
+		} else {
+			src.conds = src.conds[:len(src.conds)-1]
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// evalRcCondition evaluates the condition of an if or elseif line.
+//
+// A condition is either a single term, optionally negated with a leading
+// "!", or a comparison of two strings with "==" or "!=". The arguments are
+// interpolated before evaluation. A term is true if:
+//
+// golem:SETTING or webkit:SETTING is a setting which is true, non-empty or
+// non-zero.
+//
+// env:NAME is a non-empty environment variable.
+//
+// exists:NAME is a variable defined with let.
+//
+// Any other term is true unless it is empty, "0" or "false".
+//
+// Errors are recorded against the rc file, and lead to the condition being
+// false.
+func (g *Golem) evalRcCondition(
+	w *Window,
+	src *rcSource,
+	args []string) bool {
+
+	for i, arg := range args {
+		args[i] = g.interpolate(w, arg)
+	}
+	switch {
+	case len(args) == 3 && args[1] == "==":
+		return args[0] == args[2]
+	case len(args) == 3 && args[1] == "!=":
+		return args[0] != args[2]
+	case len(args) != 1:
+		src.logError(fmt.Sprintf(
+			"Invalid condition: '%v'",
+			strings.Join(args, " ")))
+		return false
+	}
+	term := args[0]
+	negate := false
+	for strings.HasPrefix(term, "!") {
+		negate = !negate
+		term = term[1:]
+	}
+	val, err := g.evalRcTerm(w, term)
+	if err != nil {
+		src.logError(fmt.Sprintf("Invalid condition: %v", err))
+		return false
+	}
+	return val != negate
+}
+
+// evalRcTerm evaluates a single term of a condition.
+func (g *Golem) evalRcTerm(w *Window, term string) (bool, error) {
+	split := strings.SplitN(term, ":", 2)
+	if len(split) == 2 {
+		switch split[0] {
+		case "golem", "g":
+			if _, err := g.globalCfg.typeOf(split[1]); err != nil {
+				return false, err
+			}
+			return truthy(g.globalCfg.get(split[1])), nil
+		case "webkit", "w":
+			t, err := webkit.GetSettingsType(split[1])
+			if err != nil {
+				return false, err
+			}
+			settings := g.DefaultSettings
+			if w != nil {
+				settings = w.getWebView().settings
+			}
+			switch t.Kind() {
+			case reflect.Bool:
+				return settings.GetBool(split[1]), nil
+			case reflect.String:
+				return settings.GetString(split[1]) != "", nil
+			default:
+				return settings.GetUint(split[1]) != 0, nil
+			}
+		case "env":
+			return os.Getenv(split[1]) != "", nil
+		case "exists":
+			g.wMutex.Lock()
+			_, ok := g.rcVars[split[1]]
+			g.wMutex.Unlock()
+			return ok, nil
+		}
+	}
+	return truthy(term), nil
+}
+
+// truthy checks whether a value counts as true in a condition.
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case uint:
+		return v != 0
+	case string:
+		return v != "" && v != "0" && v != "false"
+	default:
+		return v != nil
+	}
+}
+
+// interpolationRegex matches a variable reference in a command argument, or
+// an escaped brace.
+var interpolationRegex = regexp.MustCompile(
+	`\{\{|\{([A-Za-z_][A-Za-z0-9_.:-]*)\}`)
+
+// interpolate replaces variable references of the form {NAME} in a string.
+//
+// The following names are recognized:
+//
+// golem_version and golem_version_name are golem's version string and name.
+//
+// uri, host and title are the uri, host and title of the current tab.
+//
+// selection and clipboard are the contents of the primary selection and the
+// clipboard.
+//
+// env:NAME is the environment variable NAME.
+//
+// golem:SETTING is the value of a golem setting.
+//
+// Any other name refers to a variable defined with let. References to unknown
+// names are left as they are.
+//
+// "{{" is replaced with a literal "{", so e.g. "{{uri}" becomes "{uri}".
+func (g *Golem) interpolate(w *Window, str string) string {
+	return interpolationRegex.ReplaceAllStringFunc(str, func(ref string) string {
+		if ref == "{{" {
+			return "{"
+		}
+		name := ref[1 : len(ref)-1]
+		if val, ok := g.lookupVar(w, name); ok {
+			return val
+		}
+		return ref
+	})
+}
+
+// lookupVar looks up the value of a variable for interpolation.
+func (g *Golem) lookupVar(w *Window, name string) (string, bool) {
+	switch name {
+	case "golem_version":
+		return version.Version, true
+	case "golem_version_name":
+		return version.Name, true
+	case "uri", "host":
+		if w == nil {
+			return "", false
+		}
+		uri := w.getWebView().GetURI()
+		if name == "uri" {
+			return uri, true
+		}
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", true
+		}
+		return u.Hostname(), true
+	case "title":
+		if w == nil {
+			return "", false
+		}
+		return w.getWebView().GetTitle(), true
+	case "selection", "clipboard":
+		selection := gdk.SELECTION_PRIMARY
+		if name == "clipboard" {
+			selection = gdk.SELECTION_CLIPBOARD
+		}
+		rets := ggtk.GlibMainContextInvoke(func() (string, error) {
+			clip, err := gtk.ClipboardGet(selection)
+			if err != nil {
+				return "", err
+			}
+			return clip.WaitForText()
+		})
+		if rets[1] != nil {
+			return "", true
+		}
+		return rets[0].(string), true
+	}
+	split := strings.SplitN(name, ":", 2)
+	if len(split) == 2 {
+		switch split[0] {
+		case "env":
+			return os.LookupEnv(split[1])
+		case "golem", "g":
+			if _, err := g.globalCfg.typeOf(split[1]); err != nil {
+				return "", false
+			}
+			return fmt.Sprint(g.globalCfg.get(split[1])), true
+		}
+		return "", false
+	}
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	val, ok := g.rcVars[name]
+	return val, ok
+}
+
+// resetConfig resets everything which is set up by rc files to its default
+// state.
+func (g *Golem) resetConfig() {
+	g.wMutex.Lock()
+	g.rawBindings = make(map[cmd.Mode][]cmd.RawBinding)
+	g.quickmarks = make(map[string]uriEntry, 20)
+	g.hasQuickmark = make(map[string]bool, 20)
+	g.bookmarks = make([]uriEntry, 0, 100)
+	g.isBookmark = make(map[string]bool, 100)
+	g.searchEngines = &searchEngines{make(map[string]*searchEngine, 10), nil}
+	g.rcVars = make(map[string]string)
+	g.userCommands = make(map[string]*userCommand)
+	g.autocmds = nil
+	g.siteRules.clear()
+	g.passThroughSites = nil
+
+	cfgs := newDefaultCfg()
+	resetCfg(g.globalCfg, cfgs)
+	for _, w := range g.windows {
+		resetCfg(w.windowCfg, cfgs.windowCfg)
+	}
+	for _, wv := range g.webViews {
+		resetCfg(wv.tabCfg, cfgs.tabCfg)
+	}
+
+	defaults := webkit.NewSettings()
+	g.DefaultSettings.CopyFrom(defaults)
+	for _, wv := range g.webViews {
+		wv.baseSettings.CopyFrom(defaults)
+	}
+	g.wMutex.Unlock()
+}
+
+// reloadConfig resets the configuration and re-executes all rc files.
+//
+// If w is non-nil, the rc files are executed in the context of w.
+func (g *Golem) reloadConfig(w *Window) []error {
+	g.reloadMutex.Lock()
+	defer g.reloadMutex.Unlock()
+
+	g.resetConfig()
+	errs := make([]error, 0)
+	for _, rcfile := range g.files.rcFiles() {
+		lineErrs, err := g.useRcFile(w, rcfile)
+		errs = append(errs, lineErrs...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	g.wMutex.Lock()
+	windows := append([]*Window(nil), g.windows...)
+	webViews := make([]*webView, 0, len(g.webViews))
+	for _, wv := range g.webViews {
+		webViews = append(webViews, wv)
+	}
+	g.wMutex.Unlock()
+	for _, w := range windows {
+		w.rebuildBindings()
+		w.rebuildQuickmarks()
+	}
+	for _, wv := range webViews {
+		wv.applySiteSettings()
+	}
+	return errs
+}
+
+// showRcErrors logs and displays errors from executing rc files.
+//
+// The first error is displayed, along with the number of further errors. If
+// w is nil, the errors are displayed in all windows.
+func (g *Golem) showRcErrors(w *Window, errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	for _, err := range errs {
+		Errlog.Println(err)
+	}
+	msg := errs[0].Error()
+	if len(errs) > 1 {
+		msg = fmt.Sprintf("%s (and %d more errors)", msg, len(errs)-1)
+	}
+	windows := []*Window{w}
+	if w == nil {
+		windows = g.windows
+	}
+	for _, w := range windows {
+		w.setState(cmd.NewStatusMode(
+			w.State,
+			states.StatusSubstateError,
+			msg))
+	}
+}
+
+// handleRcChanges reloads the configuration whenever a changed rc file is
+// received over the given channel.
+//
+// Reloads only happen if the watch-rc setting is enabled.
+func (g *Golem) handleRcChanges(changes <-chan string) {
+	for file := range changes {
+		changed := map[string]bool{file: true}
+		// Merge changes occuring in quick succession.
+		timer := time.After(rcReloadDelay)
+	merge:
+		for {
+			select {
+			case file, ok := <-changes:
+				if !ok {
+					break merge
+				}
+				changed[file] = true
+			case <-timer:
+				break merge
+			}
+		}
+		// Settings are only changed on the main thread, so watch-rc is
+		// checked there.
+		ggtk.GlibMainContextInvoke(func() {
+			if g.watchRc && g.rcChanged(changed) {
+				g.showRcErrors(nil, g.reloadConfig(nil))
+			}
+		})
+	}
+}
+
+// rcChanged checks whether any of the given files were changed by something
+// other than golem itself.
+func (g *Golem) rcChanged(changed map[string]bool) bool {
+	ret := false
+	for _, store := range []*rcstore.File{
+		g.files.searchEnginesStore,
+		g.files.quickmarksStore,
+		g.files.bookmarksStore,
+	} {
+		if !changed[store.Path()] {
+			continue
+		}
+		delete(changed, store.Path())
+		// Writes by golem itself are already reflected in the store, and
+		// are filtered out here.
+		reloaded, err := store.Reload()
+		if err != nil {
+			(*Window)(nil).logErrorf("Failed to reload rc file: %v", err)
+			continue
+		}
+		ret = ret || reloaded
+	}
+	return ret || len(changed) > 0
+}
+
+// resolveRcPath resolves the path of an rc file given to :source.
+//
+// A leading "~/" is expanded to the home directory, and relative paths are
+// taken relative to the config directory.
+func (g *Golem) resolveRcPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(xdg.GetHomeDir(), path[2:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.files.configDir, path)
+	}
+	return path
+}
@@ -0,0 +1,42 @@
+package golem
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	g := newTestGolem("")
+	g.rcVars = map[string]string{"name": "value"}
+	for _, c := range [][2]string{
+		{"{name}", "value"},
+		{"a{name}b{name}", "avaluebvalue"},
+		// Unknown names and other braces are left as they are.
+		{"{unknown}", "{unknown}"},
+		{`{"name": 1}`, `{"name": 1}`},
+		{"}}", "}}"},
+		// "{{" escapes a brace.
+		{"{{name}", "{name}"},
+		{"{{{name}", "{value"},
+		{"{{", "{"},
+	} {
+		if str := g.interpolate(nil, c[0]); str != c[1] {
+			t.Errorf("%q interpolated to %q, expected %q.", c[0], str, c[1])
+		}
+	}
+}
+
+func TestInterpolatePartsSkipsRecords(t *testing.T) {
+	g := newTestGolem("")
+	g.rcVars = map[string]string{"q": "value"}
+	for _, name := range []string{"bm", "qm", "se"} {
+		parts := []string{name, "x", "{q}", "https://example.com/?q={q}"}
+		if got := interpolateParts(nil, g, parts); !reflect.DeepEqual(got, parts) {
+			t.Errorf("Record %q was interpolated to %q.", parts, got)
+		}
+	}
+	parts := interpolateParts(nil, g, []string{"open", "{q}"})
+	if expected := []string{"open", "value"}; !reflect.DeepEqual(parts, expected) {
+		t.Errorf("Command was interpolated to %q, expected %q.", parts, expected)
+	}
+}
@@ -0,0 +1,328 @@
+// Package rcstore provides persistence for golem's automatically maintained
+// rc files (bookmarks, quickmarks and search engines).
+//
+// Each file is modelled as a sequence of lines, some of which are typed
+// records. Lines which are not records (comments, blank lines, or other
+// commands) are kept verbatim and written back unchanged.
+package rcstore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// maxWriteAttempts is the number of times an update is retried if the file
+// is modified externally while it is being written.
+const maxWriteAttempts = 5
+
+// ErrConcurrentEdit is returned if a file was repeatedly modified externally
+// during an update, and the update could not be written safely.
+var ErrConcurrentEdit = errors.New(
+	"File was modified concurrently; update not written.")
+
+// A Record is a single typed line of an rc file.
+type Record interface {
+	// Fields returns the command name and arguments of the record, as they
+	// should be written to the rc file.
+	Fields() []string
+}
+
+// A Bookmark is a record of a bookmarked uri.
+type Bookmark struct {
+	Title string
+	URI   string
+}
+
+// Fields returns the command name and arguments of the bookmark.
+func (b Bookmark) Fields() []string {
+	return []string{"bm", b.Title, b.URI}
+}
+
+// A Quickmark is a record of a key sequence bound to a uri.
+type Quickmark struct {
+	Keys  string
+	Title string
+	URI   string
+}
+
+// Fields returns the command name and arguments of the quickmark.
+func (q Quickmark) Fields() []string {
+	return []string{"qm", q.Keys, q.Title, q.URI}
+}
+
+// A SearchEngine is a record of a search engine's shorthand, name and
+// format string.
+type SearchEngine struct {
+	Shorthand string
+	Name      string
+	Format    string
+}
+
+// Fields returns the command name and arguments of the search engine.
+func (s SearchEngine) Fields() []string {
+	return []string{"se", s.Shorthand, s.Name, s.Format}
+}
+
+// ParseRecord parses a single line into a record.
+//
+// ok is false if the line is not a record; this includes comments, blank
+// lines, lines which fail to parse and other commands.
+func ParseRecord(line string) (r Record, ok bool) {
+	parts, err := shellwords.Parse(line)
+	if err != nil || len(parts) == 0 {
+		return nil, false
+	}
+	switch parts[0] {
+	case "bm", "bookmark":
+		if len(parts) == 3 {
+			return Bookmark{parts[1], parts[2]}, true
+		}
+	case "qm", "quickmark":
+		if len(parts) == 4 {
+			return Quickmark{parts[1], parts[2], parts[3]}, true
+		}
+	case "se", "searchengine":
+		if len(parts) == 4 {
+			return SearchEngine{parts[1], parts[2], parts[3]}, true
+		}
+	}
+	return nil, false
+}
+
+// FormatRecord formats a record as a line of an rc file.
+func FormatRecord(r Record) string {
+	fields := r.Fields()
+	quoted := make([]string, len(fields))
+	quoted[0] = fields[0]
+	for i, field := range fields[1:] {
+		quoted[i+1] = strconv.Quote(field)
+	}
+	return strings.Join(quoted, "\t")
+}
+
+// A line is a single line of an rc file, and the record it holds (if any).
+type line struct {
+	text   string
+	record Record
+}
+
+// A File is an rc file containing records.
+//
+// All modifications are written atomically, by writing to a temporary file
+// and renaming it over the original. Before every modification the file is
+// re-read, so external edits made since the last access are never lost.
+type File struct {
+	path  string
+	mutex *sync.Mutex
+	lines []line
+	// data is the file content as last read or written.
+	data []byte
+}
+
+// Open opens and parses an rc file.
+//
+// A file which doesn't exist is treated as being empty.
+func Open(path string) (*File, error) {
+	f := &File{path, new(sync.Mutex), nil, nil}
+	data, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	f.parse(data)
+	return f, nil
+}
+
+// Path retrieves the path of the file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Records retrieves all records in the file, in order.
+func (f *File) Records() []Record {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	ret := make([]Record, 0, len(f.lines))
+	for _, l := range f.lines {
+		if l.record != nil {
+			ret = append(ret, l.record)
+		}
+	}
+	return ret
+}
+
+// Reload re-reads the file from disk, if it has been changed externally.
+//
+// It returns whether a change was detected.
+func (f *File) Reload() (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	data, err := f.read()
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(data, f.data) {
+		return false, nil
+	}
+	f.parse(data)
+	return true, nil
+}
+
+// Append appends records to the end of the file.
+func (f *File) Append(rs ...Record) error {
+	return f.update(func(lines []line) []line {
+		for _, r := range rs {
+			lines = append(lines, line{FormatRecord(r), r})
+		}
+		return lines
+	})
+}
+
+// Remove removes all records for which match returns true.
+//
+// It returns the number of records removed.
+func (f *File) Remove(match func(Record) bool) (int, error) {
+	n := 0
+	err := f.update(func(lines []line) []line {
+		n = 0
+		kept := make([]line, 0, len(lines))
+		for _, l := range lines {
+			if l.record != nil && match(l.record) {
+				n++
+				continue
+			}
+			kept = append(kept, l)
+		}
+		return kept
+	})
+	return n, err
+}
+
+// read reads the raw content of the file.
+func (f *File) read() ([]byte, error) {
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return []byte{}, nil
+	}
+	return data, err
+}
+
+// parse replaces the lines of the file with those parsed from data.
+func (f *File) parse(data []byte) {
+	f.data = data
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		f.lines = nil
+		return
+	}
+	split := strings.Split(text, "\n")
+	f.lines = make([]line, len(split))
+	for i, str := range split {
+		r, _ := ParseRecord(str)
+		f.lines[i] = line{str, r}
+	}
+}
+
+// serialize converts lines back into the file's content.
+func serialize(lines []line) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	strs := make([]string, len(lines))
+	for i, l := range lines {
+		strs[i] = l.text
+	}
+	return []byte(strings.Join(strs, "\n") + "\n")
+}
+
+// update applies a modification to the lines of the file and writes it.
+//
+// The file is re-read before the modification is applied. If it changes
+// again while the result is being written, the modification is retried on
+// the new content.
+func (f *File) update(modify func([]line) []line) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i := 0; i < maxWriteAttempts; i++ {
+		data, err := f.read()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, f.data) {
+			f.parse(data)
+		}
+		lines := modify(append([]line(nil), f.lines...))
+		newData := serialize(lines)
+		tmp, err := writeTemp(f.path, newData)
+		if err != nil {
+			return err
+		}
+		// Check the file didn't change while we were working on it.
+		current, err := f.read()
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		if !bytes.Equal(current, data) {
+			os.Remove(tmp)
+			continue
+		}
+		err = os.Rename(tmp, f.path)
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		f.lines = lines
+		f.data = newData
+		return nil
+	}
+	return ErrConcurrentEdit
+}
+
+// WriteFile replaces the contents of the file at path with data. The data is
+// written to a temporary file first, which is then renamed over the file, so
+// the file is never left partially written.
+func WriteFile(path string, data []byte) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// writeTemp writes data to a new temporary file in the same directory as the
+// given path, and returns the temporary file's path.
+func writeTemp(path string, data []byte) (string, error) {
+	tmp, err := ioutil.TempFile(
+		filepath.Dir(path),
+		"."+filepath.Base(path)+".")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
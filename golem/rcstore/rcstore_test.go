@@ -0,0 +1,204 @@
+package rcstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testRecords holds a record of each type.
+var testRecords = []Record{
+	Bookmark{"Go \"home\"", "https://golang.org/"},
+	Quickmark{"gh", "GitHub", "https://github.com/"},
+	SearchEngine{"ddg", "DuckDuckGo", "https://duckduckgo.com/?q=%s"},
+}
+
+// writeTestFile writes an rc file with the given content to a new temporary
+// directory, and returns its path.
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rc")
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	return path
+}
+
+// checkContent checks the content of the file at path.
+func checkContent(t *testing.T, path, expected string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("File content is %q, expected %q.", data, expected)
+	}
+}
+
+func TestRecordsRoundTrip(t *testing.T) {
+	for _, r := range testRecords {
+		parsed, ok := ParseRecord(FormatRecord(r))
+		if !ok || parsed != r {
+			t.Errorf("Record %#v was parsed back as %#v.", r, parsed)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	for _, c := range []struct {
+		line   string
+		record Record
+	}{
+		{"bm Go https://golang.org/", Bookmark{"Go", "https://golang.org/"}},
+		{"bookmark 'A b' c", Bookmark{"A b", "c"}},
+		{"qm gh GitHub https://github.com/",
+			Quickmark{"gh", "GitHub", "https://github.com/"}},
+		{"quickmark a b c", Quickmark{"a", "b", "c"}},
+		{"se ddg DuckDuckGo https://duckduckgo.com/?q=%s",
+			SearchEngine{"ddg", "DuckDuckGo", "https://duckduckgo.com/?q=%s"}},
+		{"searchengine a b c", SearchEngine{"a", "b", "c"}},
+		// Lines which aren't records.
+		{"", nil},
+		{"# bm Go https://golang.org/", nil},
+		{"set webkit:enable-javascript=false", nil},
+		{"bm https://golang.org/", nil},
+		{"qm gh https://github.com/", nil},
+		{"se ddg https://duckduckgo.com/?q=%s", nil},
+		{"bm 'unterminated https://golang.org/", nil},
+	} {
+		r, ok := ParseRecord(c.line)
+		if ok != (c.record != nil) || r != c.record {
+			t.Errorf("Line %q parsed as %#v, %v, expected %#v.",
+				c.line, r, ok, c.record)
+		}
+	}
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rc")
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open missing file: %v", err)
+	}
+	if len(f.Records()) != 0 {
+		t.Errorf("Missing file has records %v.", f.Records())
+	}
+	if err := f.Append(testRecords[0]); err != nil {
+		t.Fatalf("Failed to append to missing file: %v", err)
+	}
+	checkContent(t, path, FormatRecord(testRecords[0])+"\n")
+}
+
+func TestAppendAndRemoveEachRecordType(t *testing.T) {
+	const header = "# Managed by golem.\n\nset foo=bar\n"
+	for _, r := range testRecords {
+		path := writeTestFile(t, header)
+		f, err := Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open file: %v", err)
+		}
+		if err := f.Append(r); err != nil {
+			t.Fatalf("Failed to append %#v: %v", r, err)
+		}
+		checkContent(t, path, header+FormatRecord(r)+"\n")
+		if !reflect.DeepEqual(f.Records(), []Record{r}) {
+			t.Errorf("Records are %v after appending %#v.", f.Records(), r)
+		}
+
+		n, err := f.Remove(func(other Record) bool { return other == r })
+		if err != nil || n != 1 {
+			t.Errorf("Removing %#v removed %d records, error %v.", r, n, err)
+		}
+		// Lines which aren't records are preserved.
+		checkContent(t, path, header)
+	}
+}
+
+func TestRemoveKeepsOrder(t *testing.T) {
+	path := writeTestFile(t,
+		"bm a 1\n# comment\nqm k b 2\nse s c 3\nbm d 4\n")
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	n, err := f.Remove(func(r Record) bool {
+		_, ok := r.(Bookmark)
+		return ok
+	})
+	if err != nil || n != 2 {
+		t.Errorf("Removing bookmarks removed %d records, error %v.", n, err)
+	}
+	checkContent(t, path, "# comment\nqm k b 2\nse s c 3\n")
+	expected := []Record{Quickmark{"k", "b", "2"}, SearchEngine{"s", "c", "3"}}
+	if !reflect.DeepEqual(f.Records(), expected) {
+		t.Errorf("Records are %v, expected %v.", f.Records(), expected)
+	}
+}
+
+func TestExternalEditsArePreserved(t *testing.T) {
+	path := writeTestFile(t, "bm a 1\n")
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	err = ioutil.WriteFile(path, []byte("bm a 1\nbm b 2\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to edit file: %v", err)
+	}
+	if err := f.Append(Bookmark{"c", "3"}); err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+	checkContent(t, path,
+		"bm a 1\nbm b 2\n"+FormatRecord(Bookmark{"c", "3"})+"\n")
+}
+
+func TestReload(t *testing.T) {
+	path := writeTestFile(t, "bm a 1\n")
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	if changed, err := f.Reload(); changed || err != nil {
+		t.Errorf("Reloading an unchanged file returned %v, %v.", changed, err)
+	}
+	err = ioutil.WriteFile(path, []byte("qm k b 2\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to edit file: %v", err)
+	}
+	if changed, err := f.Reload(); !changed || err != nil {
+		t.Errorf("Reloading a changed file returned %v, %v.", changed, err)
+	}
+	expected := []Record{Quickmark{"k", "b", "2"}}
+	if !reflect.DeepEqual(f.Records(), expected) {
+		t.Errorf("Records are %v after reloading, expected %v.",
+			f.Records(), expected)
+	}
+	// A file removed externally is empty.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if changed, err := f.Reload(); !changed || err != nil {
+		t.Errorf("Reloading a removed file returned %v, %v.", changed, err)
+	}
+	if len(f.Records()) != 0 {
+		t.Errorf("Removed file has records %v.", f.Records())
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := writeTestFile(t, "old content\n")
+	if err := WriteFile(path, []byte("new content\n")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	checkContent(t, path, "new content\n")
+	// No temporary files are left behind.
+	infos, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Errorf("Directory contains %d files, expected 1.", len(infos))
+	}
+}
@@ -0,0 +1,60 @@
+package golem
+
+import (
+	"bytes"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchRcFiles watches the rc files for changes, and sends the path of any
+// changed file to the returned channel.
+//
+// The config directory is watched rather than the files themselves, as
+// editors and golem itself typically replace files instead of writing to
+// them.
+func (g *Golem) watchRcFiles() (<-chan string, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	_, err = syscall.InotifyAddWatch(
+		fd,
+		g.files.configDir,
+		syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO|syscall.IN_CREATE)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	watched := make(map[string]string)
+	for _, file := range g.files.rcFiles() {
+		watched[filepath.Base(file)] = file
+	}
+
+	changes := make(chan string)
+	go func() {
+		defer syscall.Close(fd)
+		defer close(changes)
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := syscall.Read(fd, buf)
+			if err == syscall.EINTR {
+				continue
+			} else if err != nil {
+				(*Window)(nil).logErrorf("Failed to watch rc files: %v", err)
+				return
+			}
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				nameStart := off + syscall.SizeofInotifyEvent
+				nameEnd := nameStart + int(event.Len)
+				off = nameEnd
+				name := string(bytes.TrimRight(buf[nameStart:nameEnd], "\x00"))
+				if file, ok := watched[name]; ok {
+					changes <- file
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
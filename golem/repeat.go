@@ -0,0 +1,98 @@
+package golem
+
+import "github.com/tkerber/golem/cmd"
+
+// unrepeatableBuiltins are the builtins which are not recorded as the last
+// change to repeat. Most of these start another mode, in which case the
+// command finally run is recorded instead.
+var unrepeatableBuiltins = map[string]bool{
+	"backgroundEditURI":   true,
+	"backgroundOpen":      true,
+	"caretMode":           true,
+	"commandMode":         true,
+	"editURI":             true,
+	"hintsBackground":     true,
+	"hintsDownload":       true,
+	"hintsFocusInput":     true,
+	"hintsFollow":         true,
+	"hintsHover":          true,
+	"hintsImage":          true,
+	"hintsRapid":          true,
+	"hintsScrollTarget":   true,
+	"hintsTab":            true,
+	"hintsTextFilter":     true,
+	"hintsWindow":         true,
+	"hintsYankText":       true,
+	"hintsYankURI":        true,
+	"insertMode":          true,
+	"macroRecord":         true,
+	"macroReplay":         true,
+	"markJump":            true,
+	"markJumpLine":        true,
+	"markSet":             true,
+	"normalMode":          true,
+	"nop":                 true,
+	"open":                true,
+	"passThroughMode":     true,
+	"quickmarks":          true,
+	"quickmarksRapid":     true,
+	"quickmarksTab":       true,
+	"quickmarksWindow":    true,
+	"repeat":              true,
+	"searchMode":          true,
+	"searchModeBackwards": true,
+	"tabEditURI":          true,
+	"tabOpen":             true,
+	"windowEditURI":       true,
+	"windowOpen":          true,
+}
+
+// repeatableBindings wraps bindings, so that executing them records them as
+// the last change to repeat.
+func (w *Window) repeatableBindings(bindings []*cmd.Binding) []*cmd.Binding {
+	ret := make([]*cmd.Binding, len(bindings))
+	for i, b := range bindings {
+		if unrepeatableBuiltins[b.Name] {
+			ret[i] = b
+			continue
+		}
+		to := b.To
+		ret[i] = &cmd.Binding{
+			b.From,
+			func(keys []cmd.Key, n *int, st cmd.Substate) {
+				w.setLastChange(func(override *int) {
+					if override != nil {
+						to(keys, override, st)
+					} else {
+						to(keys, n, st)
+					}
+				})
+				to(keys, n, st)
+			},
+			b.Name,
+			b.Desc,
+		}
+	}
+	return ret
+}
+
+// setLastChange sets the function run to repeat the last change. It is
+// passed the count given to the repeat, if any.
+func (w *Window) setLastChange(f func(*int)) {
+	w.wMutex.Lock()
+	defer w.wMutex.Unlock()
+	w.lastChange = f
+}
+
+// builtinRepeat repeats the last binding or command. A count replaces the
+// count the binding was originally run with.
+func (w *Window) builtinRepeat(n *int) {
+	w.wMutex.Lock()
+	f := w.lastChange
+	w.wMutex.Unlock()
+	if f == nil {
+		w.logError("No change to repeat.")
+		return
+	}
+	f(n)
+}
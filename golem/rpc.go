@@ -2,20 +2,30 @@ package golem
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
-	"math"
 	"net"
 	"net/rpc"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/mattn/go-shellwords"
+	"github.com/tkerber/golem/api"
 	"github.com/tkerber/golem/cmd"
 	"github.com/tkerber/golem/golem/states"
 	"github.com/ugorji/go/codec"
 )
 
-const HintsChars = "FDSARTGBVECWXQZIOPMNHYULKJ"
-
 type Nothing struct{}
 
 // A RPCSession manages listening on golems RPC socket, as well as serving
@@ -24,13 +34,20 @@ type RPCSession struct {
 	listener net.Listener
 	closed   bool
 	golem    *Golem
+	// events distributes events to the subscribers of the public API.
+	events *eventHub
+	// token is the random token remote control clients present, which is
+	// written to the token file next to the socket.
+	token string
 }
 
 func NewRPCSession(l net.Listener) *RPCSession {
-	s := &RPCSession{l, false, nil}
-	server := rpc.NewServer()
-	server.RegisterName("Golem", s)
-	msgpackHandle := new(codec.MsgpackHandle)
+	s := &RPCSession{l, false, nil, newEventHub(), ""}
+	// Remote control clients may make all calls, web extensions only those
+	// they need. (see extensionSession)
+	clientServer := rpc.NewServer()
+	clientServer.RegisterName("Golem", s)
+	clientServer.RegisterName("GolemAPI", &apiSession{s})
 	go func() {
 		for {
 			c, err := s.listener.Accept()
@@ -43,61 +60,183 @@ func NewRPCSession(l net.Listener) *RPCSession {
 					continue
 				}
 			}
-			// serve the new connection.
-			go func() {
-				// One of the following handshakes is performed:
-				//
-				// <<< msgpack-rpc-server\0
-				// >>> ok\0
-				//
-				// <<< msgpack-rpc-client\0
-				// >>> ok\0
-				//
-				// These idicate that the client is to be treated as the RPC
-				// server or client respectively.
-				//
-				// The handshake occurs using plain text instead of, say,
-				// message pack due to simplicity.
-				reader := bufio.NewReader(c)
-				line, err := reader.ReadBytes(0)
-				if err != nil {
-					Errlog.Printf(
-						"Failed to initialize socket connection: %v", err)
-				} else if string(line) == "msgpack-rpc-server\u0000" {
-					c.Write([]byte("ok\u0000"))
-					// The "client" is a web view, and will act as a server.
-					// connect to it.
-					client := rpc.NewClientWithCodec(
-						codec.MsgpackSpecRpc.ClientCodec(c, msgpackHandle))
-					var id uint64
-					// Try getting a web page id. If this call fails, assume that
-					// the connecting client isn't a web extension.
-					err := client.Call("GolemWebExtension.GetPageID", nil, &id)
-					if err == nil {
-						wv, ok := s.golem.webViews[id]
-						if !ok {
-							Errlog.Println(
-								"Failed to resolve web extension: No such ID")
-							return
-						}
-						wv.webExtension.conn = c
-						wv.webExtension.client = client
-					}
-				} else if string(line) == "msgpack-rpc-client\u0000" {
-					// Serve the client.
-					go server.ServeCodec(
-						codec.MsgpackSpecRpc.ServerCodec(c, msgpackHandle))
-					c.Write([]byte("ok\u0000"))
-				} else {
-					Errlog.Printf(
-						"Invalid introduction header: '%s'", string(line))
-				}
-			}()
+			go s.serve(c, clientServer)
 		}
 	}()
 	return s
 }
 
+// serve serves a new connection to golem's socket.
+//
+// One of the following handshakes is performed:
+//
+// <<< msgpack-rpc-server\0
+// >>> ok\0
+//
+// <<< msgpack-rpc-extension:page-id\0
+// >>> ok\0
+//
+// These are used by web extensions, which are treated as the RPC server or
+// client respectively. The RPC server claims the page whose ID it returns
+// from GetPageID, unless another process which is still connected already
+// claimed it. The RPC client may only make calls concerning the page it
+// names, and only once its process claimed that page.
+//
+// <<< msgpack-rpc-client[:token]\0
+// >>> ok\0
+//
+// This indicates that the client is a remote control client, and is to be
+// treated as the RPC client.
+//
+// <<< msgpack-events[:token]\0
+// >>> ok\0
+//
+// This indicates that the client subscribes to events of the public API. (see
+// the api package)
+//
+// Remote control clients must present either the token written to the token
+// file, or the remote-token setting if it is set. Web extensions can't
+// connect as remote control clients without reading either. If a handshake
+// is not accepted, golem replies with "denied\0" instead, and closes the
+// connection. Connections from other users are always closed.
+//
+// The handshake occurs using plain text instead of, say, message pack due to
+// simplicity.
+func (s *RPCSession) serve(c net.Conn, clientServer *rpc.Server) {
+	cred, err := peerCredentials(c)
+	if err != nil {
+		Errlog.Printf("Failed to retrieve peer credentials: %v", err)
+		c.Close()
+		return
+	} else if int(cred.Uid) != os.Getuid() {
+		Errlog.Printf("Rejected connection from user %d.", cred.Uid)
+		c.Close()
+		return
+	}
+	deny := func(reason string) {
+		Errlog.Printf("Rejected connection from process %d: %s",
+			cred.Pid, reason)
+		c.Write([]byte("denied\u0000"))
+		c.Close()
+	}
+
+	msgpackHandle := new(codec.MsgpackHandle)
+	reader := bufio.NewReader(c)
+	line, err := reader.ReadBytes(0)
+	if err != nil {
+		Errlog.Printf("Failed to initialize socket connection: %v", err)
+		c.Close()
+		return
+	}
+	header := strings.SplitN(
+		strings.TrimSuffix(string(line), "\u0000"), ":", 2)
+	token := ""
+	if len(header) == 2 {
+		token = header[1]
+	}
+	switch header[0] {
+	case "msgpack-rpc-server":
+		c.Write([]byte("ok\u0000"))
+		// The "client" is a web view, and will act as a server.
+		// connect to it.
+		client := rpc.NewClientWithCodec(
+			codec.MsgpackSpecRpc.ClientCodec(c, msgpackHandle))
+		var id uint64
+		// Try getting a web page id. If this call fails, assume that
+		// the connecting client isn't a web extension.
+		err := client.Call("GolemWebExtension.GetPageID", nil, &id)
+		if err != nil {
+			return
+		}
+		if s.golem == nil {
+			Errlog.Println("Failed to resolve web extension: Golem is not " +
+				"yet initialized")
+			client.Close()
+			return
+		}
+		s.golem.wMutex.Lock()
+		wv, ok := s.golem.webViews[id]
+		s.golem.wMutex.Unlock()
+		if !ok {
+			Errlog.Println("Failed to resolve web extension: No such ID")
+			client.Close()
+			return
+		}
+		// A page process which was replaced reconnects with the same ID,
+		// replacing the old connection once it is lost.
+		if !wv.webExtension.claim(c, client, cred.Pid) {
+			Errlog.Printf("Rejected connection from process %d: Page %d "+
+				"is claimed by another process.", cred.Pid, id)
+			client.Close()
+		}
+	case "msgpack-rpc-extension":
+		id, err := strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			deny("Invalid page ID.")
+			return
+		}
+		// Each web extension is served separately, to restrict it to its
+		// own page.
+		extensionServer := rpc.NewServer()
+		extensionServer.RegisterName(
+			"Golem",
+			&extensionSession{s, id, cred.Pid})
+		go extensionServer.ServeCodec(
+			codec.MsgpackSpecRpc.ServerCodec(c, msgpackHandle))
+		c.Write([]byte("ok\u0000"))
+	case "msgpack-rpc-client", "msgpack-events":
+		if err := s.checkToken(token); err != nil {
+			deny(err.Error())
+			return
+		}
+		if header[0] == "msgpack-events" {
+			c.Write([]byte("ok\u0000"))
+			s.events.serve(c, reader)
+			return
+		}
+		// Serve the client.
+		go clientServer.ServeCodec(
+			codec.MsgpackSpecRpc.ServerCodec(c, msgpackHandle))
+		c.Write([]byte("ok\u0000"))
+	default:
+		Errlog.Printf("Invalid introduction header: '%s'", string(line))
+		c.Close()
+	}
+}
+
+// checkToken checks the token presented by a remote control client against
+// golem's own token and the remote-token setting.
+func (s *RPCSession) checkToken(token string) error {
+	if s.golem == nil {
+		return errors.New("Golem is not yet initialized.")
+	}
+	for _, expected := range []string{s.token, s.golem.remoteToken} {
+		if expected != "" && subtle.ConstantTimeCompare(
+			[]byte(token),
+			[]byte(expected)) == 1 {
+
+			return nil
+		}
+	}
+	return errors.New("Invalid token.")
+}
+
+// writeToken generates golem's own token for remote control clients, and
+// writes it to the token file next to the socket, readable only by the user.
+func (s *RPCSession) writeToken() error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	s.token = hex.EncodeToString(b)
+	path := api.TokenFile(s.listener.Addr().String())
+	// A token file left behind may have been created with other permissions.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(s.token), 0600)
+}
+
 // NewWindow creates a new window in golem's main process.
 func (s *RPCSession) NewWindow(args *Nothing, ret *Nothing) error {
 	_, err := s.golem.NewWindow("")
@@ -125,6 +264,93 @@ func (s *RPCSession) NewTabs(uris []string, ret *Nothing) error {
 	return nil
 }
 
+// An extensionSession is the exported RPC object for web extensions. It
+// exposes only the calls web extensions make, and only for the page the web
+// extension connected for.
+type extensionSession struct {
+	session *RPCSession
+	// id is the ID of the page named in the handshake.
+	id uint64
+	// pid is the ID of the connected process.
+	pid int32
+}
+
+// checkPage checks that a call of a web extension concerns its own page, and
+// that its process is the one connected as that page's web extension.
+func (s *extensionSession) checkPage(id uint64) error {
+	if id != s.id {
+		return fmt.Errorf("Web extension for page %d may not access page %d.",
+			s.id, id)
+	}
+	if s.session.golem == nil {
+		return errors.New("Golem is not yet initialized.")
+	}
+	s.session.golem.wMutex.Lock()
+	wv, ok := s.session.golem.webViews[id]
+	s.session.golem.wMutex.Unlock()
+	if !ok {
+		return errors.New("Invalid web page id recieved.")
+	}
+	if !wv.webExtension.claimedBy(s.pid) {
+		return fmt.Errorf("Page %d is not claimed by process %d.", id, s.pid)
+	}
+	return nil
+}
+
+// Blocks checks whether a uri is blocked by the adblocker or not.
+func (s *extensionSession) Blocks(bq BlockQuery, ret *bool) error {
+	return s.session.Blocks(bq, ret)
+}
+
+// DomainElemHideCSS retrieves the css string to hide the elements on a given
+// domain.
+func (s *extensionSession) DomainElemHideCSS(domain string, ret *string) error {
+	return s.session.DomainElemHideCSS(domain, ret)
+}
+
+// GetHintsLabels gets labels for the elements to be hinted in a web view.
+func (s *extensionSession) GetHintsLabels(
+	hlr HintsLabelsRequest,
+	ret *[]string) error {
+
+	if err := s.checkPage(hlr.Id); err != nil {
+		return err
+	}
+	return s.session.GetHintsLabels(hlr, ret)
+}
+
+// HintCall is called if a hint was hit.
+func (s *extensionSession) HintCall(hcr HintCallRequest, ret *bool) error {
+	if err := s.checkPage(hcr.Id); err != nil {
+		return err
+	}
+	return s.session.HintCall(hcr, ret)
+}
+
+// VerticalPositionChanged is called to signal a change in the vertical
+// position of a web view.
+func (s *extensionSession) VerticalPositionChanged(
+	vpc VerticalPositionChange,
+	ret *Nothing) error {
+
+	if err := s.checkPage(vpc.Id); err != nil {
+		return err
+	}
+	return s.session.VerticalPositionChanged(vpc, ret)
+}
+
+// InputFocusChanged is called to signal a change in the input focus of a web
+// view.
+func (s *extensionSession) InputFocusChanged(
+	ifc InputFocusChange,
+	ret *Nothing) error {
+
+	if err := s.checkPage(ifc.Id); err != nil {
+		return err
+	}
+	return s.session.InputFocusChanged(ifc, ret)
+}
+
 // A BlockQuery encapsulates all the arguments for querying the blocked
 // status of a website.
 type BlockQuery struct {
@@ -146,23 +372,29 @@ func (s *RPCSession) DomainElemHideCSS(domain string, ret *string) error {
 	return nil
 }
 
-// GetHintsLabels gets n labels for hints.
-func (s *RPCSession) GetHintsLabels(n int64, ret *[]string) error {
-	*ret = make([]string, n)
-	if n == 0 {
-		return nil
-	}
-	length := int(math.Ceil(
-		math.Log(float64(n)) / math.Log(float64(len(HintsChars)))))
-	for i := range *ret {
-		bytes := make([]byte, length)
-		divI := i
-		for j := range bytes {
-			bytes[j] = HintsChars[divI%len(HintsChars)]
-			divI /= len(HintsChars)
-		}
-		(*ret)[i] = string(bytes)
+// A HintsLabelsRequest is a request to label the elements to be hinted in a
+// web view, given the size of its viewport.
+type HintsLabelsRequest struct {
+	Id         uint64
+	Width      float64
+	Height     float64
+	Candidates []hintCandidate
+}
+
+// GetHintsLabels gets labels for the elements to be hinted in a web view.
+func (s *RPCSession) GetHintsLabels(
+	hlr HintsLabelsRequest,
+	ret *[]string) error {
+
+	wv, ok := s.golem.webViews[hlr.Id]
+	if !ok {
+		return errors.New("Invalid web page id recieved.")
 	}
+	hints := s.golem.newHintsSet(hlr.Width, hlr.Height, hlr.Candidates)
+	wv.hintsMutex.Lock()
+	wv.hints = hints
+	wv.hintsMutex.Unlock()
+	*ret = hints.ids
 	return nil
 }
 
@@ -189,13 +421,7 @@ func (g *RPCSession) HintCall(hcr HintCallRequest, ret *bool) error {
 	}
 	*ret = hm.ExecuterFunction(hcr.Uri)
 	if *ret == true {
-		w.setState(&states.HintsMode{
-			hm.StateIndependant,
-			hm.Substate,
-			hm.HintsCallback,
-			nil,
-			hm.ExecuterFunction,
-		})
+		w.setState(hm.WithoutKeys())
 	}
 	return nil
 }
@@ -247,8 +473,13 @@ func (g *RPCSession) InputFocusChanged(
 	//
 	// Otherwise, if the window is currently in insert mode and it's
 	// newly unfocused, set this webview to normal mode.
+	//
+	// Pass-through mode is unaffected by either.
 	for _, w := range g.golem.windows {
 		if wv == w.getWebView() {
+			if _, ok := w.State.(*cmd.PassThroughMode); ok {
+				continue
+			}
 			if ifc.Focused {
 				w.setState(
 					cmd.NewInsertMode(w.State, cmd.SubstateDefault))
@@ -261,20 +492,170 @@ func (g *RPCSession) InputFocusChanged(
 	return nil
 }
 
+// webExtensionTimeout is the time a call to a web extension may take,
+// including waiting for the web extension to connect, before it fails.
+const webExtensionTimeout = 3 * time.Second
+
+// A webExtension is the connection to the web extension of a web view's
+// page process.
 type webExtension struct {
 	conn   net.Conn
 	client *rpc.Client
+	// pid is the ID of the process connected as the web extension.
+	pid int32
+	// ready is closed once a web extension is connected. It is replaced if
+	// the connection is lost.
+	ready chan struct{}
+	mutex *sync.Mutex
 }
 
-func (w *webExtension) call(
+// newWebExtension creates a webExtension, which is not yet connected.
+func newWebExtension() *webExtension {
+	return &webExtension{nil, nil, 0, make(chan struct{}), new(sync.Mutex)}
+}
+
+// claim attaches a web extension connecting from a process, unless another
+// process is still connected as the web extension.
+//
+// Returns whether the web extension was attached.
+func (w *webExtension) claim(
+	conn net.Conn,
+	client *rpc.Client,
+	pid int32) bool {
+
+	w.mutex.Lock()
+	prev, prevPid := w.client, w.pid
+	w.mutex.Unlock()
+	if prev != nil && prevPid != pid && w.alive(prev) {
+		return false
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	// Another process may have claimed the page in the meantime.
+	if w.client != prev {
+		return false
+	}
+	w.attachLocked(conn, client, pid)
+	return true
+}
+
+// alive checks if a connection to a web extension still responds.
+func (w *webExtension) alive(client *rpc.Client) bool {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		webExtensionTimeout)
+	defer cancel()
+	var id uint64
+	call := client.Go(
+		"GolemWebExtension.GetPageID",
+		nil,
+		&id,
+		make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// claimedBy checks if a process is connected as the web extension.
+func (w *webExtension) claimedBy(pid int32) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.client != nil && w.pid == pid
+}
+
+// attachLocked connects a web extension, replacing any previous connection.
+// The mutex must be held.
+func (w *webExtension) attachLocked(
+	conn net.Conn,
+	client *rpc.Client,
+	pid int32) {
+
+	if w.client != nil {
+		w.client.Close()
+	} else {
+		close(w.ready)
+	}
+	w.conn = conn
+	w.client = client
+	w.pid = pid
+}
+
+// detach marks a connection to the web extension as lost, if it is still
+// the current one. If client is nil, the current connection is detached.
+//
+// Calls made after this wait for the web extension to reconnect.
+func (w *webExtension) detach(client *rpc.Client) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.client == nil || (client != nil && client != w.client) {
+		return
+	}
+	w.client.Close()
+	w.conn = nil
+	w.client = nil
+	w.pid = 0
+	w.ready = make(chan struct{})
+}
+
+// connection retrieves the client connected to the web extension, waiting
+// for the web extension to connect if necessary.
+func (w *webExtension) connection(ctx context.Context) (*rpc.Client, error) {
+	for {
+		w.mutex.Lock()
+		client, ready := w.client, w.ready
+		w.mutex.Unlock()
+		if client != nil {
+			return client, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, errors.New("Failed RPC call: web view not connected.")
+		}
+	}
+}
+
+// callContext calls a method of the web extension, failing if the context
+// is done before the call completes.
+func (w *webExtension) callContext(
+	ctx context.Context,
 	method string,
 	args interface{},
 	reply interface{}) error {
 
-	if w.client == nil {
-		return errors.New("Failed RPC call: web view not connected.")
+	client, err := w.connection(ctx)
+	if err != nil {
+		return err
+	}
+	call := client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error == rpc.ErrShutdown ||
+			call.Error == io.ErrUnexpectedEOF {
+
+			w.detach(client)
+		}
+		return call.Error
+	case <-ctx.Done():
+		return fmt.Errorf("Failed RPC call %s: %v", method, ctx.Err())
 	}
-	return w.client.Call(method, args, reply)
+}
+
+// call calls a method of the web extension, failing after
+// webExtensionTimeout.
+func (w *webExtension) call(
+	method string,
+	args interface{},
+	reply interface{}) error {
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		webExtensionTimeout)
+	defer cancel()
+	return w.callContext(ctx, method, args, reply)
 }
 
 // LinkHintsMode initializes hints mode for links.
@@ -298,6 +679,61 @@ func (w *webExtension) ClickHintsMode() (int64, error) {
 	return ret, err
 }
 
+// LinkTextHintsMode initializes hints mode for links, yielding their text.
+func (w *webExtension) LinkTextHintsMode() (int64, error) {
+	var ret int64
+	err := w.call("GolemWebExtension.LinkTextHintsMode", nil, &ret)
+	return ret, err
+}
+
+// ImageHintsMode initializes hints mode for images.
+func (w *webExtension) ImageHintsMode() (int64, error) {
+	var ret int64
+	err := w.call("GolemWebExtension.ImageHintsMode", nil, &ret)
+	return ret, err
+}
+
+// InputHintsMode initializes hints mode for text input fields.
+func (w *webExtension) InputHintsMode() (int64, error) {
+	var ret int64
+	err := w.call("GolemWebExtension.InputHintsMode", nil, &ret)
+	return ret, err
+}
+
+// HoverHintsMode initializes hints mode for hoverable elements.
+func (w *webExtension) HoverHintsMode() (int64, error) {
+	var ret int64
+	err := w.call("GolemWebExtension.HoverHintsMode", nil, &ret)
+	return ret, err
+}
+
+// ScrollTargetHintsMode initializes hints mode for scrollable containers.
+func (w *webExtension) ScrollTargetHintsMode() (int64, error) {
+	var ret int64
+	err := w.call("GolemWebExtension.ScrollTargetHintsMode", nil, &ret)
+	return ret, err
+}
+
+// relabelHints displays only the hints with the given ids, with the given
+// labels.
+func (w *webExtension) relabelHints(ids, labels []string) (int64, error) {
+	var ret int64
+	err := w.call(
+		"GolemWebExtension.ShowHints",
+		codec.MsgpackSpecRpcMultiArgs{ids, labels},
+		&ret)
+	return ret, err
+}
+
+// ExecuteHint executes the hint with the given id.
+//
+// It returns true if hints mode ended as a result.
+func (w *webExtension) ExecuteHint(id string) (bool, error) {
+	var ret bool
+	err := w.call("GolemWebExtension.ExecuteHint", id, &ret)
+	return ret, err
+}
+
 // EndHintsMode ends hints mode.
 func (w *webExtension) EndHintsMode() error {
 	return w.call("GolemWebExtension.EndHintsMode", nil, nil)
@@ -312,6 +748,63 @@ func (w *webExtension) FilterHintsMode(filter string) (bool, error) {
 	return ret, err
 }
 
+// StartCaret places the caret at the top left of the viewport, unless a
+// selection exists already.
+//
+// It returns false if the page has no document to place the caret in.
+func (w *webExtension) StartCaret() (bool, error) {
+	var ret bool
+	err := w.call("GolemWebExtension.StartCaret", nil, &ret)
+	return ret, err
+}
+
+// ModifySelection moves the caret, or extends the selection, as
+// window.getSelection().modify() does.
+//
+// It returns false if there is no caret or selection to modify.
+func (w *webExtension) ModifySelection(
+	alter, direction, granularity string) (bool, error) {
+
+	var ret bool
+	err := w.call(
+		"GolemWebExtension.ModifySelection",
+		codec.MsgpackSpecRpcMultiArgs{alter, direction, granularity},
+		&ret)
+	return ret, err
+}
+
+// GetSelection retrieves the text of the current selection.
+func (w *webExtension) GetSelection() (string, error) {
+	var ret string
+	err := w.call("GolemWebExtension.GetSelection", nil, &ret)
+	return ret, err
+}
+
+// GetPageHTML retrieves the HTML source of the page, as currently displayed.
+func (w *webExtension) GetPageHTML() (string, error) {
+	var ret string
+	err := w.call("GolemWebExtension.GetPageHTML", nil, &ret)
+	return ret, err
+}
+
+// GetPageText retrieves the rendered text of the page.
+func (w *webExtension) GetPageText() (string, error) {
+	var ret string
+	err := w.call("GolemWebExtension.GetPageText", nil, &ret)
+	return ret, err
+}
+
+// CollapseSelection collapses the selection to its focus, leaving only the
+// caret.
+func (w *webExtension) CollapseSelection() error {
+	return w.call("GolemWebExtension.CollapseSelection", nil, nil)
+}
+
+// EndCaret removes the caret and any selection.
+func (w *webExtension) EndCaret() error {
+	return w.call("GolemWebExtension.EndCaret", nil, nil)
+}
+
 // getInt64 retrieves an int64 value.
 func (w *webExtension) getInt64(name string) (int64, error) {
 	var ret int64
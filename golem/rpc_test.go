@@ -0,0 +1,373 @@
+package golem
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tkerber/golem/api"
+	"github.com/ugorji/go/codec"
+)
+
+// fakeWebExtension is the web extension of a page, served in process.
+type fakeWebExtension struct {
+	id uint64
+}
+
+// GetPageID retrieves the ID of the page.
+func (e *fakeWebExtension) GetPageID(_ *Nothing, ret *uint64) error {
+	*ret = e.id
+	return nil
+}
+
+// serveFakeWebExtension serves a fake web extension over a pipe. It returns
+// golem's end of the pipe, the client connected to the web extension, and the
+// web extension's end of the pipe, which may be closed to disconnect it.
+func serveFakeWebExtension(
+	ext interface{}) (net.Conn, *rpc.Client, net.Conn) {
+
+	golemEnd, extEnd := net.Pipe()
+	h := new(codec.MsgpackHandle)
+	server := rpc.NewServer()
+	server.RegisterName("GolemWebExtension", ext)
+	go server.ServeCodec(codec.MsgpackSpecRpc.ServerCodec(extEnd, h))
+	client := rpc.NewClientWithCodec(
+		codec.MsgpackSpecRpc.ClientCodec(golemEnd, h))
+	return golemEnd, client, extEnd
+}
+
+// newTestGolem creates a golem with only what its socket needs, and the
+// given web views.
+func newTestGolem(token string, ids ...uint64) *Golem {
+	g := &Golem{
+		globalCfg:    &globalCfg{remoteToken: token},
+		webViews:     make(map[uint64]*webView),
+		wMutex:       new(sync.Mutex),
+		historyMutex: new(sync.Mutex),
+	}
+	for _, id := range ids {
+		g.webViews[id] = &webView{
+			webExtension: newWebExtension(),
+			id:           id,
+		}
+	}
+	return g
+}
+
+// listenTest serves golem's socket in a temporary directory, and returns
+// its path. g may be nil, for a golem which is not yet initialized.
+func listenTest(t *testing.T, g *Golem) string {
+	path := filepath.Join(t.TempDir(), "golem-test")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewRPCSession(l)
+	if err := s.writeToken(); err != nil {
+		t.Fatal(err)
+	}
+	s.golem = g
+	if g != nil {
+		g.RPCSession = s
+	}
+	t.Cleanup(func() {
+		s.closed = true
+		l.Close()
+	})
+	return path
+}
+
+// tokenTest reads the token of golem's socket from its token file.
+func tokenTest(t *testing.T, path string) string {
+	token, err := api.ReadToken(path)
+	if err != nil {
+		t.Fatalf("Failed to read token: %v", err)
+	}
+	return token
+}
+
+// handshakeTest performs a handshake with golem's socket, and returns the
+// reply. An empty reply means the connection was closed.
+func handshakeTest(t *testing.T, path, header string) string {
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte(header + "\u0000")); err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := bufio.NewReader(c).ReadString(0)
+	return reply
+}
+
+func TestServeChecksToken(t *testing.T) {
+	path := listenTest(t, newTestGolem("secret"))
+	token := tokenTest(t, path)
+	for _, c := range []struct {
+		header string
+		reply  string
+	}{
+		{"msgpack-rpc-client", "denied\u0000"},
+		{"msgpack-rpc-client:wrong", "denied\u0000"},
+		{"msgpack-events:secre", "denied\u0000"},
+		{"msgpack-rpc-client:secret", "ok\u0000"},
+		{"msgpack-events:secret", "ok\u0000"},
+		// Golem's own token is accepted alongside the remote-token setting.
+		{"msgpack-rpc-client:" + token, "ok\u0000"},
+		{"msgpack-events:" + token, "ok\u0000"},
+	} {
+		if reply := handshakeTest(t, path, c.header); reply != c.reply {
+			t.Errorf("Handshake '%s' got reply %q, expected %q.",
+				c.header, reply, c.reply)
+		}
+	}
+}
+
+func TestServeRequiresTokenByDefault(t *testing.T) {
+	path := listenTest(t, newTestGolem(""))
+	token := tokenTest(t, path)
+	for _, c := range []struct {
+		header string
+		reply  string
+	}{
+		{"msgpack-rpc-client", "denied\u0000"},
+		{"msgpack-rpc-client:", "denied\u0000"},
+		{"msgpack-events", "denied\u0000"},
+		{"msgpack-rpc-client:" + token, "ok\u0000"},
+	} {
+		if reply := handshakeTest(t, path, c.header); reply != c.reply {
+			t.Errorf("Handshake '%s' got reply %q, expected %q.",
+				c.header, reply, c.reply)
+		}
+	}
+	info, err := os.Stat(api.TokenFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Token file has permissions %o, expected 600.", perm)
+	}
+}
+
+func TestServeRejectsInvalidHeaders(t *testing.T) {
+	path := listenTest(t, newTestGolem(""))
+	for _, c := range []struct {
+		header string
+		reply  string
+	}{
+		{"nonsense", ""},
+		{"msgpack-rpc-extension", "denied\u0000"},
+		{"msgpack-rpc-extension:page", "denied\u0000"},
+	} {
+		if reply := handshakeTest(t, path, c.header); reply != c.reply {
+			t.Errorf("Handshake '%s' got reply %q, expected %q.",
+				c.header, reply, c.reply)
+		}
+	}
+}
+
+func TestClaimRejectsConnectedWebExtension(t *testing.T) {
+	w := newWebExtension()
+	conn, client, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	if !w.claim(conn, client, 100) {
+		t.Fatal("Failed to claim an unclaimed page.")
+	}
+	conn2, client2, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	if w.claim(conn2, client2, 200) {
+		t.Error("Claimed a page connected to another process.")
+	}
+	if !w.claimedBy(100) || w.claimedBy(200) {
+		t.Error("Page changed owner after a rejected claim.")
+	}
+	// The owning process may reconnect.
+	conn3, client3, ext := serveFakeWebExtension(&fakeWebExtension{1})
+	if !w.claim(conn3, client3, 100) {
+		t.Error("Failed to reconnect the owning process.")
+	}
+	// Once the owner is gone, another process may claim the page.
+	ext.Close()
+	conn4, client4, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	if !w.claim(conn4, client4, 200) {
+		t.Error("Failed to claim a page whose process is gone.")
+	}
+	if !w.claimedBy(200) {
+		t.Error("Page not owned by the process which claimed it.")
+	}
+}
+
+func TestExtensionSessionRejectsOtherPages(t *testing.T) {
+	g := newTestGolem("", 1, 2)
+	s := &RPCSession{nil, false, g, newEventHub(), ""}
+	pid := int32(os.Getpid())
+	conn, client, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	g.webViews[1].webExtension.claim(conn, client, pid)
+
+	own := &extensionSession{s, 1, pid}
+	var nothing Nothing
+	var ok bool
+	var labels []string
+	if err := own.InputFocusChanged(
+		InputFocusChange{2, true}, &nothing); err == nil {
+
+		t.Error("InputFocusChanged accepted another page's ID.")
+	}
+	if err := own.VerticalPositionChanged(
+		VerticalPositionChange{2, 10, 20}, &nothing); err == nil {
+
+		t.Error("VerticalPositionChanged accepted another page's ID.")
+	}
+	if err := own.HintCall(HintCallRequest{2, ""}, &ok); err == nil {
+		t.Error("HintCall accepted another page's ID.")
+	}
+	if err := own.GetHintsLabels(
+		HintsLabelsRequest{2, 100, 100, nil}, &labels); err == nil {
+
+		t.Error("GetHintsLabels accepted another page's ID.")
+	}
+	if g.webViews[2].hints != nil || g.webViews[2].top != 0 {
+		t.Error("Another page was modified.")
+	}
+
+	// A process may only act on pages it claimed.
+	unclaimed := &extensionSession{s, 2, pid}
+	if err := unclaimed.VerticalPositionChanged(
+		VerticalPositionChange{2, 10, 20}, &nothing); err == nil {
+
+		t.Error("VerticalPositionChanged accepted an unclaimed page.")
+	}
+	other := &extensionSession{s, 1, pid + 1}
+	if err := other.VerticalPositionChanged(
+		VerticalPositionChange{1, 10, 20}, &nothing); err == nil {
+
+		t.Error("VerticalPositionChanged accepted another process.")
+	}
+
+	if err := own.VerticalPositionChanged(
+		VerticalPositionChange{1, 10, 20}, &nothing); err != nil {
+
+		t.Errorf("VerticalPositionChanged failed for its own page: %v", err)
+	}
+	if g.webViews[1].top != 10 || g.webViews[1].height != 20 {
+		t.Error("VerticalPositionChanged didn't update its own page.")
+	}
+}
+
+// blockingWebExtension is a fake web extension with a method which blocks
+// until it is released.
+type blockingWebExtension struct {
+	*fakeWebExtension
+	release chan struct{}
+}
+
+// Block blocks until the web extension is released.
+func (e *blockingWebExtension) Block(_ *Nothing, ret *uint64) error {
+	<-e.release
+	*ret = e.id
+	return nil
+}
+
+// getPageIDTest retrieves the page ID from a web extension, giving up after
+// timeout.
+func getPageIDTest(w *webExtension, timeout time.Duration) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var id uint64
+	err := w.callContext(ctx, "GolemWebExtension.GetPageID", nil, &id)
+	return id, err
+}
+
+func TestWebExtensionCallDeadline(t *testing.T) {
+	w := newWebExtension()
+	ext := &blockingWebExtension{&fakeWebExtension{1}, make(chan struct{})}
+	defer close(ext.release)
+	conn, client, _ := serveFakeWebExtension(ext)
+	w.claim(conn, client, 100)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	var id uint64
+	done := make(chan error, 1)
+	go func() {
+		done <- w.callContext(ctx, "GolemWebExtension.Block", nil, &id)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Blocking call succeeded before it was released.")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Blocking call didn't time out.")
+	}
+	// A timed out call doesn't affect the connection.
+	if id, err := getPageIDTest(w, time.Second); err != nil || id != 1 {
+		t.Errorf("Call after a timeout returned %d, %v.", id, err)
+	}
+}
+
+func TestWebExtensionCallWaitsForConnection(t *testing.T) {
+	w := newWebExtension()
+	if _, err := getPageIDTest(w, 50*time.Millisecond); err == nil {
+		t.Error("Call succeeded without a web extension.")
+	}
+
+	type result struct {
+		id  uint64
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		id, err := getPageIDTest(w, 5*time.Second)
+		done <- result{id, err}
+	}()
+	select {
+	case r := <-done:
+		t.Fatalf("Call returned %d, %v before a web extension connected.",
+			r.id, r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	conn, client, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	w.claim(conn, client, 100)
+	select {
+	case r := <-done:
+		if r.err != nil || r.id != 1 {
+			t.Errorf("Call returned %d, %v once connected.", r.id, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Call didn't complete once a web extension connected.")
+	}
+}
+
+func TestWebExtensionReconnects(t *testing.T) {
+	w := newWebExtension()
+	conn, client, _ := serveFakeWebExtension(&fakeWebExtension{1})
+	w.claim(conn, client, 100)
+	if id, err := getPageIDTest(w, time.Second); err != nil || id != 1 {
+		t.Fatalf("Call returned %d, %v.", id, err)
+	}
+
+	w.detach(client)
+	if _, err := getPageIDTest(w, 50*time.Millisecond); err == nil {
+		t.Error("Call succeeded after the web extension was detached.")
+	}
+	conn2, client2, _ := serveFakeWebExtension(&fakeWebExtension{2})
+	w.claim(conn2, client2, 100)
+	if id, err := getPageIDTest(w, time.Second); err != nil || id != 2 {
+		t.Errorf("Call after reconnecting returned %d, %v.", id, err)
+	}
+
+	// Detaching a connection which was already replaced has no effect.
+	w.detach(client)
+	if id, err := getPageIDTest(w, time.Second); err != nil || id != 2 {
+		t.Errorf("Call after detaching a stale connection returned %d, %v.",
+			id, err)
+	}
+}
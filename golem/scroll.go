@@ -0,0 +1,142 @@
+package golem
+
+import (
+	"time"
+)
+
+// scrollFrame is the interval between frames of smooth scrolling.
+const scrollFrame = 16 * time.Millisecond
+
+// A scrollAnimation is a smooth scroll in progress.
+type scrollAnimation struct {
+	vertical bool
+	// target specifies whether the target scroll area is scrolled, rather
+	// than the document.
+	target bool
+	to     int64
+	cancel chan bool
+}
+
+// getScroll retrieves the scroll position along an axis.
+func (wv *webView) getScroll(vertical, target bool) (int64, error) {
+	switch {
+	case vertical && target:
+		return wv.getScrollTargetTop()
+	case target:
+		return wv.getScrollTargetLeft()
+	case vertical:
+		return wv.getScrollTop()
+	default:
+		return wv.getScrollLeft()
+	}
+}
+
+// getScrollSize retrieves the size of the scroll area along an axis.
+func (wv *webView) getScrollSize(vertical, target bool) (int64, error) {
+	switch {
+	case vertical && target:
+		return wv.getScrollTargetHeight()
+	case target:
+		return wv.getScrollTargetWidth()
+	case vertical:
+		return wv.getScrollHeight()
+	default:
+		return wv.getScrollWidth()
+	}
+}
+
+// setScroll sets the scroll position along an axis.
+func (wv *webView) setScroll(to int64, vertical, target bool) error {
+	switch {
+	case vertical && target:
+		return wv.setScrollTargetTop(to)
+	case target:
+		return wv.setScrollTargetLeft(to)
+	case vertical:
+		return wv.setScrollTop(to)
+	default:
+		return wv.setScrollLeft(to)
+	}
+}
+
+// scrollDestination retrieves the position scrolled to along an axis. If a
+// smooth scroll along it is in progress, this is where it will end.
+func (wv *webView) scrollDestination(vertical, target bool) (int64, error) {
+	wv.scrollMutex.Lock()
+	anim := wv.scrollAnim
+	wv.scrollMutex.Unlock()
+	if anim != nil && anim.vertical == vertical && anim.target == target {
+		return anim.to, nil
+	}
+	return wv.getScroll(vertical, target)
+}
+
+// scrollTo scrolls to a position along an axis, clamped to the scroll area.
+//
+// If the scroll-duration setting is non-zero, the scroll is animated. Any
+// previous animation is cancelled.
+func (wv *webView) scrollTo(to int64, vertical, target bool) error {
+	size, err := wv.getScrollSize(vertical, target)
+	if err != nil {
+		return err
+	}
+	if to > size {
+		to = size
+	}
+	if to < 0 {
+		to = 0
+	}
+	from, err := wv.getScroll(vertical, target)
+	if err != nil {
+		return err
+	}
+
+	wv.scrollMutex.Lock()
+	if wv.scrollAnim != nil {
+		close(wv.scrollAnim.cancel)
+		wv.scrollAnim = nil
+	}
+	frames := int64(time.Duration(wv.scrollDuration) * time.Millisecond /
+		scrollFrame)
+	if frames <= 1 || from == to {
+		wv.scrollMutex.Unlock()
+		return wv.setScroll(to, vertical, target)
+	}
+	anim := &scrollAnimation{vertical, target, to, make(chan bool)}
+	wv.scrollAnim = anim
+	wv.scrollMutex.Unlock()
+
+	go wv.animateScroll(anim, from, frames)
+	return nil
+}
+
+// animateScroll runs a smooth scroll from a position over a number of
+// frames, until it completes or is cancelled.
+func (wv *webView) animateScroll(
+	anim *scrollAnimation,
+	from int64,
+	frames int64) {
+
+	ticker := time.NewTicker(scrollFrame)
+	defer ticker.Stop()
+	for i := int64(1); i <= frames; i++ {
+		select {
+		case <-anim.cancel:
+			return
+		case <-ticker.C:
+		}
+		// Ease out quadratically; the scroll slows down towards its end.
+		t := float64(i) / float64(frames)
+		pos := from + int64(float64(anim.to-from)*t*(2-t))
+		err := wv.setScroll(pos, anim.vertical, anim.target)
+		if err != nil {
+			wv.window.logErrorf("Error scrolling: %v", err)
+			break
+		}
+	}
+	wv.scrollMutex.Lock()
+	if wv.scrollAnim == anim {
+		wv.scrollAnim = nil
+	}
+	wv.scrollMutex.Unlock()
+}
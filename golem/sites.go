@@ -0,0 +1,87 @@
+package golem
+
+import (
+	"sync"
+
+	"github.com/tkerber/golem/webkit"
+)
+
+// A siteRule sets a single webkit setting for all pages matching a pattern.
+type siteRule struct {
+	pattern *uriPattern
+	key     string
+	value   interface{}
+}
+
+// siteRules is the ordered collection of all per-site settings.
+//
+// Rules are applied in the order they were defined in, so where multiple
+// rules for the same setting match a page, the one defined last takes
+// precedence. Redefining a rule with the same pattern and setting replaces it
+// in place.
+type siteRules struct {
+	rules []*siteRule
+	mutex *sync.Mutex
+}
+
+// newSiteRules creates a new, empty collection of site rules.
+func newSiteRules() *siteRules {
+	return &siteRules{nil, new(sync.Mutex)}
+}
+
+// add adds a rule, or replaces an existing rule with the same pattern and
+// setting.
+func (s *siteRules) add(pattern *uriPattern, key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, r := range s.rules {
+		if r.pattern.String() == pattern.String() && r.key == key {
+			r.value = value
+			return
+		}
+	}
+	s.rules = append(s.rules, &siteRule{pattern, key, value})
+}
+
+// clear removes all rules.
+func (s *siteRules) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rules = nil
+}
+
+// apply applies all rules matching a uri to a set of settings.
+func (s *siteRules) apply(uri string, settings *webkit.Settings) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, r := range s.rules {
+		if !r.pattern.matches(uri) {
+			continue
+		}
+		switch v := r.value.(type) {
+		case bool:
+			settings.SetBool(r.key, v)
+		case string:
+			settings.SetString(r.key, v)
+		case uint:
+			settings.SetUint(r.key, v)
+		}
+	}
+}
+
+// reset resets a set of settings to a web view's base settings (those set
+// globally, for its window or for the tab), and applies the rules matching a
+// uri to them.
+//
+// Resetting first ensures the rules of previously visited sites don't
+// persist. Site rules take precedence over the base settings.
+func (s *siteRules) reset(uri string, base, settings *webkit.Settings) {
+	settings.CopyFrom(base)
+	s.apply(uri, settings)
+}
+
+// applySiteSettings updates the web view's settings to reflect the site
+// rules matching its current uri.
+func (wv *webView) applySiteSettings() {
+	wv.parent.siteRules.reset(wv.GetURI(), wv.baseSettings, wv.settings)
+}
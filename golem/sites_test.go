@@ -0,0 +1,98 @@
+package golem
+
+import (
+	"testing"
+
+	"github.com/tkerber/golem/webkit"
+)
+
+// addTestRule adds a site rule, failing if its pattern is invalid.
+func addTestRule(
+	t *testing.T,
+	s *siteRules,
+	pattern string,
+	key string,
+	value interface{}) {
+
+	p, err := newURIPattern(pattern)
+	if err != nil {
+		t.Fatalf("Failed to compile pattern '%s': %v", pattern, err)
+	}
+	s.add(p, key, value)
+}
+
+func TestSiteRulesTakePrecedenceOverBaseSettings(t *testing.T) {
+	s := newSiteRules()
+	addTestRule(t, s, "*.example.com", "default-font-size", uint(18))
+	addTestRule(t, s, "*.example.com", "auto-load-images", false)
+	base := webkit.NewSettings()
+	base.SetUint("default-font-size", 14)
+	base.SetBool("auto-load-images", true)
+	base.SetString("user-agent", "base")
+	settings := base.Clone()
+
+	s.reset("https://www.example.com/", base, settings)
+	if size := settings.GetUint("default-font-size"); size != 18 {
+		t.Errorf("Font size on a matching site is %d, expected 18.", size)
+	}
+	if settings.GetBool("auto-load-images") {
+		t.Error("Images are loaded on a matching site.")
+	}
+	// Settings without a matching rule keep their base value.
+	if ua := settings.GetString("user-agent"); ua != "base" {
+		t.Errorf("User agent on a matching site is '%s'.", ua)
+	}
+
+	// Changes to the base settings don't override site rules.
+	base.SetUint("default-font-size", 16)
+	s.reset("https://example.com/", base, settings)
+	if size := settings.GetUint("default-font-size"); size != 18 {
+		t.Errorf("Font size after changing the base is %d, expected 18.", size)
+	}
+
+	// Leaving the site restores the base settings.
+	s.reset("https://golang.org/", base, settings)
+	if size := settings.GetUint("default-font-size"); size != 16 {
+		t.Errorf("Font size on another site is %d, expected 16.", size)
+	}
+	if !settings.GetBool("auto-load-images") {
+		t.Error("Images are not loaded on another site.")
+	}
+}
+
+func TestSiteRulesDefinedLastTakePrecedence(t *testing.T) {
+	s := newSiteRules()
+	addTestRule(t, s, "*.example.com", "user-agent", "domain")
+	addTestRule(t, s, "www.example.com", "user-agent", "host")
+	addTestRule(t, s, "https://*/login", "user-agent", "login")
+	base := webkit.NewSettings()
+	settings := base.Clone()
+	for _, c := range [][2]string{
+		{"https://example.com/", "domain"},
+		{"https://www.example.com/", "host"},
+		{"https://www.example.com/login", "login"},
+	} {
+		s.reset(c[0], base, settings)
+		if ua := settings.GetString("user-agent"); ua != c[1] {
+			t.Errorf("User agent for %s is '%s', expected '%s'.", c[0], ua, c[1])
+		}
+	}
+
+	// Redefining a rule keeps its place in the order.
+	addTestRule(t, s, "*.example.com", "user-agent", "redefined")
+	s.reset("https://www.example.com/", base, settings)
+	if ua := settings.GetString("user-agent"); ua != "host" {
+		t.Errorf("Redefined rule took precedence, user agent is '%s'.", ua)
+	}
+	s.reset("https://example.com/", base, settings)
+	if ua := settings.GetString("user-agent"); ua != "redefined" {
+		t.Errorf("Rule was not redefined, user agent is '%s'.", ua)
+	}
+
+	// Cleared rules no longer apply.
+	s.clear()
+	s.reset("https://example.com/", base, settings)
+	if ua := settings.GetString("user-agent"); ua != base.GetString("user-agent") {
+		t.Errorf("Cleared rule still applied, user agent is '%s'.", ua)
+	}
+}
@@ -0,0 +1,154 @@
+package golem
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	ggtk "github.com/tkerber/golem/gtk"
+)
+
+// spawnFIFOEnd is written to a userscript's FIFO by golem once the userscript
+// exits, to mark the end of its commands.
+const spawnFIFOEnd = "\u0000"
+
+// cmdSpawn runs an external process.
+//
+// spawn [--userscript] CMD [ARGS...]
+//
+// The process is passed the environment variables GOLEM_URI and GOLEM_TITLE
+// of the current tab, as well as GOLEM_TOKEN, the token to remote control
+// golem with. If --userscript is given, the process is additionally passed:
+//
+// GOLEM_SELECTED_TEXT, the text currently selected.
+//
+// GOLEM_HTML and GOLEM_TEXT, the paths of files containing the HTML source
+// and rendered text of the page.
+//
+// GOLEM_FIFO, the path of a FIFO golem reads commands from, one per line.
+// The commands are run in the tab the userscript was spawned from, until it
+// exits.
+func cmdSpawn(w *Window, g *Golem, args []string) error {
+	if w == nil {
+		return errNonGlobalCommand
+	}
+	userscript := len(args) > 1 && args[1] == "--userscript"
+	if userscript {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return invalidArgs(args)
+	}
+	wv := w.getWebView()
+	c := exec.Command(args[1], args[2:]...)
+	c.Env = append(
+		os.Environ(),
+		"GOLEM_URI="+wv.GetURI(),
+		"GOLEM_TITLE="+wv.GetTitle())
+	// Allow the process to remote control golem.
+	c.Env = append(c.Env, "GOLEM_TOKEN="+g.RPCSession.token)
+	if userscript {
+		go spawnUserscript(w, g, wv, c)
+		return nil
+	}
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("Failed to spawn '%v': %v", args[1], err)
+	}
+	go func() {
+		if err := c.Wait(); err != nil {
+			w.logErrorf("Spawned process '%v' failed: %v", args[1], err)
+		}
+	}()
+	return nil
+}
+
+// spawnUserscript runs a userscript spawned from a web view, and executes the
+// commands it writes to its FIFO until it exits.
+func spawnUserscript(w *Window, g *Golem, wv *webView, c *exec.Cmd) {
+	dir, err := ioutil.TempDir("", "golem-userscript-")
+	if err != nil {
+		w.logErrorf("Failed to create userscript directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// The page contents are only retrieved on a best effort basis; a page
+	// which hasn't loaded yet simply has none.
+	selection, _ := wv.GetSelection()
+	html, _ := wv.GetPageHTML()
+	text, _ := wv.GetPageText()
+	htmlFile := filepath.Join(dir, "page.html")
+	textFile := filepath.Join(dir, "page.txt")
+	fifo := filepath.Join(dir, "fifo")
+	if err := ioutil.WriteFile(htmlFile, []byte(html), 0600); err != nil {
+		w.logErrorf("Failed to write page HTML: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(textFile, []byte(text), 0600); err != nil {
+		w.logErrorf("Failed to write page text: %v", err)
+		return
+	}
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		w.logErrorf("Failed to create userscript FIFO: %v", err)
+		return
+	}
+	// Opening the FIFO for reading and writing doesn't block, and doesn't
+	// end the stream if the userscript closes it between commands.
+	f, err := os.OpenFile(fifo, os.O_RDWR, 0)
+	if err != nil {
+		w.logErrorf("Failed to open userscript FIFO: %v", err)
+		return
+	}
+	defer f.Close()
+
+	c.Env = append(
+		c.Env,
+		"GOLEM_SELECTED_TEXT="+selection,
+		"GOLEM_HTML="+htmlFile,
+		"GOLEM_TEXT="+textFile,
+		"GOLEM_FIFO="+fifo)
+	if err := c.Start(); err != nil {
+		w.logErrorf("Failed to spawn userscript '%v': %v", c.Path, err)
+		return
+	}
+
+	done := make(chan bool)
+	go func() {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == spawnFIFOEnd {
+				break
+			}
+			runUserscriptCmd(wv, g, strings.TrimPrefix(line, ":"))
+		}
+		close(done)
+	}()
+
+	if err := c.Wait(); err != nil {
+		w.logErrorf("Userscript '%v' failed: %v", c.Path, err)
+	}
+	// Commands written before the userscript exited are still run.
+	f.Write([]byte("\n" + spawnFIFOEnd + "\n"))
+	<-done
+}
+
+// runUserscriptCmd runs a command from a userscript in the web view it was
+// spawned from, if it is still open.
+func runUserscriptCmd(wv *webView, g *Golem, command string) {
+	ggtk.GlibMainContextInvoke(func() {
+		w := wv.window
+		if w == nil {
+			return
+		}
+		prevTarget := w.targetWebView
+		w.targetWebView = wv
+		runCmd(w, g, command)
+		w.targetWebView = prevTarget
+	})
+}
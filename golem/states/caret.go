@@ -0,0 +1,251 @@
+package states
+
+import (
+	"errors"
+	"log"
+
+	"github.com/tkerber/golem/cmd"
+)
+
+// CaretCallback is an interface for golem.(*webView), implementing the
+// methods needed by caret and visual mode. (mostly web extension calls)
+type CaretCallback interface {
+	StartCaret() (bool, error)
+	ModifySelection(alter, direction, granularity string) (bool, error)
+	GetSelection() (string, error)
+	CollapseSelection() error
+	EndCaret() error
+}
+
+// A caretMovement is a single step of moving the caret, or the end of the
+// selection.
+type caretMovement struct {
+	direction   string
+	granularity string
+}
+
+// caretMovements maps the string values of keys to the steps they move the
+// caret or selection by.
+var caretMovements = map[string][]caretMovement{
+	"h":     {{"backward", "character"}},
+	"Left":  {{"backward", "character"}},
+	"l":     {{"forward", "character"}},
+	"Right": {{"forward", "character"}},
+	"j":     {{"forward", "line"}},
+	"Down":  {{"forward", "line"}},
+	"k":     {{"backward", "line"}},
+	"Up":    {{"backward", "line"}},
+	// WebKit moves forward to the end of words. The start of the next word
+	// is reached by moving to the end of it, and back again.
+	"w": {{"forward", "word"}, {"forward", "word"}, {"backward", "word"}},
+	"b": {{"backward", "word"}},
+	"e": {{"forward", "word"}},
+	"0": {{"backward", "lineboundary"}},
+	"$": {{"forward", "lineboundary"}},
+}
+
+// A CaretSession is the part of caret and visual mode shared between the
+// two, for as long as the caret exists.
+type CaretSession struct {
+	CaretCallback
+	// Yank is called with the selected text when it is yanked.
+	Yank func(string)
+	// callQueue holds the web extension calls waiting to be executed.
+	*callQueue
+}
+
+// move moves the caret, or extends the selection, by the given steps.
+func (s *CaretSession) move(alter string, steps []caretMovement) {
+	s.queue(func() {
+		for _, step := range steps {
+			_, err := s.ModifySelection(alter, step.direction, step.granularity)
+			if err != nil {
+				log.Printf("Failed to modify selection: %v", err)
+				return
+			}
+		}
+	})
+}
+
+// End removes the caret and any selection from the page.
+func (s *CaretSession) End() {
+	s.queue(func() {
+		if err := s.EndCaret(); err != nil {
+			log.Printf("Failed to end caret mode: %v", err)
+		}
+	})
+}
+
+// A CaretState is either caret or visual mode.
+type CaretState interface {
+	cmd.State
+	Session() *CaretSession
+}
+
+// CaretMode is a mode which moves a caret through the text of a web page, as
+// a starting point for selecting text in visual mode.
+type CaretMode struct {
+	*cmd.StateIndependant
+	cmd.Substate
+	*CaretSession
+}
+
+// NewCaretMode creates a new caret mode.
+//
+// It returns a caret mode, and asynchronously an error in the given channel
+// if the caret couldn't be placed.
+//
+// The channel is closed when initialization finishes.
+func NewCaretMode(
+	s cmd.State,
+	cb CaretCallback,
+	yank func(string)) (*CaretMode, <-chan error) {
+
+	cm := &CaretMode{
+		s.GetStateIndependant(),
+		cmd.SubstateDefault,
+		&CaretSession{cb, yank, newCallQueue()},
+	}
+	c := make(chan error, 1)
+	cm.queue(func() {
+		ok, err := cb.StartCaret()
+		if err != nil {
+			c <- err
+		} else if !ok {
+			c <- errors.New("No text to place the caret in")
+		}
+		close(c)
+	})
+	return cm, c
+}
+
+// ProcessKeyPress processes exactly one key press in caret mode.
+//
+// Movement keys move the caret, v and V start visual and visual line mode,
+// and escape returns to normal mode. All other keys are swallowed.
+func (s *CaretMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
+	if key.Keyval == cmd.KeyEscape {
+		return cmd.NewNormalMode(s), true
+	}
+	str := key.Normalize().String()
+	if steps, ok := caretMovements[str]; ok {
+		s.move("move", steps)
+		return s, true
+	}
+	switch str {
+	case "v":
+		return newVisualMode(s, VisualSubstateCharacter), true
+	case "V":
+		return newVisualMode(s, VisualSubstateLine), true
+	}
+	return s, true
+}
+
+// Session retrieves the caret session of this state.
+func (s *CaretMode) Session() *CaretSession {
+	return s.CaretSession
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *CaretMode) GetStateIndependant() *cmd.StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *CaretMode) GetSubstate() cmd.Substate {
+	return s.Substate
+}
+
+// VisualMode is a mode which selects text in a web page, starting at the
+// caret, for it to be yanked.
+type VisualMode struct {
+	*cmd.StateIndependant
+	cmd.Substate
+	*CaretSession
+}
+
+// newVisualMode starts visual mode from a caret or visual mode.
+func newVisualMode(s CaretState, st cmd.Substate) *VisualMode {
+	vm := &VisualMode{s.GetStateIndependant(), st, s.Session()}
+	if st == VisualSubstateLine {
+		vm.move("move", []caretMovement{{"backward", "lineboundary"}})
+		vm.move("extend", []caretMovement{{"forward", "lineboundary"}})
+	}
+	return vm
+}
+
+// ProcessKeyPress processes exactly one key press in visual mode.
+//
+// Movement keys extend the selection, and y yanks it and returns to normal
+// mode. Escape, or the key of the current visual mode (v or V) returns to
+// caret mode, while the other switches visual modes. All other keys are
+// swallowed.
+func (s *VisualMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
+	if key.Keyval == cmd.KeyEscape {
+		return s.caretMode(), true
+	}
+	str := key.Normalize().String()
+	if steps, ok := caretMovements[str]; ok {
+		if s.Substate == VisualSubstateLine {
+			// Lines are always selected completely.
+			last := steps[len(steps)-1]
+			steps = append(
+				steps[:len(steps):len(steps)],
+				caretMovement{last.direction, "lineboundary"})
+		}
+		s.move("extend", steps)
+		return s, true
+	}
+	switch str {
+	case "v":
+		if s.Substate == VisualSubstateCharacter {
+			return s.caretMode(), true
+		}
+		return &VisualMode{
+			s.StateIndependant,
+			VisualSubstateCharacter,
+			s.CaretSession,
+		}, true
+	case "V":
+		if s.Substate == VisualSubstateLine {
+			return s.caretMode(), true
+		}
+		return newVisualMode(s, VisualSubstateLine), true
+	case "y":
+		s.queue(func() {
+			str, err := s.GetSelection()
+			if err != nil {
+				log.Printf("Failed to get selection: %v", err)
+				return
+			}
+			s.Yank(str)
+		})
+		return cmd.NewNormalMode(s), true
+	}
+	return s, true
+}
+
+// caretMode returns to caret mode, collapsing the selection.
+func (s *VisualMode) caretMode() *CaretMode {
+	s.queue(func() {
+		if err := s.CollapseSelection(); err != nil {
+			log.Printf("Failed to collapse selection: %v", err)
+		}
+	})
+	return &CaretMode{s.StateIndependant, cmd.SubstateDefault, s.CaretSession}
+}
+
+// Session retrieves the caret session of this state.
+func (s *VisualMode) Session() *CaretSession {
+	return s.CaretSession
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *VisualMode) GetStateIndependant() *cmd.StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *VisualMode) GetSubstate() cmd.Substate {
+	return s.Substate
+}
@@ -0,0 +1,201 @@
+package states
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tkerber/golem/cmd"
+)
+
+// fakeCaretCallback records the calls made to a page's caret.
+type fakeCaretCallback struct {
+	calls     []string
+	selection string
+	mutex     *sync.Mutex
+}
+
+// newFakeCaretCallback creates a fake caret with the given text selected.
+func newFakeCaretCallback(selection string) *fakeCaretCallback {
+	return &fakeCaretCallback{nil, selection, new(sync.Mutex)}
+}
+
+// record records a call.
+func (cb *fakeCaretCallback) record(call string) {
+	cb.mutex.Lock()
+	cb.calls = append(cb.calls, call)
+	cb.mutex.Unlock()
+}
+
+// takeCalls retrieves and forgets the calls made so far.
+func (cb *fakeCaretCallback) takeCalls() []string {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	calls := cb.calls
+	cb.calls = nil
+	return calls
+}
+
+func (cb *fakeCaretCallback) StartCaret() (bool, error) {
+	cb.record("start")
+	return true, nil
+}
+
+func (cb *fakeCaretCallback) ModifySelection(
+	alter, direction, granularity string) (bool, error) {
+
+	cb.record(fmt.Sprintf("%s %s %s", alter, direction, granularity))
+	return true, nil
+}
+
+func (cb *fakeCaretCallback) GetSelection() (string, error) {
+	cb.record("get")
+	return cb.selection, nil
+}
+
+func (cb *fakeCaretCallback) CollapseSelection() error {
+	cb.record("collapse")
+	return nil
+}
+
+func (cb *fakeCaretCallback) EndCaret() error {
+	cb.record("end")
+	return nil
+}
+
+// newTestState creates a normal mode state without any bindings.
+func newTestState() cmd.State {
+	var state cmd.State
+	state = cmd.NewState(
+		nil,
+		nil,
+		nil,
+		func() time.Duration { return time.Second },
+		func(s cmd.State) { state = s },
+		func() cmd.State { return state },
+		nil)
+	return state
+}
+
+// drain waits until all calls queued so far have been executed.
+func drain(q *callQueue) {
+	done := make(chan struct{})
+	q.queue(func() { close(done) })
+	<-done
+}
+
+// pressKeys feeds keys to a state, and returns the resulting state.
+func pressKeys(t *testing.T, s cmd.State, keys ...string) cmd.State {
+	for _, k := range keys {
+		key, ok := cmd.NewKeyFromString(k).(cmd.RealKey)
+		if !ok {
+			t.Fatalf("Key '%s' is not a real key.", k)
+		}
+		s, _ = s.ProcessKeyPress(key)
+	}
+	return s
+}
+
+// startTestCaret starts caret mode on a fake caret.
+func startTestCaret(
+	t *testing.T,
+	cb *fakeCaretCallback,
+	yank func(string)) *CaretMode {
+
+	cm, c := NewCaretMode(newTestState(), cb, yank)
+	if err := <-c; err != nil {
+		t.Fatalf("Failed to start caret mode: %v", err)
+	}
+	cb.takeCalls()
+	return cm
+}
+
+func TestCaretModeTransitions(t *testing.T) {
+	cm := startTestCaret(t, newFakeCaretCallback(""), nil)
+	for _, c := range []struct {
+		keys     []string
+		substate cmd.Substate
+		visual   bool
+	}{
+		{[]string{"v"}, VisualSubstateCharacter, true},
+		{[]string{"V"}, VisualSubstateLine, true},
+		{[]string{"v", "V"}, VisualSubstateLine, true},
+		{[]string{"V", "v"}, VisualSubstateCharacter, true},
+		{[]string{"v", "v"}, cmd.SubstateDefault, false},
+		{[]string{"V", "V"}, cmd.SubstateDefault, false},
+		{[]string{"v", "Escape"}, cmd.SubstateDefault, false},
+		{[]string{"x"}, cmd.SubstateDefault, false},
+	} {
+		s := pressKeys(t, cm, c.keys...)
+		_, isVisual := s.(*VisualMode)
+		_, isCaret := s.(*CaretMode)
+		if isVisual != c.visual || isCaret == c.visual ||
+			s.GetSubstate() != c.substate {
+
+			t.Errorf("Keys %v led to %T in substate %d.",
+				c.keys, s, s.GetSubstate())
+		}
+	}
+	if _, ok := pressKeys(t, cm, "Escape").(*cmd.NormalMode); !ok {
+		t.Error("Escape didn't leave caret mode.")
+	}
+}
+
+func TestCaretModeMovesInOrder(t *testing.T) {
+	cb := newFakeCaretCallback("")
+	cm := startTestCaret(t, cb, nil)
+	s := pressKeys(t, cm, "l", "w", "v", "j", "Escape", "0")
+	drain(s.(*CaretMode).callQueue)
+	expected := []string{
+		"move forward character",
+		"move forward word",
+		"move forward word",
+		"move backward word",
+		"extend forward line",
+		"collapse",
+		"move backward lineboundary",
+	}
+	if calls := cb.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Got calls %q, expected %q.", calls, expected)
+	}
+}
+
+func TestVisualLineModeSelectsLines(t *testing.T) {
+	cb := newFakeCaretCallback("")
+	cm := startTestCaret(t, cb, nil)
+	s := pressKeys(t, cm, "V", "k")
+	drain(s.(*VisualMode).callQueue)
+	expected := []string{
+		"move backward lineboundary",
+		"extend forward lineboundary",
+		"extend backward line",
+		"extend backward lineboundary",
+	}
+	if calls := cb.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Got calls %q, expected %q.", calls, expected)
+	}
+}
+
+func TestVisualModeYanks(t *testing.T) {
+	cb := newFakeCaretCallback("selected text")
+	yanked := make(chan string, 1)
+	cm := startTestCaret(t, cb, func(str string) { yanked <- str })
+	s := pressKeys(t, cm, "v", "e", "y")
+	if _, ok := s.(*cmd.NormalMode); !ok {
+		t.Errorf("Yanking led to %T, expected normal mode.", s)
+	}
+	select {
+	case str := <-yanked:
+		if str != "selected text" {
+			t.Errorf("Yanked %q, expected \"selected text\".", str)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Selection wasn't yanked.")
+	}
+	expected := []string{"extend forward word", "get"}
+	if calls := cb.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Got calls %q, expected %q.", calls, expected)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/tkerber/golem/cmd"
 )
@@ -14,19 +16,36 @@ type HintsCallback interface {
 	LinkHintsMode() (int64, error)
 	FormVariableHintsMode() (int64, error)
 	ClickHintsMode() (int64, error)
+	LinkTextHintsMode() (int64, error)
+	ImageHintsMode() (int64, error)
+	InputHintsMode() (int64, error)
+	HoverHintsMode() (int64, error)
+	ScrollTargetHintsMode() (int64, error)
 	EndHintsMode() error
 	FilterHintsMode(string) (bool, error)
+	HintsTexts() ([]HintText, error)
+	ShowHints([]string) (int64, error)
+	ExecuteHint(string) (bool, error)
 }
 
 // HintsMode is a mode which displays key strings on items of intrest in a
 // web view, and allows the selection of said items by typing these key
 // strings.
+//
+// If TextFilter is set, lower case letters, spaces and punctuation typed are
+// instead used to filter the items by their text, and the remaining items
+// are labelled afresh. Upper case letters and digits still select labels.
 type HintsMode struct {
 	*cmd.StateIndependant
 	cmd.Substate
 	HintsCallback
 	CurrentKeys      []cmd.Key
 	ExecuterFunction func(string) bool
+	TextFilter       bool
+	FilterText       string
+	// callQueue holds the web extension calls waiting to be executed. It is
+	// shared by all hints mode states of one use of hints mode.
+	*callQueue
 }
 
 // NewHintsMode creates a new hints mode.
@@ -50,6 +69,9 @@ func NewHintsMode(
 		cb,
 		make([]cmd.Key, 0),
 		e,
+		false,
+		"",
+		newCallQueue(),
 	}
 
 	c := make(chan error, 1)
@@ -64,11 +86,23 @@ func NewHintsMode(
 		case HintsSubstateBackground,
 			HintsSubstateRapid,
 			HintsSubstateTab,
-			HintsSubstateWindow:
+			HintsSubstateWindow,
+			HintsSubstateYankURI,
+			HintsSubstateDownload:
 
 			nHints, err = hm.HintsCallback.LinkHintsMode()
 		case HintsSubstateSearchEngine:
 			nHints, err = hm.HintsCallback.FormVariableHintsMode()
+		case HintsSubstateYankText:
+			nHints, err = hm.HintsCallback.LinkTextHintsMode()
+		case HintsSubstateImage:
+			nHints, err = hm.HintsCallback.ImageHintsMode()
+		case HintsSubstateFocusInput:
+			nHints, err = hm.HintsCallback.InputHintsMode()
+		case HintsSubstateHover:
+			nHints, err = hm.HintsCallback.HoverHintsMode()
+		case HintsSubstateScrollTarget:
+			nHints, err = hm.HintsCallback.ScrollTargetHintsMode()
 		default:
 			err = fmt.Errorf("Unknown hints type: %d", hm.Substate)
 		}
@@ -87,23 +121,91 @@ func NewHintsMode(
 	return hm, c
 }
 
+// WithTextFilter returns a copy of the hints mode, with filtering by text
+// enabled or disabled.
+//
+// Any text filtered by is cleared.
+func (s *HintsMode) WithTextFilter(enabled bool) *HintsMode {
+	ret := &HintsMode{
+		s.StateIndependant,
+		s.Substate,
+		s.HintsCallback,
+		nil,
+		s.ExecuterFunction,
+		enabled,
+		"",
+		s.callQueue,
+	}
+	if s.FilterText != "" || len(s.CurrentKeys) != 0 {
+		ret.queue(func() { ret.filterText(s) })
+	}
+	return ret
+}
+
+// WithoutKeys returns a copy of the hints mode, with the keys typed cleared.
+func (s *HintsMode) WithoutKeys() *HintsMode {
+	return &HintsMode{
+		s.StateIndependant,
+		s.Substate,
+		s.HintsCallback,
+		nil,
+		s.ExecuterFunction,
+		s.TextFilter,
+		s.FilterText,
+		s.callQueue,
+	}
+}
+
+// isFilterTextKey checks if a key is used to filter hints by text, rather
+// than to select a label.
+func isFilterTextKey(key cmd.RealKey) bool {
+	r := cmd.KeyRune(key)
+	if r == 0 || unicode.IsUpper(r) || unicode.IsDigit(r) {
+		return false
+	}
+	return unicode.IsPrint(r)
+}
+
 // ProcessKeyPress processes exactly one key press in hints mode.
 //
 // It returns the new state, and whether the key press was swallowed or not.
+//
+// Hints mode bindings take precedence over selecting hints.
 func (s *HintsMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
-	var newKeys []cmd.Key
+	if s.ExecuteModeBinding(s, cmd.ModeHints, key) {
+		return s, true
+	}
+	newKeys := s.CurrentKeys
+	filterText := s.FilterText
 	switch key.Keyval {
-	// TODO maybe do something on enter. For now, just end hints mode.
 	// TODO maybe handle tab.
-	case cmd.KeyReturn, cmd.KeyKPEnter, cmd.KeyEscape:
+	case cmd.KeyReturn, cmd.KeyKPEnter:
+		// Select the best match for the text typed, if any.
+		if s.FilterText != "" {
+			s.queue(s.executeBestMatch)
+			return s, true
+		}
+		return cmd.NewNormalMode(s), true
+	case cmd.KeyEscape:
 		return cmd.NewNormalMode(s), true
 	case cmd.KeyBackSpace:
-		if len(s.CurrentKeys) == 0 {
+		switch {
+		case len(s.CurrentKeys) != 0:
+			newKeys = s.CurrentKeys[:len(s.CurrentKeys)-1]
+		case s.FilterText != "":
+			_, size := utf8.DecodeLastRuneInString(s.FilterText)
+			filterText = s.FilterText[:len(s.FilterText)-size]
+		default:
 			return cmd.NewNormalMode(s), true
 		}
-		newKeys = s.CurrentKeys[:len(s.CurrentKeys)-1]
 	default:
-		newKeys = cmd.ImmutableAppend(s.CurrentKeys, key)
+		if s.TextFilter && isFilterTextKey(key) {
+			// The labels change, so any label typed is discarded.
+			newKeys = nil
+			filterText += string(cmd.KeyRune(key))
+		} else {
+			newKeys = cmd.ImmutableAppend(s.CurrentKeys, key)
+		}
 	}
 	ret := &HintsMode{
 		s.StateIndependant,
@@ -111,21 +213,97 @@ func (s *HintsMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
 		s.HintsCallback,
 		newKeys,
 		s.ExecuterFunction,
+		s.TextFilter,
+		filterText,
+		s.callQueue,
 	}
-	go func() {
+	if filterText != s.FilterText {
+		ret.queue(func() { ret.filterText(s) })
+		return ret, true
+	}
+	ret.queue(func() {
+		if !ret.current(s) {
+			return
+		}
 		hitAndEnd, err := s.HintsCallback.FilterHintsMode(cmd.KeysString(newKeys))
 		if err != nil {
 			log.Printf("Failed to filter hints: %v", err)
 		} else if hitAndEnd {
-			sNew := s.GetState()
-			if sNew == s || sNew == ret {
-				s.StateIndependant.SetState(cmd.NewNormalMode(s))
-			}
+			ret.end(s)
 		}
-	}()
+	})
 	return ret, true
 }
 
+// current checks if the current state is still this one, or the previous
+// state prev, which it is about to replace.
+//
+// Calls queued for a state which is no longer current are dropped, as a
+// newer state has queued calls of its own.
+func (s *HintsMode) current(prev *HintsMode) bool {
+	sNew := s.GetState()
+	return sNew == s || (prev != nil && sNew == prev)
+}
+
+// end ends hints mode, if the current state is still this one, or the
+// previous state prev.
+func (s *HintsMode) end(prev *HintsMode) {
+	if s.current(prev) {
+		s.StateIndependant.SetState(cmd.NewNormalMode(s))
+	}
+}
+
+// filterText displays only the hints matching the text filtered by, labelled
+// afresh. If only one hint matches, it is executed.
+//
+// It does nothing if the state is no longer current.
+func (s *HintsMode) filterText(prev *HintsMode) {
+	if !s.current(prev) {
+		return
+	}
+	texts, err := s.HintsCallback.HintsTexts()
+	if err != nil {
+		log.Printf("Failed to filter hints: %v", err)
+		return
+	}
+	matches := MatchHints(texts, s.FilterText)
+	if s.FilterText != "" && len(matches) == 1 {
+		hitAndEnd, err := s.HintsCallback.ExecuteHint(matches[0].ID)
+		if err != nil {
+			log.Printf("Failed to execute hint: %v", err)
+		} else if hitAndEnd {
+			s.end(prev)
+		}
+		return
+	}
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	if _, err := s.HintsCallback.ShowHints(ids); err != nil {
+		log.Printf("Failed to filter hints: %v", err)
+	}
+}
+
+// executeBestMatch executes the hint best matching the text filtered by.
+func (s *HintsMode) executeBestMatch() {
+	texts, err := s.HintsCallback.HintsTexts()
+	if err != nil {
+		log.Printf("Failed to execute hint: %v", err)
+		return
+	}
+	matches := MatchHints(texts, s.FilterText)
+	if len(matches) == 0 {
+		return
+	}
+	hitAndEnd, err := s.HintsCallback.ExecuteHint(matches[0].ID)
+	if err != nil {
+		log.Printf("Failed to execute hint: %v", err)
+	} else if hitAndEnd {
+		s.end(nil)
+	}
+}
+
 // GetStateIndependant gets the state independant associated with this state.
 func (s *HintsMode) GetStateIndependant() *cmd.StateIndependant {
 	return s.StateIndependant
@@ -0,0 +1,95 @@
+package states
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A HintText is the text of a hinted element, along with the id of the hint.
+type HintText struct {
+	ID   string
+	Text string
+}
+
+// scoredHint is a hint matching a query, along with how well it matches.
+type scoredHint struct {
+	HintText
+	score int
+}
+
+// scoredHints sorts hints by their score, best first.
+type scoredHints []scoredHint
+
+// Len returns the number of hints.
+func (s scoredHints) Len() int {
+	return len(s)
+}
+
+// Less checks if the hint i matches better than the hint j.
+func (s scoredHints) Less(i, j int) bool {
+	return s[i].score > s[j].score
+}
+
+// Swap swaps the hints i and j.
+func (s scoredHints) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// MatchHints filters hints by their text.
+//
+// Each whitespace separated word of the query must occur in the text of a
+// hint for it to match, ignoring case. The matching hints are returned with
+// the best matches first: Those with more words found at the start of a word
+// of their text precede the others. Hints matching equally well keep their
+// original order.
+func MatchHints(hints []HintText, query string) []HintText {
+	words := strings.Fields(strings.ToLower(query))
+	matches := make(scoredHints, 0, len(hints))
+	for _, h := range hints {
+		score, ok := matchHint(strings.ToLower(h.Text), words)
+		if ok {
+			matches = append(matches, scoredHint{h, score})
+		}
+	}
+	sort.Stable(matches)
+	ret := make([]HintText, len(matches))
+	for i, m := range matches {
+		ret[i] = m.HintText
+	}
+	return ret
+}
+
+// matchHint checks if all words occur in a lower case text, and scores the
+// match by the number of words found at the start of a word of the text.
+func matchHint(text string, words []string) (int, bool) {
+	score := 0
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return 0, false
+		}
+		for i := 0; i < len(text); {
+			j := strings.Index(text[i:], word)
+			if j == -1 {
+				break
+			}
+			if isWordStart(text, i+j) {
+				score++
+				break
+			}
+			_, size := utf8.DecodeRuneInString(text[i+j:])
+			i += j + size
+		}
+	}
+	return score, true
+}
+
+// isWordStart checks if the byte index i of text starts a word.
+func isWordStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,79 @@
+package states
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testHints are hinted elements, in page order.
+var testHints = []HintText{
+	{"a", "Download the manual"},
+	{"b", "Manual (PDF)"},
+	{"c", "Release notes"},
+	{"d", "Über uns"},
+	{"e", ""},
+	{"f", "Unmanaged downloads"},
+}
+
+// hintIDs retrieves the ids of hints, in order.
+func hintIDs(hints []HintText) []string {
+	ids := make([]string, len(hints))
+	for i, h := range hints {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestMatchHints(t *testing.T) {
+	for _, c := range []struct {
+		query string
+		ids   []string
+	}{
+		// All hints match an empty query, in their original order.
+		{"", []string{"a", "b", "c", "d", "e", "f"}},
+		{"   ", []string{"a", "b", "c", "d", "e", "f"}},
+		// Case is ignored, also outside of ASCII.
+		{"MANUAL", []string{"a", "b"}},
+		{"über", []string{"d"}},
+		{"ÜBER", []string{"d"}},
+		// Matches at the start of a word precede others.
+		{"man", []string{"a", "b", "f"}},
+		{"anual", []string{"a", "b"}},
+		{"pdf", []string{"b"}},
+		// All words must match, in any order.
+		{"manual download", []string{"a"}},
+		{"download manual", []string{"a"}},
+		{"download notes", []string{}},
+		// More words matching at the start of a word rank higher.
+		{"down man", []string{"a", "f"}},
+		{"load man", []string{"a", "f"}},
+		{"xyz", []string{}},
+	} {
+		ids := hintIDs(MatchHints(testHints, c.query))
+		if !reflect.DeepEqual(ids, c.ids) {
+			t.Errorf("Query %q matched %q, expected %q.", c.query, ids, c.ids)
+		}
+	}
+}
+
+func TestMatchHintsRanksWordStarts(t *testing.T) {
+	hints := []HintText{
+		{"a", "command"},
+		{"b", "man page"},
+		{"c", "human"},
+		{"d", "mandatory"},
+	}
+	ids := hintIDs(MatchHints(hints, "man"))
+	expected := []string{"b", "d", "a", "c"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Got %q, expected %q.", ids, expected)
+	}
+}
+
+func TestMatchHintsDoesNotModifyHints(t *testing.T) {
+	hints := append([]HintText(nil), testHints...)
+	MatchHints(hints, "manual")
+	if !reflect.DeepEqual(hints, testHints) {
+		t.Error("MatchHints modified the hints passed to it.")
+	}
+}
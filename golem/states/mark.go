@@ -0,0 +1,58 @@
+package states
+
+import (
+	"unicode"
+
+	"github.com/tkerber/golem/cmd"
+)
+
+// MarkMode is a mode which reads the name of a mark, and passes it on to a
+// callback.
+//
+// Marks are named by a single letter; lower case letters name marks local to
+// a page, upper case letters global marks. The names ' and ` both name the
+// mark of the position before the last jump.
+type MarkMode struct {
+	*cmd.StateIndependant
+	cmd.Substate
+	Callback func(mark rune)
+}
+
+// NewMarkMode creates a new mark mode.
+func NewMarkMode(
+	s cmd.State,
+	st cmd.Substate,
+	callback func(rune)) *MarkMode {
+
+	return &MarkMode{s.GetStateIndependant(), st, callback}
+}
+
+// IsMarkName checks if a rune is a valid mark name.
+func IsMarkName(r rune) bool {
+	return r == '\'' || r == '`' ||
+		(r < unicode.MaxASCII && unicode.IsLetter(r))
+}
+
+// ProcessKeyPress processes a single key press in mark mode.
+//
+// A key naming a mark returns to normal mode and runs the callback. Any
+// other key cancels mark mode.
+func (s *MarkMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
+	r := cmd.KeyRune(key)
+	if !IsMarkName(r) {
+		return cmd.NewNormalMode(s), true
+	}
+	s.SetState(cmd.NewNormalMode(s))
+	s.Callback(r)
+	return s.GetState(), true
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *MarkMode) GetStateIndependant() *cmd.StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *MarkMode) GetSubstate() cmd.Substate {
+	return s.Substate
+}
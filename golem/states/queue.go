@@ -0,0 +1,45 @@
+package states
+
+import (
+	"sync"
+)
+
+// A callQueue executes web extension calls in order, in a goroutine of its
+// own, so that processing key presses neither blocks on nor reorders them.
+type callQueue struct {
+	calls   []func()
+	running bool
+	mutex   *sync.Mutex
+}
+
+// newCallQueue creates a new, empty call queue.
+func newCallQueue() *callQueue {
+	return &callQueue{nil, false, new(sync.Mutex)}
+}
+
+// queue queues a web extension call.
+func (q *callQueue) queue(f func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.calls = append(q.calls, f)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+// run executes queued calls until none are left.
+func (q *callQueue) run() {
+	for {
+		q.mutex.Lock()
+		if len(q.calls) == 0 {
+			q.running = false
+			q.mutex.Unlock()
+			return
+		}
+		f := q.calls[0]
+		q.calls = q.calls[1:]
+		q.mutex.Unlock()
+		f()
+	}
+}
@@ -0,0 +1,59 @@
+package states
+
+import (
+	"unicode"
+
+	"github.com/tkerber/golem/cmd"
+)
+
+// RegisterMode is a mode which reads the name of a register, and passes it
+// on to a callback.
+//
+// Registers are named by a single letter or digit.
+type RegisterMode struct {
+	*cmd.StateIndependant
+	cmd.Substate
+	Callback func(register rune)
+}
+
+// NewRegisterMode creates a new register mode.
+func NewRegisterMode(
+	s cmd.State,
+	st cmd.Substate,
+	callback func(rune)) *RegisterMode {
+
+	return &RegisterMode{s.GetStateIndependant(), st, callback}
+}
+
+// IsRegisterName checks if a rune is a valid register name.
+func IsRegisterName(r rune) bool {
+	return r < unicode.MaxASCII &&
+		(unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// ProcessKeyPress processes a single key press in register mode.
+//
+// A key naming a register returns to normal mode and runs the callback.
+// Any other key cancels register mode.
+func (s *RegisterMode) ProcessKeyPress(key cmd.RealKey) (cmd.State, bool) {
+	str := key.Normalize().String()
+	r := []rune(str)
+	if len(r) != 1 || !IsRegisterName(r[0]) {
+		return cmd.NewNormalMode(s), true
+	}
+	// The callback may process further keys, so normal mode is entered
+	// before it is run.
+	s.SetState(cmd.NewNormalMode(s))
+	s.Callback(r[0])
+	return s.GetState(), true
+}
+
+// GetStateIndependant gets the state independant associated with this state.
+func (s *RegisterMode) GetStateIndependant() *cmd.StateIndependant {
+	return s.StateIndependant
+}
+
+// GetSubstate gets the substate associated with this state.
+func (s *RegisterMode) GetSubstate() cmd.Substate {
+	return s.Substate
+}
@@ -52,4 +52,53 @@ const (
 	// HintsSubstateSearchEngine indicates to register a new search engine on
 	// the page.
 	HintsSubstateSearchEngine
+	// HintsSubstateYankURI indicates to yank the uri of a link.
+	HintsSubstateYankURI
+	// HintsSubstateYankText indicates to yank the text of a link.
+	HintsSubstateYankText
+	// HintsSubstateDownload indicates to download the target of a link.
+	HintsSubstateDownload
+	// HintsSubstateImage indicates to open the source of an image.
+	HintsSubstateImage
+	// HintsSubstateFocusInput indicates to focus an input field.
+	HintsSubstateFocusInput
+	// HintsSubstateHover indicates to move the mouse over an element.
+	HintsSubstateHover
+	// HintsSubstateScrollTarget indicates to select a container to scroll.
+	HintsSubstateScrollTarget
+)
+
+const (
+	// RegisterSubstateRecord indicates a macro is to be recorded into the
+	// register.
+	RegisterSubstateRecord cmd.Substate = iota
+	// RegisterSubstateReplay indicates the macro in the register is to be
+	// replayed.
+	RegisterSubstateReplay
+)
+
+const (
+	// PassThroughSubstateManual indicates pass-through mode was entered
+	// explicitly.
+	PassThroughSubstateManual cmd.Substate = iota
+	// PassThroughSubstateAuto indicates pass-through mode was entered
+	// automatically, due to the page matching a pass-through site.
+	PassThroughSubstateAuto
+)
+
+const (
+	// VisualSubstateCharacter indicates text is selected by character.
+	VisualSubstateCharacter cmd.Substate = iota
+	// VisualSubstateLine indicates text is selected by entire lines.
+	VisualSubstateLine
+)
+
+const (
+	// MarkSubstateSet indicates a mark is to be set at the scroll position.
+	MarkSubstateSet cmd.Substate = iota
+	// MarkSubstateJump indicates to jump to the exact position of a mark.
+	MarkSubstateJump
+	// MarkSubstateJumpLine indicates to jump to the vertical position of a
+	// mark.
+	MarkSubstateJumpLine
 )
@@ -141,9 +141,11 @@ func (w *Window) TabGo(index int) error {
 		w.currentWebView = index
 		w.Window.TabNumber = index + 1
 		wv := w.getWebView()
-		if _, ok := w.State.(*states.HintsMode); ok {
+		switch w.State.(type) {
+		case *states.HintsMode, states.CaretState:
 			w.setState(cmd.NewNormalMode(w.State))
 		}
+		wv.applyPassThrough()
 		w.reconnectWebViewSignals()
 		w.SwitchToWebView(wv)
 		w.Window.TabBar.FocusTab(index)
@@ -158,6 +160,11 @@ func (w *Window) TabGo(index int) error {
 			w.fullscreenHidingUI = true
 		}
 	})
+	if err == nil {
+		gtk.GlibMainContextInvoke(func() {
+			w.getWebView().fireAutocmds(autocmdTabFocus)
+		})
+	}
 	return err
 }
 
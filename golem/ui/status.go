@@ -86,6 +86,8 @@ func (w *Window) UpdateState(state cmd.State) {
 			keysToMarkupString(s.CurrentKeys, true, true))
 	case *cmd.InsertMode:
 		newStatus = "-- <em>insert</em> --"
+	case *cmd.PassThroughMode:
+		newStatus = "-- <em>passthrough</em> --"
 	case *cmd.CommandLineMode:
 		var substateStr string
 		switch s.Substate {
@@ -103,6 +105,50 @@ func (w *Window) UpdateState(state cmd.State) {
 			substateStr,
 			keysToMarkupString(beforeCursor, false, false),
 			keysToMarkupString(afterCursor, false, false))
+	case *cmd.HistorySearchMode:
+		prompt := "reverse-i-search"
+		if s.Failing {
+			prompt = "failing " + prompt
+		}
+		newStatus = fmt.Sprintf(
+			"(%s) <em>%v</em><cursor>_</cursor>: %s",
+			prompt,
+			keysToMarkupString(s.Query, false, false),
+			html.EscapeString(s.MatchText))
+	case *states.RegisterMode:
+		switch s.Substate {
+		case states.RegisterSubstateRecord:
+			newStatus = "Record macro: <cursor>_</cursor>"
+		case states.RegisterSubstateReplay:
+			newStatus = "Replay macro: <cursor>_</cursor>"
+		}
+	case *states.MarkMode:
+		switch s.Substate {
+		case states.MarkSubstateSet:
+			newStatus = "Set mark: <cursor>_</cursor>"
+		case states.MarkSubstateJump:
+			newStatus = "Jump to mark: <cursor>_</cursor>"
+		case states.MarkSubstateJumpLine:
+			newStatus = "Jump to mark line: <cursor>_</cursor>"
+		}
+	case *cmd.KeyTestMode:
+		if s.Key == nil {
+			newStatus = "Key test: press any key, <em>Escape</em> twice to " +
+				"leave"
+		} else {
+			newStatus = fmt.Sprintf(
+				"Key test: <em>%s</em>",
+				html.EscapeString(s.Key.Describe()))
+		}
+	case *states.CaretMode:
+		newStatus = "-- <em>caret</em> --"
+	case *states.VisualMode:
+		switch s.Substate {
+		case states.VisualSubstateCharacter:
+			newStatus = "-- <em>visual</em> --"
+		case states.VisualSubstateLine:
+			newStatus = "-- <em>visual line</em> --"
+		}
 	case *cmd.StatusMode:
 		var fmtString string
 		switch s.Substate {
@@ -133,10 +179,31 @@ func (w *Window) UpdateState(state cmd.State) {
 			action = "follow in new window"
 		case states.HintsSubstateSearchEngine:
 			action = "select search engine to add"
+		case states.HintsSubstateYankURI:
+			action = "yank link"
+		case states.HintsSubstateYankText:
+			action = "yank link text"
+		case states.HintsSubstateDownload:
+			action = "download"
+		case states.HintsSubstateImage:
+			action = "open image"
+		case states.HintsSubstateFocusInput:
+			action = "focus input"
+		case states.HintsSubstateHover:
+			action = "hover"
+		case states.HintsSubstateScrollTarget:
+			action = "select scroll target"
+		}
+		if s.TextFilter {
+			newStatus = fmt.Sprintf("%s: \"<em>%s</em>\" <em>%s</em>",
+				action,
+				html.EscapeString(s.FilterText),
+				keysToMarkupString(s.CurrentKeys, true, true))
+		} else {
+			newStatus = fmt.Sprintf("%s: <em>%s</em>",
+				action,
+				keysToMarkupString(s.CurrentKeys, true, true))
 		}
-		newStatus = fmt.Sprintf("%s: <em>%s</em>",
-			action,
-			keysToMarkupString(s.CurrentKeys, true, true))
 	}
 	split := strings.SplitN(newStatus, "<cursor>_</cursor>", 2)
 	if len(split) == 1 {
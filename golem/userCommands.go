@@ -0,0 +1,214 @@
+package golem
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/states"
+)
+
+// maxUserCommandDepth is the maximum depth of user commands running other
+// user commands.
+const maxUserCommandDepth = 20
+
+// userCommandNameRegex matches valid names for user commands.
+var userCommandNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// userCommandNargsRegex matches the optional number of arguments given when
+// defining a user command.
+var userCommandNargsRegex = regexp.MustCompile(`^(\d|\*|\?|\+)$`)
+
+// userCommandArgRegex matches references to arguments in the body of a user
+// command.
+var userCommandArgRegex = regexp.MustCompile(`\$(\d|@|\$)`)
+
+// A userCommand is a command defined with the command or alias commands.
+type userCommand struct {
+	name string
+	// nargs is the number of arguments the command takes: a digit, '*' for
+	// any number, '?' for at most one or '+' for at least one.
+	nargs string
+	body  string
+	// alias is set for commands defined with alias, which append their
+	// arguments to the body instead of substituting them.
+	alias bool
+}
+
+// acceptsArgs checks if the command accepts n arguments.
+func (c *userCommand) acceptsArgs(n int) bool {
+	switch c.nargs {
+	case "*":
+		return true
+	case "?":
+		return n <= 1
+	case "+":
+		return n >= 1
+	default:
+		m, _ := strconv.Atoi(c.nargs)
+		return n == m
+	}
+}
+
+// expand expands the body of the command with the given arguments.
+//
+// $1 to $9 are replaced with the respective argument, $0 with the command's
+// name, $@ with all arguments and $$ with a literal $.
+func (c *userCommand) expand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	if c.alias {
+		return strings.Join(append([]string{c.body}, quoted...), " ")
+	}
+	return userCommandArgRegex.ReplaceAllStringFunc(
+		c.body,
+		func(ref string) string {
+			switch ref[1] {
+			case '$':
+				return "$"
+			case '@':
+				return strings.Join(quoted, " ")
+			case '0':
+				return c.name
+			default:
+				i := int(ref[1] - '0')
+				if i > len(args) {
+					return ""
+				}
+				return quoted[i-1]
+			}
+		})
+}
+
+// String formats the command for display.
+func (c *userCommand) String() string {
+	if c.alias {
+		return fmt.Sprintf("%s\t%s", c.name, c.body)
+	}
+	return fmt.Sprintf("%s\t%s\t%s", c.name, c.nargs, c.body)
+}
+
+// shellQuote quotes a string, so that it is parsed as a single argument.
+func shellQuote(str string) string {
+	if str != "" && !strings.ContainsAny(str, " \t\n'\"\\|&;<>()`") {
+		return str
+	}
+	return "'" + strings.Replace(str, "'", `'\''`, -1) + "'"
+}
+
+// joinQuoted joins command arguments, quoting them where necessary.
+func joinQuoted(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// userCommand retrieves the user command with the given name.
+func (g *Golem) userCommand(name string) (*userCommand, bool) {
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	c, ok := g.userCommands[name]
+	return c, ok
+}
+
+// sortedUserCommands retrieves all user commands, sorted by name.
+func (g *Golem) sortedUserCommands() []*userCommand {
+	g.wMutex.Lock()
+	defer g.wMutex.Unlock()
+	ret := make([]*userCommand, 0, len(g.userCommands))
+	for _, c := range g.userCommands {
+		ret = append(ret, c)
+	}
+	sort.Sort(userCommandsByName(ret))
+	return ret
+}
+
+// userCommandsByName sorts user commands by their name.
+type userCommandsByName []*userCommand
+
+// Len returns the number of commands.
+func (s userCommandsByName) Len() int {
+	return len(s)
+}
+
+// Less compares the names of two commands.
+func (s userCommandsByName) Less(i, j int) bool {
+	return s[i].name < s[j].name
+}
+
+// Swap swaps two commands.
+func (s userCommandsByName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// allCommandNames retrieves the names of all built-in and user commands.
+func (g *Golem) allCommandNames() []string {
+	userCommands := g.sortedUserCommands()
+	ret := make([]string, len(commandNames), len(commandNames)+len(userCommands))
+	copy(ret, commandNames)
+	for _, c := range userCommands {
+		ret = append(ret, c.name)
+	}
+	return ret
+}
+
+// runUserCommand runs a user command with the given arguments.
+func runUserCommand(
+	w *Window,
+	g *Golem,
+	c *userCommand,
+	args []string) error {
+
+	if !c.acceptsArgs(len(args)) {
+		return fmt.Errorf(
+			"Command %v expects %v arguments, got %d.",
+			c.name,
+			c.nargs,
+			len(args))
+	}
+	var err error
+	if atomic.AddInt32(&g.userCommandDepth, 1) > maxUserCommandDepth {
+		err = fmt.Errorf("User command recursion too deep in %v.", c.name)
+	} else {
+		err = execCmd(w, g, c.expand(args))
+	}
+	atomic.AddInt32(&g.userCommandDepth, -1)
+	return err
+}
+
+// expandAlias expands an alias in an already split command.
+//
+// ok is false if the command is not an alias.
+func (g *Golem) expandAlias(parts []string) (expanded []string, ok bool, err error) {
+	c, ok := g.userCommand(parts[0])
+	if !ok || !c.alias {
+		return parts, false, nil
+	}
+	expanded, err = shellwords.Parse(c.expand(parts[1:]))
+	if err == nil && len(expanded) == 0 {
+		err = fmt.Errorf("Alias %v is empty.", c.name)
+	}
+	return expanded, true, err
+}
+
+// showUserCommands displays the given user commands.
+func (w *Window) showUserCommands(cs []*userCommand) {
+	strs := make([]string, len(cs))
+	for i, c := range cs {
+		strs[i] = c.String()
+	}
+	msg := strings.Join(strs, "  ")
+	if msg == "" {
+		msg = "No user commands."
+	}
+	w.setState(cmd.NewStatusMode(w.State, states.StatusSubstateMinor, msg))
+}
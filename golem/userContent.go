@@ -0,0 +1,410 @@
+package golem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/golem/states"
+	"github.com/tkerber/golem/webkit"
+)
+
+// A userContentHeader is the metadata of a user style or script, as
+// specified in its Greasemonkey-style header.
+type userContentHeader struct {
+	name     string
+	matches  []string
+	excludes []string
+	runAt    string
+	noFrames bool
+}
+
+// A userContent is a single user style sheet or user script.
+type userContent struct {
+	file    string
+	source  string
+	header  *userContentHeader
+	enabled bool
+}
+
+// userContents manages all user style sheets and scripts.
+type userContents struct {
+	styles  []*userContent
+	scripts []*userContent
+	mutex   *sync.Mutex
+}
+
+// parseUserContentHeader parses the metadata header of a user style sheet or
+// script.
+//
+// The header is delimited by lines containing "==UserScript==" and
+// "==/UserScript==" (or "==UserStyle==" and "==/UserStyle==" respectively),
+// and each line in it of the form "@key value" sets a value. Leading comment
+// characters are ignored. The following keys are recognized:
+//
+// @name is the display name of the content.
+//
+// @match adds a pattern the content is applied to. Patterns use webkit's
+// syntax, e.g. "*://*.example.com/*".
+//
+// @include adds a Greasemonkey glob the content is applied to, e.g.
+// "http*://*.example.com/*", which is translated to webkit's syntax.
+//
+// @exclude adds a glob the content is not applied to.
+//
+// @run-at is one of document-start, document-end or document-idle. It only
+// affects scripts.
+//
+// @noframes causes content to only be applied to the top frame.
+//
+// An error is returned if an @include or @exclude glob can't be translated.
+func parseUserContentHeader(
+	source string,
+	startTag string,
+	endTag string) (*userContentHeader, error) {
+
+	h := &userContentHeader{}
+	inHeader := false
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, endTag) {
+			break
+		} else if strings.Contains(line, startTag) {
+			inHeader = true
+			continue
+		} else if !inHeader {
+			continue
+		}
+		line = strings.TrimLeft(line, "/* \t")
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		fields := strings.Fields(line)
+		key := fields[0][1:]
+		value := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		switch key {
+		case "name":
+			h.name = value
+		case "match":
+			h.matches = append(h.matches, value)
+		case "include":
+			patterns, err := globToMatchPatterns(value)
+			if err != nil {
+				return nil, err
+			}
+			h.matches = append(h.matches, patterns...)
+		case "exclude":
+			patterns, err := globToMatchPatterns(value)
+			if err != nil {
+				return nil, err
+			}
+			h.excludes = append(h.excludes, patterns...)
+		case "run-at":
+			h.runAt = value
+		case "noframes":
+			h.noFrames = true
+		}
+	}
+	return h, nil
+}
+
+// globToMatchPatterns translates a Greasemonkey glob, in which "*" matches
+// any string, into webkit match patterns.
+//
+// Only globs whose host is "*", "*.DOMAIN" or free of wildcards can be
+// translated. Their scheme may be "*", "http*" (translated to both http and
+// https) or free of wildcards, and is "*" if the glob starts with "*" and
+// has none. Match patterns are themselves valid globs, and are left as they
+// are.
+func globToMatchPatterns(glob string) ([]string, error) {
+	if glob == "*" {
+		return []string{"*://*/*"}, nil
+	}
+	var schemes []string
+	rest := glob
+	if i := strings.Index(glob, "://"); i >= 0 {
+		scheme := glob[:i]
+		rest = glob[i+len("://"):]
+		switch {
+		case scheme == "*":
+			schemes = []string{"*"}
+		case scheme == "http*":
+			schemes = []string{"http", "https"}
+		case scheme != "" && !strings.Contains(scheme, "*"):
+			schemes = []string{scheme}
+		}
+	} else if strings.HasPrefix(glob, "*") {
+		schemes = []string{"*"}
+	}
+	if schemes == nil {
+		return nil, fmt.Errorf("Unsupported pattern: '%v'", glob)
+	}
+	host := rest
+	path := "/"
+	if i := strings.Index(rest, "/"); i >= 0 {
+		host = rest[:i]
+		path = rest[i:]
+	} else if strings.HasSuffix(host, "*") && host != "*" {
+		host = strings.TrimSuffix(host, "*")
+		path = "/*"
+	}
+	wildHost := strings.Contains(strings.TrimPrefix(host, "*."), "*")
+	if host != "*" && (wildHost || host == "" && schemes[0] != "file") {
+		return nil, fmt.Errorf("Unsupported pattern: '%v'", glob)
+	}
+	patterns := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		patterns[i] = scheme + "://" + host + path
+	}
+	return patterns, nil
+}
+
+// loadUserContentDir loads all user content with the given extension from a
+// directory.
+//
+// Files which were loaded previously retain their enabled state; new files
+// are enabled. Files which can't be read or parsed are skipped, and the error
+// logged in the given window.
+func loadUserContentDir(
+	w *Window,
+	dir string,
+	ext string,
+	startTag string,
+	endTag string,
+	prev []*userContent) ([]*userContent, error) {
+
+	enabled := make(map[string]bool, len(prev))
+	for _, c := range prev {
+		enabled[c.file] = c.enabled
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	ret := make([]*userContent, 0, len(matches))
+	for _, path := range matches {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			w.logErrorf("Failed to read user content '%v': %v", path, err)
+			continue
+		}
+		source := string(data)
+		header, err := parseUserContentHeader(source, startTag, endTag)
+		if err != nil {
+			w.logErrorf("Failed to parse user content '%v': %v", path, err)
+			continue
+		}
+		file := filepath.Base(path)
+		e, ok := enabled[file]
+		ret = append(ret, &userContent{
+			file,
+			source,
+			header,
+			e || !ok,
+		})
+	}
+	return ret, nil
+}
+
+// loadUserContent (re-)reads all user style sheets and scripts from the
+// profile's userstyles and userscripts directories, and applies them.
+//
+// Errors in individual style sheets and scripts are logged in the given
+// window, and the remaining ones loaded regardless.
+func (g *Golem) loadUserContent(w *Window) error {
+	g.userContents.mutex.Lock()
+	styles, err := loadUserContentDir(
+		w,
+		g.files.userstylesDir,
+		".css",
+		"==UserStyle==",
+		"==/UserStyle==",
+		g.userContents.styles)
+	if err != nil {
+		g.userContents.mutex.Unlock()
+		return err
+	}
+	scripts, err := loadUserContentDir(
+		w,
+		g.files.userscriptsDir,
+		".js",
+		"==UserScript==",
+		"==/UserScript==",
+		g.userContents.scripts)
+	if err != nil {
+		g.userContents.mutex.Unlock()
+		return err
+	}
+	g.userContents.styles = styles
+	g.userContents.scripts = scripts
+	g.userContents.mutex.Unlock()
+	return g.applyUserContent(w)
+}
+
+// applyUserContent replaces the content of the user content manager with
+// golem's own style sheets, and all enabled user style sheets and scripts.
+//
+// User style sheets and scripts which fail to load are skipped, and the error
+// logged in the given window.
+func (g *Golem) applyUserContent(w *Window) error {
+	g.userContents.mutex.Lock()
+	defer g.userContents.mutex.Unlock()
+
+	ucm := g.userContentManager
+	ucm.RemoveAllStyleSheets()
+	ucm.RemoveAllScripts()
+
+	css, err := webkit.NewUserStyleSheet(
+		scrollbarHideCSS,
+		webkit.UserContentInjectTopFrame,
+		webkit.UserStyleLevelUser,
+		nil,
+		nil)
+	if err != nil {
+		return err
+	}
+	ucm.AddStyleSheet(css)
+	css, err = webkit.NewUserStyleSheet(
+		injectedHTMLCSS,
+		webkit.UserContentInjectAllFrames,
+		webkit.UserStyleLevelAuthor,
+		nil,
+		nil)
+	if err != nil {
+		return err
+	}
+	ucm.AddStyleSheet(css)
+
+	for _, c := range g.userContents.styles {
+		if !c.enabled {
+			continue
+		}
+		frames := webkit.UserContentInjectAllFrames
+		if c.header.noFrames {
+			frames = webkit.UserContentInjectTopFrame
+		}
+		css, err := webkit.NewUserStyleSheet(
+			c.source,
+			frames,
+			webkit.UserStyleLevelUser,
+			c.header.matches,
+			c.header.excludes)
+		if err != nil {
+			w.logErrorf("Failed to load user style '%v': %v", c.file, err)
+			continue
+		}
+		ucm.AddStyleSheet(css)
+	}
+	for _, c := range g.userContents.scripts {
+		if !c.enabled {
+			continue
+		}
+		frames := webkit.UserContentInjectAllFrames
+		if c.header.noFrames {
+			frames = webkit.UserContentInjectTopFrame
+		}
+		injectTime := webkit.UserScriptInjectAtDocumentEnd
+		if c.header.runAt == "document-start" {
+			injectTime = webkit.UserScriptInjectAtDocumentStart
+		}
+		js, err := webkit.NewUserScript(
+			c.source,
+			frames,
+			injectTime,
+			c.header.matches,
+			c.header.excludes)
+		if err != nil {
+			w.logErrorf("Failed to load user script '%v': %v", c.file, err)
+			continue
+		}
+		ucm.AddScript(js)
+	}
+	return nil
+}
+
+// userContentCommand implements the :styles and :scripts-user commands.
+//
+// The commands take the forms:
+//
+// CMD - lists all entries, prefixing disabled ones with "-".
+//
+// CMD enable|disable|toggle NAME... - changes whether entries are applied.
+//
+// CMD reload - re-reads all entries from disk.
+//
+// NAME is either the file name of an entry, or its @name.
+func (g *Golem) userContentCommand(
+	w *Window,
+	args []string,
+	entries func() []*userContent) error {
+
+	if len(args) == 1 {
+		g.userContents.mutex.Lock()
+		names := make([]string, 0)
+		for _, c := range entries() {
+			if c.enabled {
+				names = append(names, c.file)
+			} else {
+				names = append(names, "-"+c.file)
+			}
+		}
+		g.userContents.mutex.Unlock()
+		if w == nil {
+			return nil
+		}
+		msg := strings.Join(names, " ")
+		if msg == "" {
+			msg = "No entries."
+		}
+		w.setState(cmd.NewStatusMode(w.State, states.StatusSubstateMinor, msg))
+		return nil
+	}
+	switch args[1] {
+	case "reload":
+		if len(args) != 2 {
+			return invalidArgs(args)
+		}
+		if err := g.loadUserContent(w); err != nil {
+			return fmt.Errorf("Failed to load user content: %v", err)
+		}
+		return nil
+	case "enable", "disable", "toggle":
+		if len(args) < 3 {
+			return invalidArgs(args)
+		}
+	default:
+		return invalidArgs(args)
+	}
+	var errs errorList
+	g.userContents.mutex.Lock()
+	for _, name := range args[2:] {
+		found := false
+		for _, c := range entries() {
+			if c.file != name && c.header.name != name {
+				continue
+			}
+			found = true
+			switch args[1] {
+			case "enable":
+				c.enabled = true
+			case "disable":
+				c.enabled = false
+			case "toggle":
+				c.enabled = !c.enabled
+			}
+		}
+		if !found {
+			errs.add(fmt.Errorf("No such entry: '%v'", name))
+		}
+	}
+	g.userContents.mutex.Unlock()
+	if err := g.applyUserContent(w); err != nil {
+		errs.add(fmt.Errorf("Failed to apply user content: %v", err))
+	}
+	return errs.err()
+}
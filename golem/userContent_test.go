@@ -0,0 +1,73 @@
+package golem
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlobToMatchPatterns(t *testing.T) {
+	for glob, expected := range map[string][]string{
+		"http*://*.example.com/*": {
+			"http://*.example.com/*",
+			"https://*.example.com/*",
+		},
+		"*":                      {"*://*/*"},
+		"*.example.com/*":        {"*://*.example.com/*"},
+		"https://example.com/a*": {"https://example.com/a*"},
+		"http://example.com":     {"http://example.com/"},
+		"http://example.com*":    {"http://example.com/*"},
+		"file:///home/*":         {"file:///home/*"},
+		// Match patterns are left as they are.
+		"*://*.example.com/*": {"*://*.example.com/*"},
+	} {
+		patterns, err := globToMatchPatterns(glob)
+		if err != nil {
+			t.Errorf("Failed to translate '%s': %v", glob, err)
+		} else if !reflect.DeepEqual(patterns, expected) {
+			t.Errorf("'%s' translated to %q, expected %q.", glob, patterns, expected)
+		}
+	}
+	for _, glob := range []string{
+		"http://www.example.*/*",
+		"*example.com*",
+		"/^https?:\\/\\/example\\.com/",
+		"example.com/*",
+		"ht*p://example.com/",
+	} {
+		if patterns, err := globToMatchPatterns(glob); err == nil {
+			t.Errorf("'%s' translated to %q, expected an error.", glob, patterns)
+		}
+	}
+}
+
+func TestParseUserContentHeaderIncludes(t *testing.T) {
+	source := `// ==UserScript==
+// @name    Example
+// @include http*://*.example.com/*
+// @match   *://example.org/*
+// @exclude http://*.example.com/private/*
+// ==/UserScript==`
+	h, err := parseUserContentHeader(source, "==UserScript==", "==/UserScript==")
+	if err != nil {
+		t.Fatalf("Failed to parse header: %v", err)
+	}
+	matches := []string{
+		"http://*.example.com/*",
+		"https://*.example.com/*",
+		"*://example.org/*",
+	}
+	if !reflect.DeepEqual(h.matches, matches) {
+		t.Errorf("Matches are %q, expected %q.", h.matches, matches)
+	}
+	excludes := []string{"http://*.example.com/private/*"}
+	if !reflect.DeepEqual(h.excludes, excludes) {
+		t.Errorf("Excludes are %q, expected %q.", h.excludes, excludes)
+	}
+
+	source = "// ==UserScript==\n// @include http://www.example.*/\n" +
+		"// ==/UserScript=="
+	_, err = parseUserContentHeader(source, "==UserScript==", "==/UserScript==")
+	if err == nil {
+		t.Error("Untranslatable @include was accepted.")
+	}
+}
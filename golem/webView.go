@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"html"
 	"net/url"
+	"sync"
 	"unsafe"
 
 	"github.com/conformal/gotk3/gdk"
 	"github.com/conformal/gotk3/glib"
 	"github.com/conformal/gotk3/gtk"
+	"github.com/tkerber/golem/api"
 	"github.com/tkerber/golem/cmd"
 	"github.com/tkerber/golem/golem/ui"
 	ggtk "github.com/tkerber/golem/gtk"
@@ -28,11 +30,23 @@ type webView struct {
 	height        int64
 	parent        *Golem
 	settings      *webkit.Settings
+	baseSettings  *webkit.Settings
 	window        *Window
 	tabUI         *ui.TabBarTab
 	fullscreen    bool
 	searchForward bool
 	handles       []glib.SignalHandle
+	// hints are the hints displayed, if any.
+	hints      *hintsSet
+	hintsMutex *sync.Mutex
+	// scrollAnim is the smooth scroll in progress, if any.
+	scrollAnim  *scrollAnimation
+	scrollMutex *sync.Mutex
+	// pendingMark is a mark on another page jumped to, which is scrolled to
+	// once that page has loaded.
+	pendingMark      *mark
+	pendingMarkLine  bool
+	pendingMarkMutex *sync.Mutex
 }
 
 // newWebView creates a new webView.
@@ -47,11 +61,12 @@ func (w *Window) newWebView() (*webView, error) {
 
 	// Each WebView gets it's own settings, to allow toggling settings on a
 	// per tab and/or per window basis.
-	newSettings := w.defaultSettings.Clone()
+	baseSettings := w.defaultSettings.Clone()
+	newSettings := baseSettings.Clone()
 
 	wv.SetSettings(newSettings)
 
-	webExten := new(webExtension)
+	webExten := newWebExtension()
 
 	ret := &webView{
 		wv,
@@ -62,11 +77,19 @@ func (w *Window) newWebView() (*webView, error) {
 		0,
 		w.parent,
 		newSettings,
+		baseSettings,
 		w,
 		nil,
 		false,
 		true,
 		make([]glib.SignalHandle, 0, 4),
+		nil,
+		new(sync.Mutex),
+		nil,
+		new(sync.Mutex),
+		nil,
+		false,
+		new(sync.Mutex),
 	}
 
 	// Attach to the create signal, which creates new tabs on demand.
@@ -183,8 +206,18 @@ func (w *Window) newWebView() (*webView, error) {
 	handle, err = ret.WebView.Connect("load-changed",
 		func(_ interface{}, e C.WebKitLoadEvent) {
 			switch e {
+			case C.WEBKIT_LOAD_STARTED:
+				ret.applySiteSettings()
+				ret.fireAutocmds(autocmdLoadStarted)
+			case C.WEBKIT_LOAD_COMMITTED:
+				// The uri may have changed through redirects.
+				ret.applySiteSettings()
+				ret.applyPassThrough()
 			case C.WEBKIT_LOAD_FINISHED:
 				go ret.parent.updateHistory(wv.GetURI(), wv.GetTitle())
+				ret.fireAutocmds(autocmdLoadFinished)
+				ret.emitEvent(api.EventLoadFinished)
+				ret.applyPendingMark()
 			}
 		})
 	if err == nil {
@@ -196,6 +229,7 @@ func (w *Window) newWebView() (*webView, error) {
 			if ret.tabUI != nil {
 				ret.tabUI.SetTitle(wv.GetTitle())
 			}
+			ret.emitEvent(api.EventTitleChanged)
 		})
 	if err == nil {
 		ret.handles = append(ret.handles, handle)
@@ -213,6 +247,15 @@ func (w *Window) newWebView() (*webView, error) {
 	if err == nil {
 		ret.handles = append(ret.handles, handle)
 	}
+	// The page process is replaced after a crash; calls wait for the new
+	// web extension to connect.
+	handle, err = wv.Connect("web-process-crashed", func() bool {
+		ret.webExtension.detach(nil)
+		return false
+	})
+	if err == nil {
+		ret.handles = append(ret.handles, handle)
+	}
 	// fullscreen handles
 	handle, err = wv.Connect("enter-fullscreen", func() bool {
 		ret.fullscreen = true
@@ -231,8 +274,9 @@ func (w *Window) newWebView() (*webView, error) {
 
 	// Add webview to golem and return.
 	w.parent.wMutex.Lock()
-	defer w.parent.wMutex.Unlock()
 	w.parent.webViews[ret.id] = ret
+	w.parent.wMutex.Unlock()
+	ret.emitEvent(api.EventTabOpened)
 	return ret, nil
 }
 
@@ -289,6 +333,7 @@ func (wv *webView) close() {
 	wv.parent.wMutex.Lock()
 	delete(wv.parent.webViews, wv.id)
 	wv.parent.wMutex.Unlock()
+	wv.webExtension.detach(nil)
 	ggtk.GlibMainContextInvoke(wv.detach)
 	schedGc()
 }
@@ -0,0 +1,70 @@
+package golem
+
+import (
+	"time"
+
+	"github.com/tkerber/golem/cmd"
+	"github.com/tkerber/golem/gtk"
+)
+
+// bindingTimeoutDuration retrieves the binding timeout as a duration.
+func (g *Golem) bindingTimeoutDuration() time.Duration {
+	return time.Duration(g.bindingTimeout) * time.Millisecond
+}
+
+// scheduleWhichKey displays the continuations of an incomplete key sequence
+// in the completion bar, if the window is still in the same state after the
+// binding timeout.
+//
+// Key sequences which are themselves bound are executed once the timeout
+// passes, and therefore never displayed.
+func (w *Window) scheduleWhichKey(s *cmd.NormalMode) {
+	if !w.parent.whichKey ||
+		len(s.CurrentKeys) == 0 ||
+		s.CurrentTree.Binding != nil {
+
+		return
+	}
+	time.AfterFunc(w.parent.bindingTimeoutDuration(), func() {
+		if w.State != s {
+			return
+		}
+		var strs []string
+		var compStates []cmd.State
+		cancelled := false
+		w.parent.completeNormalMode(
+			s,
+			&cancelled,
+			func(bool) {},
+			&compStates,
+			&strs)
+		if len(strs) == 0 {
+			return
+		}
+		gtk.GlibMainContextInvoke(func() {
+			if w.State != s {
+				return
+			}
+			w.whichKey = s
+			w.Window.CompletionBar.UpdateCompletions(strs)
+			w.Window.CompletionBar.UpdateAt(-1)
+			w.Window.CompletionBar.Container.Show()
+		})
+	})
+}
+
+// hideWhichKey hides the continuations displayed by scheduleWhichKey, if the
+// window has left the state they were displayed for.
+func (w *Window) hideWhichKey() {
+	if w.whichKey == nil || w.whichKey == w.State {
+		return
+	}
+	w.whichKey = nil
+	// Completion takes over the completion bar.
+	if _, ok := w.State.(*cmd.CompletionMode); ok {
+		return
+	}
+	w.Window.CompletionBar.UpdateCompletions(nil)
+	w.Window.CompletionBar.UpdateAt(0)
+	gtk.GlibMainContextInvoke(w.Window.CompletionBar.Container.Hide)
+}
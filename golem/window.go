@@ -8,7 +8,9 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -72,11 +74,28 @@ type Window struct {
 	parent              *Golem
 	builtins            cmd.Builtins
 	bindings            map[cmd.Substate]*cmd.BindingTree
+	modeBindings        map[cmd.Mode]*cmd.BindingTree
 	activeSignalHandles []*signalHandle
 	windowSignalHandles []*signalHandle
 	timeoutChan         chan bool
 	fullscreenHidingUI  bool
 	wMutex              *sync.Mutex
+	// targetWebView, if set, is used instead of the current web view as the
+	// target of commands. This is used to run autocommands in background
+	// tabs.
+	targetWebView *webView
+	// recording is the macro currently being recorded, or nil if none is.
+	recording *macroRecording
+	// replayDepth is the number of macro replays currently in progress.
+	replayDepth int
+	// lastChange repeats the last binding or command run.
+	lastChange func(*int)
+	// whichKey is the state whose continuations are currently displayed in
+	// the completion bar, if any.
+	whichKey *cmd.NormalMode
+	// lastJump is the position in the window before its last jump, if any.
+	// Unlike the persisted jump mark, it is kept across pages.
+	lastJump *mark
 }
 
 // keyTimeout is the timeout between two key presses where no key press is
@@ -116,11 +135,27 @@ func (w *Window) setState(state cmd.State) {
 			}
 		}
 	}
+	// End caret and visual mode.
+	if cs, ok := w.State.(states.CaretState); ok {
+		if cs2, ok := state.(states.CaretState); !ok ||
+			cs2.Session() != cs.Session() {
+
+			if cont, ok := state.(cmd.ContainerState); !ok ||
+				cont.ChildState() != w.State {
+
+				cs.Session().End()
+			}
+		}
+	}
 	w.State = state
+	w.hideWhichKey()
 	// Update completion bar
 	if cm, ok := w.State.(*cmd.CompletionMode); ok {
 		w.Window.CompletionBar.UpdateAt(cm.CurrentCompletion)
 	}
+	if nm, ok := w.State.(*cmd.NormalMode); ok {
+		w.scheduleWhichKey(nm)
+	}
 	w.UpdateState(w.State)
 }
 
@@ -138,11 +173,18 @@ func (g *Golem) initWindow() *Window {
 		g,
 		nil,
 		make(map[cmd.Substate]*cmd.BindingTree),
+		make(map[cmd.Mode]*cmd.BindingTree),
 		make([]*signalHandle, 0),
 		make([]*signalHandle, 0, 5),
 		make(chan bool, 1),
 		false,
 		new(sync.Mutex),
+		nil,
+		nil,
+		0,
+		nil,
+		nil,
+		nil,
 	}
 }
 
@@ -168,16 +210,26 @@ func (w *Window) initWindowWebView(wv *webView) error {
 
 	w.builtins = builtinsFor(w)
 
-	w.setState(cmd.NewState(w.bindings, w.setState, func() cmd.State {
-		return w.State
-	}, w.completeState))
+	w.setState(cmd.NewState(
+		w.bindings,
+		w.modeBindings,
+		w.parent.cmdHistory,
+		w.parent.bindingTimeoutDuration,
+		w.setState,
+		func() cmd.State {
+			return w.State
+		},
+		w.completeState))
 
 	w.rebuildBindings()
 	w.rebuildQuickmarks()
 
 	w.parent.wMutex.Lock()
 	w.parent.windows = append(w.parent.windows, w)
+	rcErrors := w.parent.rcErrors
+	w.parent.rcErrors = nil
 	w.parent.wMutex.Unlock()
+	w.parent.showRcErrors(w, rcErrors)
 
 	w.reconnectWebViewSignals()
 
@@ -340,42 +392,68 @@ func (w *Window) handleKeyPress(uiWin *gtk.Window, e *gdk.Event) bool {
 			return false
 		}
 
-		oldState := w.State
-		newState, ret := w.State.ProcessKeyPress(key)
-		// If this is not the case, a state change command was issued. This
-		// takes precedence.
-		if oldState == w.State {
-			w.setState(newState)
-		} else if cont, ok := w.State.(cmd.ContainerState); ok &&
-			cont.ChildState() == oldState {
-			w.setState(cont.SwapChildState(newState))
-		}
+		rec := w.recording
+		before := w.State
+		ret := w.processKey(key)
+		w.recordKey(rec, before, key)
 		return ret
 	default:
 		return false
 	}
 }
 
+// processKey passes a single key press to the current state.
+//
+// It returns whether the key press was swallowed.
+func (w *Window) processKey(key cmd.RealKey) bool {
+	oldState := w.State
+	newState, ret := w.State.ProcessKeyPress(key)
+	// If this is not the case, a state change command was issued. This
+	// takes precedence.
+	if oldState == w.State {
+		w.setState(newState)
+	} else if cont, ok := w.State.(cmd.ContainerState); ok &&
+		cont.ChildState() == oldState {
+		w.setState(cont.SwapChildState(newState))
+	}
+	return ret
+}
+
 // rebuildBindings rebuilds the bindings for this window.
 func (w *Window) rebuildBindings() {
-	bindings, errs := cmd.ParseRawBindings(
-		w.parent.rawBindings,
-		w.builtins,
-		w.runCmd)
-	if errs != nil {
-		for _, err := range errs {
-			w.logErrorf("Error: Failed to parse key bindings: %v", err)
+	for _, mode := range []cmd.Mode{
+		cmd.ModeNormal,
+		cmd.ModeInsert,
+		cmd.ModeCommandLine,
+		cmd.ModeHints,
+		cmd.ModePassThrough} {
+
+		bindings, errs := cmd.ParseRawBindings(
+			w.parent.rawBindings[mode],
+			w.builtins,
+			w.runCmd)
+		if errs != nil {
+			for _, err := range errs {
+				w.logErrorf("Error: Failed to parse key bindings: %v", err)
+			}
+			(*Window)(nil).logError("Faulty bindings have been dropped.")
 		}
-		(*Window)(nil).logError("Faulty bindings have been dropped.")
-	}
-	bindingTree, errs := cmd.NewBindingTree(bindings)
-	if errs != nil {
-		for _, err := range errs {
-			w.logErrorf("Error: Failed to parse key bindings: %v", err)
+		if mode == cmd.ModeNormal {
+			bindings = w.repeatableBindings(bindings)
+		}
+		bindingTree, errs := cmd.NewBindingTree(bindings)
+		if errs != nil {
+			for _, err := range errs {
+				w.logErrorf("Error: Failed to parse key bindings: %v", err)
+			}
+			(*Window)(nil).logError("Faulty bindings have been dropped.")
+		}
+		if mode == cmd.ModeNormal {
+			w.bindings[states.NormalSubstateNormal] = bindingTree
+		} else {
+			w.modeBindings[mode] = bindingTree
 		}
-		(*Window)(nil).logError("Faulty bindings have been dropped.")
 	}
-	w.bindings[states.NormalSubstateNormal] = bindingTree
 }
 
 // rebuildQuickmarks rebuild the quickmark bindings for this window.
@@ -436,6 +514,9 @@ func (w *Window) quickmarkCallback(keys []cmd.Key, _ *int, s cmd.Substate) {
 
 // getWebView retrieves the currently active webView.
 func (w *Window) getWebView() *webView {
+	if w.targetWebView != nil {
+		return w.targetWebView
+	}
 	return w.webViews[w.currentWebView]
 }
 
@@ -503,34 +584,122 @@ func (w *Window) reconnectWebViewSignals() {
 
 // runCmd runs a command.
 func (w *Window) runCmd(cmd string) {
+	w.setLastChange(func(_ *int) {
+		runCmd(w, w.parent, cmd)
+	})
 	runCmd(w, w.parent, cmd)
 }
 
-// runCmd runs a command.
+// runCmd runs a command, and logs any errors which occur.
 func runCmd(w *Window, g *Golem, command string) {
+	w.logCmdError(execCmd(w, g, command))
+}
+
+// execCmd runs a command, and returns the errors which occur while it runs.
+//
+// The command may be preceded by a range of tabs to act on, e.g.
+// "3,5tabclose". A range on its own goes to the first tab of the range.
+func execCmd(w *Window, g *Golem, command string) error {
 	// Space followed optionally by a line comment (starting with ")
 	if blankLineRegex.MatchString(command) {
-		return
+		return nil
+	}
+
+	i, j, command, hasRange, err := w.parseCmdRange(command)
+	if err != nil {
+		return fmt.Errorf("Error: Failed to parse command range: %v", err)
+	}
+	if hasRange && blankLineRegex.MatchString(command) {
+		if err := w.TabGo(i); err != nil {
+			return fmt.Errorf("Failed to switch tab: %v", err)
+		}
+		return nil
 	}
 
 	parts, err := shellwords.Parse(command)
 	if err != nil {
-		w.logErrorf("Error: Failed to parse command '%v': %v", command, err)
-		return
+		return fmt.Errorf(
+			"Error: Failed to parse command '%v': %v", command, err)
 	}
 	if len(parts[0]) == 0 {
 		parts = parts[1:len(parts)]
 	}
+	if hasRange {
+		return runCmdRange(w, g, parts, i, j)
+	}
+	return runCmdParts(w, g, parts)
+}
+
+// runCmdParts runs an already split command.
+//
+// Unless the command defers interpolation, all arguments are interpolated
+// before the command is executed. The arguments of user commands are instead
+// interpolated when the expanded command is run, so that interpolated values
+// are never interpolated again.
+func runCmdParts(w *Window, g *Golem, parts []string) error {
 	f, ok := commands[parts[0]]
-	if ok {
+	if c, isUser := g.userCommand(parts[0]); !ok && isUser {
 		if PrintCommands {
-			log.Printf("Running command '%v'.", command)
+			log.Printf("Running user command '%v'.", strings.Join(parts, " "))
 		}
-		f(w, g, parts)
-	} else {
-		w.logErrorf("Error: Failed to run command '%v': No such command.",
-			command)
+		return runUserCommand(w, g, c, parts[1:])
+	}
+	if !ok {
+		return fmt.Errorf(
+			"Error: Failed to run command '%v': No such command.",
+			strings.Join(parts, " "))
+	}
+	parts = interpolateParts(w, g, parts)
+	if PrintCommands {
+		log.Printf("Running command '%v'.", strings.Join(parts, " "))
 	}
+	return f(w, g, parts)
+}
+
+// runCmdRange runs an already split command on the tabs [i, j).
+func runCmdRange(w *Window, g *Golem, parts []string, i, j int) error {
+	expanded, isAlias, err := g.expandAlias(parts)
+	if err != nil {
+		return fmt.Errorf("Error: Failed to expand alias: %v", err)
+	} else if isAlias {
+		// Aliases may refer to each other, or themselves.
+		if atomic.AddInt32(&g.userCommandDepth, 1) > maxUserCommandDepth {
+			err = fmt.Errorf("User command recursion too deep in %v.", parts[0])
+		} else {
+			err = runCmdRange(w, g, expanded, i, j)
+		}
+		atomic.AddInt32(&g.userCommandDepth, -1)
+		return err
+	}
+	f, ok := rangeCommands[parts[0]]
+	if !ok {
+		return fmt.Errorf(
+			"Error: Failed to run command '%v': No range allowed.",
+			strings.Join(parts, " "))
+	}
+	parts = interpolateParts(w, g, parts)
+	if PrintCommands {
+		log.Printf(
+			"Running command '%v' on tabs %d to %d.",
+			strings.Join(parts, " "),
+			i+1,
+			j)
+	}
+	return f(w, g, parts, i, j)
+}
+
+// interpolateParts interpolates the arguments of a split command, unless the
+// command defers interpolation.
+func interpolateParts(w *Window, g *Golem, parts []string) []string {
+	if uninterpolatedCommands[parts[0]] {
+		return parts
+	}
+	interpolated := make([]string, len(parts))
+	interpolated[0] = parts[0]
+	for i, part := range parts[1:] {
+		interpolated[i+1] = g.interpolate(w, part)
+	}
+	return interpolated
 }
 
 // addDownload adds an active download.
@@ -552,6 +721,64 @@ func (w *Window) logError(err string) {
 	Errlog.Println(err)
 }
 
+// logCmdError logs (and displays) an error returned by a command, if any.
+//
+// Of a list of errors, all are logged, but only the first is displayed, along
+// with the number of further errors.
+func (w *Window) logCmdError(err error) {
+	errs, ok := err.(errorList)
+	if !ok {
+		if err != nil {
+			w.logError(err.Error())
+		}
+		return
+	}
+	if len(errs) == 0 {
+		return
+	}
+	for _, err := range errs[1:] {
+		Errlog.Println(err)
+	}
+	msg := errs[0].Error()
+	if len(errs) > 1 {
+		msg = fmt.Sprintf("%s (and %d more errors)", msg, len(errs)-1)
+	}
+	w.logError(msg)
+}
+
+// An errorList is a list of errors which occurred while running a command,
+// where the command continued after each.
+type errorList []error
+
+// Error joins the messages of all errors.
+func (errs errorList) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// add adds an error to the list, unless it is nil. The errors of a list are
+// added individually.
+func (errs *errorList) add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case errorList:
+		*errs = append(*errs, err...)
+	default:
+		*errs = append(*errs, err)
+	}
+}
+
+// err returns the list as an error, or nil if it is empty.
+func (errs errorList) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
 // logErrorf logs (and displays) an errormessage, supplies as a format string
 // with arguments.
 func (w *Window) logErrorf(fmtStr string, args ...interface{}) {
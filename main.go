@@ -10,10 +10,12 @@ import (
 	"os"
 	"regexp"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/conformal/gotk3/gtk"
 	"github.com/mattn/go-shellwords"
+	"github.com/tkerber/golem/api"
 	"github.com/tkerber/golem/golem"
 	"github.com/ugorji/go/codec"
 )
@@ -48,6 +50,15 @@ func main() {
 		"default",
 		"Sets the profile to use. Each profile saves its data seperately, "+
 			"and uses a seperate instance of Golem.")
+	var token string
+	flag.StringVar(
+		&token,
+		"token",
+		os.Getenv("GOLEM_TOKEN"),
+		"Sets the token to present to a running golem. Defaults to "+
+			"$GOLEM_TOKEN, or the token the running golem wrote next to its "+
+			"socket.")
+	flag.Usage = usage
 	flag.Parse()
 	if !regexp.MustCompile(`^[a-zA-Z]\w*$`).MatchString(profile) {
 		fmt.Println("Please use a alphanumeric profile name starting with a letter.")
@@ -55,11 +66,61 @@ func main() {
 		return
 	}
 	args := flag.Args()
+	if token == "" {
+		// Without a running golem, there is no token file, and none is
+		// needed.
+		token, _ = api.ReadToken(socketFile(profile))
+	}
+
+	if len(args) > 0 {
+		if sub, ok := subcommands[args[0]]; ok {
+			acquireSocket(
+				profile,
+				func(l net.Listener) {
+					if sub.start == nil {
+						// Closing the listener removes the socket file,
+						// which would otherwise be left behind dead.
+						l.Close()
+						golem.Errlog.Printf(
+							"No instance of golem is running for profile '%s'.",
+							profile)
+						exitCode = exitNoInstance
+						return
+					}
+					uris, err := sub.start(args[1:])
+					if err != nil {
+						l.Close()
+						reportSubcommandError(args[0], err)
+						return
+					}
+					socketAcquired(l, profile, uris)
+				},
+				func(c net.Conn) { runSubcommand(c, token, args[0], args[1:]) })
+			return
+		}
+	}
 
 	acquireSocket(
 		profile,
 		func(l net.Listener) { socketAcquired(l, profile, args) },
-		func(c net.Conn) { socketFound(c, args) })
+		func(c net.Conn) { socketFound(c, token, args) })
+}
+
+// usage prints golem's command line usage.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: golem [-p PROFILE] [URI...]\n")
+	fmt.Fprintf(os.Stderr,
+		"       golem [-p PROFILE] [-token TOKEN] SUBCOMMAND [ARGS...]\n\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nSubcommands remotely control a running golem:\n\n")
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, subcommands[name].usage)
+	}
 }
 
 // socketAcquired is called when golem obtains ownership of the socket, and
@@ -119,10 +180,14 @@ func socketAcquired(l net.Listener, profile string, args []string) {
 
 // handshake performs golems msgpack-rpc client handshake with server:
 //
-// >>> json-rpc-client\0
+// >>> msgpack-rpc-client[:token]\0
 // <<< ok\0
-func handshake(c net.Conn) error {
-	_, err := c.Write([]byte("msgpack-rpc-client\u0000"))
+func handshake(c net.Conn, token string) error {
+	header := "msgpack-rpc-client"
+	if token != "" {
+		header += ":" + token
+	}
+	_, err := c.Write([]byte(header + "\u0000"))
 	if err != nil {
 		return err
 	}
@@ -139,8 +204,8 @@ func handshake(c net.Conn) error {
 // socketFound is executed when a socket occupied by a running golem instance
 // if found. It communicates with the running golem. (Note that the connection
 // if closed outwith this function)
-func socketFound(c net.Conn, args []string) {
-	err := handshake(c)
+func socketFound(c net.Conn, token string, args []string) {
+	err := handshake(c, token)
 	if err != nil {
 		golem.Errlog.Printf("Failed to establish connection: %v", err)
 		exitCode = 1
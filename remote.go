@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tkerber/golem/api"
+	"github.com/tkerber/golem/golem"
+)
+
+// Exit codes of remote control subcommands.
+const (
+	// exitFailure indicates that golem failed to carry out the request.
+	exitFailure = 1
+	// exitUsage indicates that the subcommand was used incorrectly.
+	exitUsage = 2
+	// exitNoInstance indicates that no instance of golem is running for the
+	// profile.
+	exitNoInstance = 3
+)
+
+// A subcommand remotely controls a running instance of golem.
+type subcommand struct {
+	usage string
+	// start, if set, is used to start golem if no instance is running,
+	// rather than failing. It returns the uris golem is started with.
+	start func(args []string) ([]string, error)
+	run   func(c *api.Client, args []string) error
+}
+
+// subcommands are the remote control subcommands golem accepts as its first
+// argument.
+var subcommands = map[string]*subcommand{
+	"exec":   {"[--tab ID] ':command'", nil, subcmdExec},
+	"tabs":   {"", nil, subcmdTabs},
+	"open":   {"[--window|--background] URI", startOpen, subcmdOpen},
+	"close":  {"TAB", nil, subcmdClose},
+	"reload": {"[TAB]", nil, subcmdReload},
+	"quit":   {"", nil, subcmdQuit},
+}
+
+// A usageError is an error in the arguments passed to a subcommand.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// newFlagSet creates a flag set for a subcommand, which doesn't print errors
+// itself.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+// parseTab parses a tab ID argument. If there is no argument, 0 (the current
+// tab) is returned.
+func parseTab(args []string) (uint64, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return 0, usageError(fmt.Sprintf("Invalid tab ID: '%s'", args[0]))
+		}
+		return id, nil
+	default:
+		return 0, usageError("Too many arguments.")
+	}
+}
+
+// runSubcommand runs a subcommand over a connection to a running golem.
+func runSubcommand(conn net.Conn, token, name string, args []string) {
+	sub := subcommands[name]
+	c, err := api.NewClient(conn, token)
+	if err != nil {
+		golem.Errlog.Printf("Failed to establish connection: %v", err)
+		exitCode = exitFailure
+		return
+	}
+	reportSubcommandError(name, sub.run(c, args))
+}
+
+// reportSubcommandError reports the error a subcommand failed with, if any,
+// and sets the exit code accordingly.
+func reportSubcommandError(name string, err error) {
+	if _, ok := err.(usageError); ok {
+		golem.Errlog.Printf("%v", err)
+		fmt.Fprintf(
+			os.Stderr,
+			"Usage: golem %s %s\n",
+			name,
+			subcommands[name].usage)
+		exitCode = exitUsage
+	} else if err != nil {
+		golem.Errlog.Printf("Failed to %s: %v", name, err)
+		exitCode = exitFailure
+	}
+}
+
+// subcmdExec runs a command in the current tab, or a given tab.
+func subcmdExec(c *api.Client, args []string) error {
+	fs := newFlagSet("exec")
+	tab := fs.Uint64("tab", 0, "")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if fs.NArg() != 1 {
+		return usageError("Expected exactly one command.")
+	}
+	return c.RunCommand(*tab, strings.TrimPrefix(fs.Arg(0), ":"))
+}
+
+// subcmdTabs prints all windows and their tabs as JSON.
+func subcmdTabs(c *api.Client, args []string) error {
+	if len(args) != 0 {
+		return usageError("Too many arguments.")
+	}
+	windows, err := c.Windows()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(windows)
+}
+
+// parseOpenArgs parses the arguments of the open subcommand.
+//
+// The uri is a single argument, which may be a search as on golem's own
+// command line, e.g. 'golem open "ddg golang"'.
+func parseOpenArgs(args []string) (req api.OpenRequest, err error) {
+	fs := newFlagSet("open")
+	fs.BoolVar(&req.Window, "window", false, "")
+	fs.BoolVar(&req.Background, "background", false, "")
+	if err := fs.Parse(args); err != nil {
+		return req, usageError(err.Error())
+	}
+	if req.Window && req.Background {
+		return req, usageError(
+			"Only one of --window and --background may be given.")
+	}
+	if fs.NArg() != 1 {
+		return req, usageError("Expected exactly one URI.")
+	}
+	req.URI = fs.Arg(0)
+	return req, nil
+}
+
+// startOpen starts golem with the uri of an open subcommand, if no instance
+// is running. The options only apply to a running instance.
+func startOpen(args []string) ([]string, error) {
+	req, err := parseOpenArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return []string{req.URI}, nil
+}
+
+// subcmdOpen opens a uri in a new tab, and prints the tab's ID.
+func subcmdOpen(c *api.Client, args []string) error {
+	req, err := parseOpenArgs(args)
+	if err != nil {
+		return err
+	}
+	id, err := c.Open(req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+// subcmdClose closes a tab.
+func subcmdClose(c *api.Client, args []string) error {
+	if len(args) != 1 {
+		return usageError("Expected exactly one tab ID.")
+	}
+	id, err := parseTab(args)
+	if err != nil {
+		return err
+	}
+	return c.CloseTab(id)
+}
+
+// subcmdReload reloads the current tab, or a given tab.
+func subcmdReload(c *api.Client, args []string) error {
+	id, err := parseTab(args)
+	if err != nil {
+		return err
+	}
+	return c.Reload(id)
+}
+
+// subcmdQuit quits golem.
+func subcmdQuit(c *api.Client, args []string) error {
+	if len(args) != 0 {
+		return usageError("Too many arguments.")
+	}
+	return c.Quit()
+}
@@ -134,16 +134,21 @@ func wrapSettings(ptr *C.WebKitSettings) *Settings {
 // Clone clones a set of settings.
 func (s *Settings) Clone() *Settings {
 	sNew := NewSettings()
+	sNew.CopyFrom(s)
+	return sNew
+}
+
+// CopyFrom overwrites all settings with those of another set of settings.
+func (s *Settings) CopyFrom(from *Settings) {
 	for setting := range boolSetting {
-		sNew.SetBool(setting, s.GetBool(setting))
+		s.SetBool(setting, from.GetBool(setting))
 	}
 	for setting := range stringSetting {
-		sNew.SetString(setting, s.GetString(setting))
+		s.SetString(setting, from.GetString(setting))
 	}
 	for setting := range uintSetting {
-		sNew.SetUint(setting, s.GetUint(setting))
+		s.SetUint(setting, from.GetUint(setting))
 	}
-	return sNew
 }
 
 // object converts settings from their go representation to their C gobject
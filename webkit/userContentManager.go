@@ -39,3 +39,23 @@ func (ucm *UserContentManager) AddStyleSheet(s *UserStyleSheet) {
 		(*C.WebKitUserContentManager)(unsafe.Pointer(ucm.Native())),
 		(*C.WebKitUserStyleSheet)(unsafe.Pointer(s.Native())))
 }
+
+// RemoveAllStyleSheets removes all UserStyleSheets from this
+// UserContentManager.
+func (ucm *UserContentManager) RemoveAllStyleSheets() {
+	C.webkit_user_content_manager_remove_all_style_sheets(
+		(*C.WebKitUserContentManager)(unsafe.Pointer(ucm.Native())))
+}
+
+// AddScript attaches a UserScript to this UserContentManager.
+func (ucm *UserContentManager) AddScript(s *UserScript) {
+	C.webkit_user_content_manager_add_script(
+		(*C.WebKitUserContentManager)(unsafe.Pointer(ucm.Native())),
+		(*C.WebKitUserScript)(unsafe.Pointer(s.Native())))
+}
+
+// RemoveAllScripts removes all UserScripts from this UserContentManager.
+func (ucm *UserContentManager) RemoveAllScripts() {
+	C.webkit_user_content_manager_remove_all_scripts(
+		(*C.WebKitUserContentManager)(unsafe.Pointer(ucm.Native())))
+}
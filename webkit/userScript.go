@@ -0,0 +1,93 @@
+package webkit
+
+// #cgo pkg-config: webkit2gtk-4.0
+// #include <webkit2/webkit2.h>
+// #include <stdlib.h>
+import "C"
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/tkerber/golem/gtk"
+)
+
+const (
+	// UserScriptInjectAtDocumentStart specifies that the script should be
+	// injected before any other content is loaded.
+	UserScriptInjectAtDocumentStart C.WebKitUserScriptInjectionTime = C.WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_START
+	// UserScriptInjectAtDocumentEnd specifies that the script should be
+	// injected after the document has been loaded.
+	UserScriptInjectAtDocumentEnd C.WebKitUserScriptInjectionTime = C.WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_END
+)
+
+// A UserScript is a wrapper around WebKitUserScript.
+//
+// It represents user-defined JavaScript code, which can be injected into a
+// website.
+type UserScript struct {
+	native uintptr
+}
+
+// NewUserScript creates a new user script.
+//
+// It takes the raw JavaScript source code, one of UserContentInjectAllFrames
+// or UserContentInjectTopFrame; one of UserScriptInjectAtDocumentStart or
+// UserScriptInjectAtDocumentEnd; and uri white- and blacklists.
+//
+// Passing nil for the whitelist implies all URIs are whitelisted; passing
+// nil for the blacklist implies no URIs are blacklisted.
+func NewUserScript(
+	source string,
+	frames C.WebKitUserContentInjectedFrames,
+	time C.WebKitUserScriptInjectionTime,
+	whitelist []string,
+	blacklist []string) (*UserScript, error) {
+
+	csrc := C.CString(source)
+	defer C.free(unsafe.Pointer(csrc))
+
+	// Creates C whitelist, NULL terminated and w/ c strings.
+	cwl := make([]*C.gchar, len(whitelist)+1)
+	for i, item := range whitelist {
+		cstr := C.CString(item)
+		defer C.free(unsafe.Pointer(cstr))
+		cwl[i] = (*C.gchar)(cstr)
+	}
+	cwl[len(whitelist)] = nil
+
+	// Creates C blaclist, NULL terminated and w/ c strings.
+	cbl := make([]*C.gchar, len(blacklist)+1)
+	for i, item := range blacklist {
+		cstr := C.CString(item)
+		defer C.free(unsafe.Pointer(cstr))
+		cbl[i] = (*C.gchar)(cstr)
+	}
+	cbl[len(blacklist)] = nil
+
+	cjs := C.webkit_user_script_new(
+		(*C.gchar)(csrc),
+		frames,
+		time,
+		&cwl[0],
+		&cbl[0])
+	if cjs == nil {
+		return nil, errNilPtr
+	}
+	js := &UserScript{uintptr(unsafe.Pointer(cjs))}
+	// It starts with a ref count of 1, so no need to manually ref it.
+	runtime.SetFinalizer(js, func(s *UserScript) {
+		gtk.GlibMainContextInvoke(s.unref)
+	})
+	return js, nil
+}
+
+// unref dereferences the script.
+func (js *UserScript) unref() {
+	C.webkit_user_script_unref(
+		(*C.WebKitUserScript)(unsafe.Pointer(js.native)))
+}
+
+// Native returns the pointer to the native C object of the script.
+func (js *UserScript) Native() uintptr {
+	return js.native
+}
@@ -14,10 +14,10 @@ import (
 const (
 	// UserContentInjectAllFrames specifies that user content should be
 	// injected into all frames.
-	UserContentInjectAllFrames = C.WEBKIT_USER_CONTENT_INJECT_ALL_FRAMES
+	UserContentInjectAllFrames C.WebKitUserContentInjectedFrames = C.WEBKIT_USER_CONTENT_INJECT_ALL_FRAMES
 	// UserContentInjectTopFrame specifies that user content should be
 	// injected only into the top-level frame.
-	UserContentInjectTopFrame = C.WEBKIT_USER_CONTENT_INJECT_TOP_FRAME
+	UserContentInjectTopFrame C.WebKitUserContentInjectedFrames = C.WEBKIT_USER_CONTENT_INJECT_TOP_FRAME
 )
 
 const (